@@ -0,0 +1,218 @@
+package bastion
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ssm"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type BastionInput struct {
+	// optional, names the instance and its IAM role, defaults to "bastion"
+	Name string `json:"name"`
+	// one of "ssm" (default), an instance in a private subnet reachable only
+	// through session manager, or "host", an instance in a public subnet that
+	// also accepts ssh
+	Mode string `json:"mode" validate:"oneof=ssm|host"`
+
+	VpcId string `json:"vpc-id" validate:"required"`
+	// subnet of the instance, a private subnet in ssm mode and a public subnet
+	// in host mode
+	SubnetId string `json:"subnet-id" validate:"required"`
+
+	// optional, defaults to t3.micro
+	InstanceType string `json:"instance-type"`
+	// optional, defaults to the latest amazon linux 2 ami
+	Ami string `json:"ami"`
+
+	// ssm mode, optional, creates the ssm, ssmmessages, and ec2messages
+	// interface endpoints in the given subnets, so that session manager works
+	// without a NAT gateway
+	EndpointSubnetIds []string `json:"endpoint-subnet-ids"`
+
+	// host mode, key pair and the cidrs that may ssh to the instance
+	KeyName      string   `json:"key-name"`
+	AllowedCidrs []string `json:"allowed-cidrs" validate:"cidr"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one bastion in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+// bastion modes
+const (
+	BastionModeSsm  = "ssm"
+	BastionModeHost = "host"
+)
+
+// SyncBastion creates an instance that operators can reach private resources through, i.e. private endpoint clusters.
+// The instance is managed by session manager in both modes, with the SSO or IAM identity of the operator, and in host
+// mode additionally accepts ssh from the allowed cidrs.
+func SyncBastion(ctx *pulumi.Context, config BastionInput, opts ...pulumi.ResourceOption) (*ec2.Instance, error) {
+	if config.VpcId == "" || config.SubnetId == "" {
+		return nil, errors.New("bastion requires a vpc and subnet")
+	}
+	name := "bastion"
+	if config.Name != "" {
+		name = config.Name
+	}
+	instanceType := "t3.micro"
+	if config.InstanceType != "" {
+		instanceType = config.InstanceType
+	}
+
+	securityGroupArgs := &ec2.SecurityGroupArgs{
+		Description: pulumi.String(fmt.Sprintf("access to the %s instance", name)),
+		VpcId:       pulumi.String(config.VpcId),
+		Egress: ec2.SecurityGroupEgressArray{
+			ec2.SecurityGroupEgressArgs{
+				Protocol:   pulumi.String("-1"),
+				FromPort:   pulumi.Int(0),
+				ToPort:     pulumi.Int(0),
+				CidrBlocks: pulumi.ToStringArray([]string{"0.0.0.0/0"}),
+			},
+		},
+	}
+	switch config.Mode {
+	case "", BastionModeSsm:
+		if len(config.EndpointSubnetIds) != 0 {
+			err := syncSsmEndpoints(ctx, config, opts...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case BastionModeHost:
+		if config.KeyName == "" || len(config.AllowedCidrs) == 0 {
+			return nil, errors.New("bastion host requires a key name and allowed cidrs")
+		}
+		securityGroupArgs.Ingress = ec2.SecurityGroupIngressArray{
+			ec2.SecurityGroupIngressArgs{
+				Protocol:   pulumi.String("tcp"),
+				FromPort:   pulumi.Int(22),
+				ToPort:     pulumi.Int(22),
+				CidrBlocks: pulumi.ToStringArray(config.AllowedCidrs),
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unknown bastion mode: '%s'", config.Mode)
+	}
+
+	securityGroup, err := ec2.NewSecurityGroup(ctx, utils.PrefixedName(config.ResourcePrefix, name+"-security-group"), securityGroupArgs, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceProfile, err := newSsmInstanceProfile(ctx, config, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ami := config.Ami
+	if ami == "" {
+		parameter, err := ssm.LookupParameter(ctx, &ssm.LookupParameterArgs{
+			Name: "/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2",
+		})
+		if err != nil {
+			return nil, err
+		}
+		ami = parameter.Value
+	}
+
+	instanceArgs := &ec2.InstanceArgs{
+		Ami:                      pulumi.String(ami),
+		InstanceType:             pulumi.String(instanceType),
+		SubnetId:                 pulumi.String(config.SubnetId),
+		VpcSecurityGroupIds:      pulumi.StringArray{securityGroup.ID()},
+		IamInstanceProfile:       instanceProfile.Name,
+		AssociatePublicIpAddress: pulumi.Bool(config.Mode == BastionModeHost),
+		MetadataOptions: &ec2.InstanceMetadataOptionsArgs{
+			HttpTokens: pulumi.String("required"),
+		},
+		RootBlockDevice: &ec2.InstanceRootBlockDeviceArgs{
+			Encrypted: pulumi.Bool(true),
+		},
+		Tags: pulumi.StringMap{
+			"Name": pulumi.String(name),
+		},
+	}
+	if config.KeyName != "" {
+		instanceArgs.KeyName = pulumi.String(config.KeyName)
+	}
+	// the latest ami changes over time, replacing the instance is left to the
+	// operator
+	return ec2.NewInstance(ctx, utils.PrefixedName(config.ResourcePrefix, name), instanceArgs, append(append([]pulumi.ResourceOption{}, opts...), pulumi.IgnoreChanges([]string{"ami"}))...)
+}
+
+// creates the instance profile of the bastion, allowing session manager to
+// manage the instance
+func newSsmInstanceProfile(ctx *pulumi.Context, config BastionInput, name string, opts ...pulumi.ResourceOption) (*iam.InstanceProfile, error) {
+	assumeRolePolicy, err := iampolicy.NewDocument(
+		iampolicy.Allow("sts:AssumeRole").For("Service", "ec2.amazonaws.com"),
+	).Json()
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := iam.NewRole(ctx, utils.PrefixedName(config.ResourcePrefix, name+"-role"), &iam.RoleArgs{
+		AssumeRolePolicy:  pulumi.String(assumeRolePolicy),
+		ManagedPolicyArns: pulumi.ToStringArray([]string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"}),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return iam.NewInstanceProfile(ctx, utils.PrefixedName(config.ResourcePrefix, name+"-instance-profile"), &iam.InstanceProfileArgs{
+		Role: role.Name,
+	}, opts...)
+}
+
+// creates the interface endpoints that session manager needs, reachable over
+// https from the vpc
+func syncSsmEndpoints(ctx *pulumi.Context, config BastionInput, opts ...pulumi.ResourceOption) error {
+	vpc, err := ec2.LookupVpc(ctx, &ec2.LookupVpcArgs{
+		Id: &config.VpcId,
+	})
+	if err != nil {
+		return err
+	}
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	securityGroup, err := ec2.NewSecurityGroup(ctx, utils.PrefixedName(config.ResourcePrefix, "ssm-endpoints-security-group"), &ec2.SecurityGroupArgs{
+		Description: pulumi.String("https access to the session manager endpoints"),
+		VpcId:       pulumi.String(config.VpcId),
+		Ingress: ec2.SecurityGroupIngressArray{
+			ec2.SecurityGroupIngressArgs{
+				Protocol:   pulumi.String("tcp"),
+				FromPort:   pulumi.Int(443),
+				ToPort:     pulumi.Int(443),
+				CidrBlocks: pulumi.ToStringArray([]string{vpc.CidrBlock}),
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range []string{"ssm", "ssmmessages", "ec2messages"} {
+		_, err = ec2.NewVpcEndpoint(ctx, utils.PrefixedName(config.ResourcePrefix, service+"-endpoint"), &ec2.VpcEndpointArgs{
+			VpcId:             pulumi.String(config.VpcId),
+			ServiceName:       pulumi.String(fmt.Sprintf("com.amazonaws.%s.%s", region.Name, service)),
+			VpcEndpointType:   pulumi.String("Interface"),
+			SubnetIds:         pulumi.ToStringArray(config.EndpointSubnetIds),
+			SecurityGroupIds:  pulumi.StringArray{securityGroup.ID()},
+			PrivateDnsEnabled: pulumi.Bool(true),
+		}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
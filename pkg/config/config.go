@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"github.com/joomcode/errorx"
+	pulumiconfig "github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GetObject loads a config object like config.GetObject, then validates it with Validate, so that invalid configs
+// fail during preview with every problem listed instead of on the first nil value at apply time.
+func GetObject(cfg *pulumiconfig.Config, key string, output interface{}) error {
+	err := cfg.GetObject(key, output)
+	if err != nil {
+		return errorx.IllegalArgument.Wrap(err, "error reading config object %s", key)
+	}
+	return Validate(key, output)
+}
+
+// Validate checks the `validate` struct tags of a config object and returns all failures as one error. Fields are
+// named by their json tags, prefixed with the given name. The supported rules, separated by commas, are:
+//
+//	required       the field is not empty
+//	oneof=a|b      the string field is empty or one of the values
+//	cidr           the string, or every string of the list, is empty or an ipv4 or ipv6 cidr
+//	semver         the string field is empty or a semantic version, with an optional v prefix
+//	semverrange    the string field is empty, a semantic version, "latest", or a ~ or ^ range, i.e. "~33.1"
+//	iampath        the string field is empty or an IAM path, i.e. "/platform/"
+//	max=n          the list or map has at most n entries
+//
+// Nested structs are validated too, except structs with an Enabled field that is false, so that the settings of
+// disabled features are not required.
+func Validate(name string, object interface{}) error {
+	var failures []string
+	validateValue(name, reflect.ValueOf(object), &failures)
+	if len(failures) != 0 {
+		return errorx.IllegalArgument.New("invalid config %s:\n  - %s", name, strings.Join(failures, "\n  - "))
+	}
+	return nil
+}
+
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+var semverRangePattern = regexp.MustCompile(`^[~^]v?(0|[1-9]\d*)(\.(0|[1-9]\d*)){0,2}$`)
+
+var iamPathPattern = regexp.MustCompile(`^/([\x21-\x7e]+/)?$`)
+
+func validateValue(path string, value reflect.Value, failures *[]string) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			validateValue(path, value.Elem(), failures)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(fmt.Sprintf("%s[%d]", path, i), value.Index(i), failures)
+		}
+	case reflect.Struct:
+		validateStruct(path, value, failures)
+	}
+}
+
+func validateStruct(path string, value reflect.Value, failures *[]string) {
+	if enabled := value.FieldByName("Enabled"); enabled.IsValid() && enabled.Kind() == reflect.Bool && !enabled.Bool() {
+		return
+	}
+
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		// embedded structs share the path of the struct embedding them
+		fieldPath := path
+		if !field.Anonymous {
+			fieldPath = path + "." + fieldName(field)
+		}
+		fieldValue := value.Field(i)
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "" {
+				continue
+			}
+			failure := validateRule(rule, fieldValue)
+			if failure != "" {
+				*failures = append(*failures, fmt.Sprintf("%s %s", fieldPath, failure))
+			}
+		}
+		validateValue(fieldPath, fieldValue, failures)
+	}
+}
+
+// returns the failure of a rule, empty when the value is valid
+func validateRule(rule string, value reflect.Value) string {
+	ruleName, argument := rule, ""
+	if i := strings.Index(rule, "="); i != -1 {
+		ruleName, argument = rule[:i], rule[i+1:]
+	}
+
+	switch ruleName {
+	case "required":
+		if value.IsZero() || ((value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() == 0) {
+			return "is required"
+		}
+	case "oneof":
+		if value.Kind() == reflect.String && value.String() != "" {
+			allowed := strings.Split(argument, "|")
+			for _, option := range allowed {
+				if value.String() == option {
+					return ""
+				}
+			}
+			return fmt.Sprintf("must be one of %s, got '%s'", strings.Join(allowed, ", "), value.String())
+		}
+	case "cidr":
+		for _, cidr := range stringValues(value) {
+			if _, _, err := net.ParseCIDR(cidr); cidr != "" && err != nil {
+				return fmt.Sprintf("must be a cidr, got '%s'", cidr)
+			}
+		}
+	case "semver":
+		if value.Kind() == reflect.String && value.String() != "" && !semverPattern.MatchString(value.String()) {
+			return fmt.Sprintf("must be a semantic version, got '%s'", value.String())
+		}
+	case "semverrange":
+		if value.Kind() == reflect.String && value.String() != "" && value.String() != "latest" &&
+			!semverPattern.MatchString(value.String()) && !semverRangePattern.MatchString(value.String()) {
+			return fmt.Sprintf("must be a semantic version, latest, or a ~ or ^ range, got '%s'", value.String())
+		}
+	case "iampath":
+		if value.Kind() == reflect.String && value.String() != "" && !iamPathPattern.MatchString(value.String()) {
+			return fmt.Sprintf("must be an IAM path starting and ending with /, got '%s'", value.String())
+		}
+	case "max":
+		max, err := strconv.Atoi(argument)
+		if err != nil {
+			return fmt.Sprintf("has invalid validation rule '%s'", rule)
+		}
+		if (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() > max {
+			return fmt.Sprintf("must have at most %d entries, got %d", max, value.Len())
+		}
+	default:
+		return fmt.Sprintf("has unknown validation rule '%s'", rule)
+	}
+	return ""
+}
+
+// returns the string, or the strings of a string list
+func stringValues(value reflect.Value) []string {
+	switch {
+	case value.Kind() == reflect.String:
+		return []string{value.String()}
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		var values []string
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, value.Index(i).String())
+		}
+		return values
+	}
+	return nil
+}
+
+// returns the json name of a field, falling back to the field name
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+type testFeature struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name" validate:"required"`
+}
+
+type testConfig struct {
+	Name         string            `json:"name" validate:"required"`
+	Mode         string            `json:"mode" validate:"oneof=a|b"`
+	Cidrs        []string          `json:"cidrs" validate:"cidr"`
+	Version      string            `json:"version" validate:"semver"`
+	VersionRange string            `json:"version-range" validate:"semverrange"`
+	Path         string            `json:"path" validate:"iampath"`
+	Tags         map[string]string `json:"tags" validate:"max=1"`
+	Required     []string          `json:"required" validate:"required"`
+	Feature      testFeature       `json:"feature"`
+	Features     []testFeature     `json:"features"`
+	Untagged     string
+}
+
+// returns a config that passes validation
+func validTestConfig() testConfig {
+	return testConfig{
+		Name:         "platform",
+		Mode:         "a",
+		Cidrs:        []string{"10.0.0.0/16", "fd00::/8"},
+		Version:      "v1.2.3",
+		VersionRange: "~1.2",
+		Path:         "/platform/",
+		Tags:         map[string]string{"cluster": "prod"},
+		Required:     []string{"value"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(config *testConfig)
+		failures []string
+	}{
+		{
+			name:   "valid",
+			modify: func(config *testConfig) {},
+		},
+		{
+			name: "empty optional values",
+			modify: func(config *testConfig) {
+				config.Mode, config.Version, config.VersionRange, config.Path = "", "", "", ""
+				config.Cidrs, config.Tags = nil, nil
+			},
+		},
+		{
+			name:     "required",
+			modify:   func(config *testConfig) { config.Name, config.Required = "", []string{} },
+			failures: []string{"test.name is required", "test.required is required"},
+		},
+		{
+			name:     "oneof",
+			modify:   func(config *testConfig) { config.Mode = "c" },
+			failures: []string{"test.mode must be one of a, b, got 'c'"},
+		},
+		{
+			name:     "cidr",
+			modify:   func(config *testConfig) { config.Cidrs = []string{"10.0.0.0/16", "10.0.0.0"} },
+			failures: []string{"test.cidrs must be a cidr, got '10.0.0.0'"},
+		},
+		{
+			name:     "semver",
+			modify:   func(config *testConfig) { config.Version = "1.2" },
+			failures: []string{"test.version must be a semantic version, got '1.2'"},
+		},
+		{
+			name:     "semverrange",
+			modify:   func(config *testConfig) { config.VersionRange = ">=1.2" },
+			failures: []string{"test.version-range must be a semantic version, latest, or a ~ or ^ range, got '>=1.2'"},
+		},
+		{
+			name:   "semverrange latest",
+			modify: func(config *testConfig) { config.VersionRange = "latest" },
+		},
+		{
+			name:     "iampath",
+			modify:   func(config *testConfig) { config.Path = "platform" },
+			failures: []string{"test.path must be an IAM path starting and ending with /, got 'platform'"},
+		},
+		{
+			name:     "max",
+			modify:   func(config *testConfig) { config.Tags = map[string]string{"cluster": "prod", "team": "platform"} },
+			failures: []string{"test.tags must have at most 1 entries, got 2"},
+		},
+		{
+			name:   "disabled nested struct",
+			modify: func(config *testConfig) { config.Feature = testFeature{Enabled: false} },
+		},
+		{
+			name:     "enabled nested struct",
+			modify:   func(config *testConfig) { config.Feature = testFeature{Enabled: true} },
+			failures: []string{"test.feature.name is required"},
+		},
+		{
+			name: "list of structs",
+			modify: func(config *testConfig) {
+				config.Features = []testFeature{{Enabled: true, Name: "a"}, {Enabled: true}}
+			},
+			failures: []string{"test.features[1].name is required"},
+		},
+		{
+			name: "all failures",
+			modify: func(config *testConfig) {
+				config.Name, config.Mode = "", "c"
+			},
+			failures: []string{"test.name is required", "test.mode must be one of a, b, got 'c'"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := validTestConfig()
+			test.modify(&config)
+			err := Validate("test", &config)
+			if len(test.failures) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected failures %v, got no error", test.failures)
+			}
+			for _, failure := range test.failures {
+				if !strings.Contains(err.Error(), "\n  - "+failure) {
+					t.Errorf("expected failure %q, got %v", failure, err)
+				}
+			}
+			if count := strings.Count(err.Error(), "\n  - "); count != len(test.failures) {
+				t.Errorf("expected %d failures, got %d: %v", len(test.failures), count, err)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownRule(t *testing.T) {
+	config := struct {
+		Name string `json:"name" validate:"email"`
+	}{}
+	err := Validate("test", config)
+	if err == nil || !strings.Contains(err.Error(), "test.name has unknown validation rule 'email'") {
+		t.Fatalf("expected an unknown rule failure, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package cost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/messaging"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/budgets"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/sns"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type BudgetsInput struct {
+	Budgets []BudgetInput `json:"budgets"`
+
+	// optional sns topic the budgets alert, created when named. it is not
+	// encrypted, as aws budgets cannot publish to topics encrypted with the
+	// aws managed key
+	AlertTopicName string `json:"alert-topic-name"`
+	// optional subscriptions of the alert topic, i.e. https endpoints of chat
+	// integrations
+	AlertTopicSubscriptions []messaging.SubscriptionInput `json:"alert-topic-subscriptions"`
+
+	Tags map[string]string `json:"tags"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type BudgetInput struct {
+	Name string `json:"name" validate:"required"`
+	// limit of the budget in USD, i.e. "1500"
+	LimitAmount string `json:"limit-amount" validate:"required"`
+	// optional, one of MONTHLY (default), QUARTERLY, or ANNUALLY
+	TimeUnit string `json:"time-unit" validate:"oneof=MONTHLY|QUARTERLY|ANNUALLY"`
+
+	// optional cost allocation tag the budget is scoped to, i.e.
+	// {"cluster": "prod"}. only one tag is supported, as the aws provider in
+	// use filters a budget by a single tag, so config with more tags fails
+	// validation. the tag must be activated as a cost allocation tag in the
+	// billing console
+	CostAllocationTags map[string]string `json:"cost-allocation-tags" validate:"max=1"`
+
+	// optional percentages of the limit at which actual and forecasted costs
+	// alert, default to 80 and 100 for actual costs
+	ActualThresholds     []float64 `json:"actual-thresholds"`
+	ForecastedThresholds []float64 `json:"forecasted-thresholds"`
+	// optional email addresses alerted directly, in addition to the alert
+	// topic
+	EmailAddresses []string `json:"email-addresses"`
+}
+
+type BudgetsOutput struct {
+	// empty when no alert topic is created
+	AlertTopicArn pulumi.StringOutput
+}
+
+// SyncBudgets creates AWS budgets scoped to a cost allocation tag, i.e. that of a cluster, alerting email addresses
+// and an optional sns topic when actual or forecasted costs cross thresholds of the limits.
+func SyncBudgets(ctx *pulumi.Context, config BudgetsInput, opts ...pulumi.ResourceOption) (BudgetsOutput, error) {
+	var output BudgetsOutput
+	var topicArns pulumi.StringArray
+	if config.AlertTopicName != "" {
+		topic, err := syncAlertTopic(ctx, config, opts...)
+		if err != nil {
+			return output, err
+		}
+		output.AlertTopicArn = topic.Arn
+		topicArns = pulumi.StringArray{topic.Arn}
+	}
+
+	for _, budgetConfig := range config.Budgets {
+		_, err := newBudget(ctx, config.ResourcePrefix, budgetConfig, topicArns, opts...)
+		if err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+func newBudget(ctx *pulumi.Context, resourcePrefix string, config BudgetInput, topicArns pulumi.StringArray, opts ...pulumi.ResourceOption) (*budgets.Budget, error) {
+	if config.Name == "" || config.LimitAmount == "" {
+		return nil, errors.New("budget requires a name and limit amount")
+	}
+	if len(topicArns) == 0 && len(config.EmailAddresses) == 0 {
+		return nil, fmt.Errorf("budget %s requires email addresses or an alert topic", config.Name)
+	}
+	timeUnit := "MONTHLY"
+	if config.TimeUnit != "" {
+		timeUnit = config.TimeUnit
+	}
+	actualThresholds := config.ActualThresholds
+	if len(actualThresholds) == 0 && len(config.ForecastedThresholds) == 0 {
+		actualThresholds = []float64{80, 100}
+	}
+
+	var notifications budgets.BudgetNotificationArray
+	for _, threshold := range []struct {
+		notificationType string
+		percentages      []float64
+	}{
+		{"ACTUAL", actualThresholds},
+		{"FORECASTED", config.ForecastedThresholds},
+	} {
+		for _, percentage := range threshold.percentages {
+			notification := budgets.BudgetNotificationArgs{
+				ComparisonOperator: pulumi.String("GREATER_THAN"),
+				NotificationType:   pulumi.String(threshold.notificationType),
+				Threshold:          pulumi.Float64(percentage),
+				ThresholdType:      pulumi.String("PERCENTAGE"),
+			}
+			if len(config.EmailAddresses) != 0 {
+				notification.SubscriberEmailAddresses = pulumi.ToStringArray(config.EmailAddresses)
+			}
+			if len(topicArns) != 0 {
+				notification.SubscriberSnsTopicArns = topicArns
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+
+	// the tag filter is "user:<key>$<value>". the aws provider in use maps
+	// each cost filter to a single value, so only one tag is supported
+	if len(config.CostAllocationTags) > 1 {
+		return nil, fmt.Errorf("budget %s is scoped to more than one cost allocation tag, only one is supported", config.Name)
+	}
+	var costFilters pulumi.StringMap
+	for key, value := range config.CostAllocationTags {
+		costFilters = pulumi.StringMap{
+			"TagKeyValue": pulumi.String(fmt.Sprintf("user:%s$%s", key, value)),
+		}
+	}
+
+	return budgets.NewBudget(ctx, utils.PrefixedName(resourcePrefix, fmt.Sprintf("budget-%s", config.Name)), &budgets.BudgetArgs{
+		Name:          pulumi.String(config.Name),
+		BudgetType:    pulumi.String("COST"),
+		LimitAmount:   pulumi.String(config.LimitAmount),
+		LimitUnit:     pulumi.String("USD"),
+		TimeUnit:      pulumi.String(timeUnit),
+		CostFilters:   costFilters,
+		Notifications: notifications,
+	}, opts...)
+}
+
+// creates the alert topic with a policy allowing aws budgets to publish to
+// it, and its subscriptions
+func syncAlertTopic(ctx *pulumi.Context, config BudgetsInput, opts ...pulumi.ResourceOption) (*sns.Topic, error) {
+	topic, err := sns.NewTopic(ctx, utils.PrefixedName(config.ResourcePrefix, config.AlertTopicName), &sns.TopicArgs{
+		Name: pulumi.String(config.AlertTopicName),
+		Tags: pulumi.ToStringMap(config.Tags),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := topic.Arn.ApplyT(func(topicArn string) (string, error) {
+		return iampolicy.NewDocument(
+			iampolicy.Allow("sns:Publish").For("Service", "budgets.amazonaws.com").On(topicArn),
+		).Json()
+	}).(pulumi.StringOutput)
+	_, err = sns.NewTopicPolicy(ctx, utils.PrefixedName(config.ResourcePrefix, config.AlertTopicName+"-policy"), &sns.TopicPolicyArgs{
+		Arn:    topic.Arn,
+		Policy: policy,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, subscription := range config.AlertTopicSubscriptions {
+		if subscription.Protocol == "" || subscription.Endpoint == "" {
+			return nil, fmt.Errorf("subscription %d of topic %s requires a protocol and endpoint", i, config.AlertTopicName)
+		}
+		args := &sns.TopicSubscriptionArgs{
+			Topic:              topic.Arn,
+			Protocol:           pulumi.String(subscription.Protocol),
+			Endpoint:           pulumi.String(subscription.Endpoint),
+			RawMessageDelivery: pulumi.Bool(subscription.RawMessageDelivery),
+		}
+		if len(subscription.FilterPolicy) != 0 {
+			filterPolicy, err := json.Marshal(subscription.FilterPolicy)
+			if err != nil {
+				return nil, err
+			}
+			args.FilterPolicy = pulumi.String(string(filterPolicy))
+		}
+		_, err = sns.NewTopicSubscription(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("%s-subscription-%d", config.AlertTopicName, i)), args, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return topic, nil
+}
@@ -0,0 +1,132 @@
+package eks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type AddonsInput struct {
+	EKSClusterName string `json:"eks-cluster-name" validate:"required"`
+
+	// list of addons to manage on the cluster
+	Addons []AddonConfigInput `json:"addons"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type AddonConfigInput struct {
+	// addon name, e.g. vpc-cni, coredns, kube-proxy, aws-ebs-csi-driver,
+	// aws-efs-csi-driver
+	Name string `json:"name" validate:"required"`
+
+	// optional pinned addon version, defaults to the version AWS selects for
+	// the cluster
+	Version string `json:"version"`
+
+	// optional conflict resolution strategy, one of NONE or OVERWRITE.
+	// defaults to OVERWRITE so that pulumi owns the addon configuration
+	ResolveConflicts string `json:"resolve-conflicts" validate:"oneof=NONE|OVERWRITE"`
+
+	// optional IRSA role for the addon's service account. defaults are
+	// provided for well known addons, see wellKnownAddonServiceAccounts
+	DisableIrsa    bool     `json:"disable-irsa"`
+	Namespace      string   `json:"namespace"`
+	ServiceAccount string   `json:"service-account"`
+	PolicyArns     []string `json:"policy-arns"`
+}
+
+type addonServiceAccount struct {
+	serviceAccount string
+	policyArns     []string
+}
+
+// service accounts and managed policies for addons that need AWS permissions
+var wellKnownAddonServiceAccounts = map[string]addonServiceAccount{
+	"vpc-cni": {
+		serviceAccount: "aws-node",
+		policyArns:     []string{"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy"},
+	},
+	"aws-ebs-csi-driver": {
+		serviceAccount: "ebs-csi-controller-sa",
+		policyArns:     []string{"arn:aws:iam::aws:policy/service-role/AmazonEBSCSIDriverPolicy"},
+	},
+	"aws-efs-csi-driver": {
+		serviceAccount: "efs-csi-controller-sa",
+		policyArns:     []string{"arn:aws:iam::aws:policy/service-role/AmazonEFSCSIDriverPolicy"},
+	},
+}
+
+// SyncAddons creates an eks addon for each configured addon, along with an IRSA role for addons that need AWS
+// permissions, so that addon versions are pinned and managed by pulumi instead of drifting.
+func SyncAddons(ctx *pulumi.Context, config AddonsInput, opts ...pulumi.ResourceOption) ([]pulumi.Resource, error) {
+	if config.EKSClusterName == "" {
+		return nil, errors.New("EKS cluster name not supplied, cannot manage addons")
+	}
+
+	var resources []pulumi.Resource
+	for _, addonConfig := range config.Addons {
+		addon, err := syncAddon(ctx, config.EKSClusterName, config.ResourcePrefix, addonConfig, opts...)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, addon)
+	}
+	return resources, nil
+}
+
+func syncAddon(ctx *pulumi.Context, clusterName string, resourcePrefix string, config AddonConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	resolveConflicts := "OVERWRITE"
+	if config.ResolveConflicts != "" {
+		resolveConflicts = config.ResolveConflicts
+	}
+
+	addonArgs := &eks.AddonArgs{
+		AddonName:        pulumi.String(config.Name),
+		ClusterName:      pulumi.String(clusterName),
+		ResolveConflicts: pulumi.String(resolveConflicts),
+	}
+	if config.Version != "" {
+		addonArgs.AddonVersion = pulumi.String(config.Version)
+	}
+
+	// create an IRSA role when the addon needs one, either from explicit
+	// configuration or from the well known defaults
+	if !config.DisableIrsa {
+		serviceAccount := config.ServiceAccount
+		policyArns := config.PolicyArns
+		if defaults, ok := wellKnownAddonServiceAccounts[config.Name]; ok {
+			if serviceAccount == "" {
+				serviceAccount = defaults.serviceAccount
+			}
+			if len(policyArns) == 0 {
+				policyArns = defaults.policyArns
+			}
+		}
+
+		if serviceAccount != "" && len(policyArns) != 0 {
+			namespace := "kube-system"
+			if config.Namespace != "" {
+				namespace = config.Namespace
+			}
+
+			role, err := NewIrsaRole(ctx, utils.PrefixedName(resourcePrefix, fmt.Sprintf("eks-addon-%s-role", config.Name)), IrsaRoleInput{
+				Name:           fmt.Sprintf("%s-%s", clusterName, config.Name),
+				EKSClusterName: clusterName,
+				Namespace:      namespace,
+				ServiceAccount: serviceAccount,
+				PolicyArns:     policyArns,
+			}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			addonArgs.ServiceAccountRoleArn = role.Arn
+		}
+	}
+
+	return eks.NewAddon(ctx, utils.PrefixedName(resourcePrefix, fmt.Sprintf("eks-addon-%s", config.Name)), addonArgs, opts...)
+}
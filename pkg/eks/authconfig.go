@@ -3,13 +3,17 @@ package eks
 import (
 	"errors"
 	"fmt"
-	"github.com/catalystcommunity/app-utils-go/errorutils"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/logging"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
 	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
 	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
-	"github.com/pulumi/pulumi-command/sdk/go/command/local"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-	"os"
 	"strings"
+	"time"
 
 	// use yaml v2 because it uses indentation that matches the default
 	// aws-auth configmap, so that the initial import does not fail
@@ -17,38 +21,80 @@ import (
 )
 
 type AuthConfigMapInput struct {
-	// disables all extra auth configuration, so that the configmap can be
-	// imported by pulumi. set this value to true on new clusters, disable
-	// after the configmap is imported and all additional permissions will be
+	// disables all extra auth configuration, so that the data matches the
+	// configmap created by EKS when it is adopted. only needed on the first
+	// run when sso roles, iam roles, or iam users are configured, disable
+	// after the configmap is adopted and all additional permissions will be
 	// added
 	InitialImport bool `json:"initial-import"`
 
-	// required if nodegroup IAM role autodiscovery not enabled
-	NodeGroupIamRole string `json:"nodegroup-iam-role"`
+	// the configmap created by EKS is adopted into pulumi by default. set
+	// this value to true when EKS does not create the configmap, i.e. on
+	// clusters with only self-managed nodes, so that it is created instead
+	CreateConfigMap bool `json:"create-configmap"`
 
-	// required if nodegroup IAM role not supplied
+	// required if nodegroup IAM role autodiscovery not enabled, either field
+	// may be used and both are combined
+	NodeGroupIamRole  string   `json:"nodegroup-iam-role"`
+	NodeGroupIamRoles []string `json:"nodegroup-iam-roles"`
+
+	// required if nodegroup IAM role not supplied, discovers the distinct IAM
+	// roles of all nodegroups in the cluster
 	NodeGroupIamRoleAutoDiscover bool   `json:"nodegroup-iam-role-autodiscover"`
 	EKSClusterName               string `json:"eks-cluster-name"`
 
+	// optional list of fargate pod execution role arns
+	FargatePodExecutionRoles []string `json:"fargate-pod-execution-roles"`
+
 	// optional list of AWS SSO permission set roles to autodiscover
 	AutoDiscoverSSORoles []SSORolePermissionSetInput `json:"sso-permission-set-roles"`
 
 	// optional list of IAM roles and users
 	IAMRoles []IAMIdentityInput `json:"iam-roles"`
 	IAMUsers []IAMIdentityInput `json:"iam-users"`
+
+	// optional kubeconfig of the cluster, the ambient kubeconfig is used when
+	// not set
+	KubeConfig pulumi.StringOutput
+
+	// optional aws provider of the cluster's account and region, used to
+	// discover nodegroup and sso roles. the default provider is used when not
+	// set
+	AwsProvider *aws.Provider
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
 }
 
 type SSORolePermissionSetInput struct {
 	// name of permission set to discover for use in configmap
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required"`
 
 	// required groups to add role to
-	PermissionGroups []string `json:"permission-groups"`
+	PermissionGroups []string `json:"permission-groups" validate:"required"`
 
 	// optional username field, defaults to name field
 	Username string `json:"username"`
+
+	// optional strategy for when more than one role matches the permission
+	// set, which happens when a permission set is reprovisioned. one of
+	// "error" (default), "newest", "suffix", or "all"
+	MultipleMatchStrategy string `json:"multiple-match-strategy" validate:"oneof=error|newest|suffix|all"`
+
+	// required for the "suffix" strategy, the unique suffix of the role name
+	// after the permission set name, i.e. AWSReservedSSO_<name>_<suffix>
+	RoleSuffix string `json:"role-suffix"`
 }
 
+// SSO role multiple match strategies
+const (
+	SSORoleMatchError  = "error"
+	SSORoleMatchNewest = "newest"
+	SSORoleMatchSuffix = "suffix"
+	SSORoleMatchAll    = "all"
+)
+
 type IAMIdentityInput struct {
 	// arn of IAM role to use in configmap
 	Arn string `json:"arn"`
@@ -86,10 +132,18 @@ type ConfigMapMetadata struct {
 
 var ssoRolePathPrefix string = "/aws-reserved/sso.amazonaws.com/"
 
-func SyncAuthConfigMap(ctx *pulumi.Context, config AuthConfigMapInput) error {
+// SyncAuthConfigMap manages the aws-auth configmap, mapping node roles and the configured IAM identities to kubernetes
+// groups. The configmap is created by EKS and adopted into pulumi on the first run; the import fails when the data
+// differs from the configmap EKS created, so set InitialImport for the first run when extra identities are configured.
+// The configmap is retained when the resource is deleted, so that nodes keep joining the cluster.
+//
+// Stacks that synced the configmap with the kubectl command of earlier versions adopt the applied configmap on the
+// first run with this version, and the "aws-auth-configmap" command resource is deleted from the stack. The command
+// has no delete action, so deleting it leaves the configmap in place.
+func SyncAuthConfigMap(ctx *pulumi.Context, config AuthConfigMapInput, opts ...pulumi.ResourceOption) error {
 	var authConfigMap ConfigMap = ConfigMap{
 		ApiVersion: "v1",
-		Data: map[string]string{},
+		Data:       map[string]string{},
 		Kind:       "ConfigMap",
 		Metadata: ConfigMapMetadata{
 			Name:      "aws-auth",
@@ -99,11 +153,11 @@ func SyncAuthConfigMap(ctx *pulumi.Context, config AuthConfigMapInput) error {
 	var mapRoles []MapRolesElement
 	var mapUsers []MapUsersElement
 
-	var nodeRoleArn string
+	var nodeRoleArns []string
 	var err error
 	if config.NodeGroupIamRoleAutoDiscover {
 		if config.EKSClusterName != "" {
-			nodeRoleArn, err = discoverNodeIAMRole(ctx, config.EKSClusterName)
+			nodeRoleArns, err = discoverNodeIAMRoles(ctx, config.EKSClusterName, awsInvokeOpts(config.AwsProvider)...)
 			if err != nil {
 				return err
 			}
@@ -112,21 +166,38 @@ func SyncAuthConfigMap(ctx *pulumi.Context, config AuthConfigMapInput) error {
 		}
 	} else {
 		if config.NodeGroupIamRole != "" {
-			nodeRoleArn = config.NodeGroupIamRole
-		} else {
+			nodeRoleArns = append(nodeRoleArns, config.NodeGroupIamRole)
+		}
+		nodeRoleArns = append(nodeRoleArns, config.NodeGroupIamRoles...)
+		if len(nodeRoleArns) == 0 {
 			return errors.New("Node Group IAM Role not supplied, auto discover not enabled")
 		}
 	}
 
-	// add nodegroup iam role to mapRoles
-	mapRoles = append(mapRoles, MapRolesElement{
-		RoleArn:  nodeRoleArn,
-		Username: "system:node:{{EC2PrivateDNSName}}",
-		Groups: []string{
-			"system:bootstrappers",
-			"system:nodes",
-		},
-	})
+	// add nodegroup iam roles to mapRoles
+	for _, nodeRoleArn := range nodeRoleArns {
+		mapRoles = append(mapRoles, MapRolesElement{
+			RoleArn:  removeArnPath(nodeRoleArn),
+			Username: "system:node:{{EC2PrivateDNSName}}",
+			Groups: []string{
+				"system:bootstrappers",
+				"system:nodes",
+			},
+		})
+	}
+
+	// add fargate pod execution roles to mapRoles
+	for _, podExecutionRoleArn := range config.FargatePodExecutionRoles {
+		mapRoles = append(mapRoles, MapRolesElement{
+			RoleArn:  removeArnPath(podExecutionRoleArn),
+			Username: "system:node:{{SessionName}}",
+			Groups: []string{
+				"system:bootstrappers",
+				"system:nodes",
+				"system:node-proxier",
+			},
+		})
+	}
 
 	if !config.InitialImport {
 		// add all sso autodiscovery roles
@@ -137,16 +208,18 @@ func SyncAuthConfigMap(ctx *pulumi.Context, config AuthConfigMapInput) error {
 				username = ssoRoleConfig.Username
 			}
 
-			roleArn, err := discoverSSORole(ctx, ssoRoleConfig.Name)
+			roleArns, err := discoverSSORoles(ctx, ssoRoleConfig, awsInvokeOpts(config.AwsProvider)...)
 			if err != nil {
 				return err
 			}
 
-			mapRoles = append(mapRoles, MapRolesElement{
-				RoleArn:  removeArnPath(roleArn),
-				Username: username,
-				Groups:   ssoRoleConfig.PermissionGroups,
-			})
+			for _, roleArn := range roleArns {
+				mapRoles = append(mapRoles, MapRolesElement{
+					RoleArn:  removeArnPath(roleArn),
+					Username: username,
+					Groups:   ssoRoleConfig.PermissionGroups,
+				})
+			}
 		}
 
 		// add all iam roles
@@ -196,55 +269,143 @@ func SyncAuthConfigMap(ctx *pulumi.Context, config AuthConfigMapInput) error {
 		authConfigMap.Data["mapUsers"] = string(mapUsersBytes)
 	}
 
-	// marshal configmap
-	configMapYaml, err := yaml.Marshal(&authConfigMap)
-	applyKubernetesManifest(ctx, "aws-auth-configmap", configMapYaml)
-	return err
+	configMapOpts := append([]pulumi.ResourceOption{}, opts...)
+
+	// use the cluster's kubeconfig when supplied
+	if config.KubeConfig.OutputState != nil {
+		provider, err := kubernetes.NewProvider(ctx, utils.PrefixedName(config.ResourcePrefix, "aws-auth-provider"), &kubernetes.ProviderArgs{
+			Kubeconfig: config.KubeConfig,
+		}, opts...)
+		if err != nil {
+			return err
+		}
+		configMapOpts = append(configMapOpts, pulumi.Provider(provider))
+	}
+
+	// the configmap is created by EKS, so adopt it. pulumi only imports the
+	// configmap when it is not in the stack's state yet, and ignores the import
+	// on later runs
+	if !config.CreateConfigMap {
+		configMapOpts = append(configMapOpts, utils.GetImportOpt(fmt.Sprintf("%s/%s", authConfigMap.Metadata.Namespace, authConfigMap.Metadata.Name)))
+	}
+	// deleting the configmap would lock the nodes out of the cluster
+	configMapOpts = append(configMapOpts, pulumi.RetainOnDelete(true))
+
+	resourceName := utils.PrefixedName(config.ResourcePrefix, "aws-auth-configmap")
+	_, err = corev1.NewConfigMap(ctx, resourceName, &corev1.ConfigMapArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(authConfigMap.Metadata.Name),
+			Namespace: pulumi.String(authConfigMap.Metadata.Namespace),
+		},
+		Data: pulumi.ToStringMap(authConfigMap.Data),
+	}, configMapOpts...)
+	return logging.New(ctx, "eks").WithResource(resourceName).Wrap("error syncing aws-auth configmap", err)
 }
 
-// assumes that all nodegroups have the same IAM role, so only finds the first
-// roleArn of the first nodegroup discovered
-func discoverNodeIAMRole(ctx *pulumi.Context, clusterName string) (roleArn string, err error) {
+// finds the distinct IAM roles of all nodegroups in the cluster, in the order
+// the nodegroups are discovered
+func discoverNodeIAMRoles(ctx *pulumi.Context, clusterName string, opts ...pulumi.InvokeOption) (roleArns []string, err error) {
 	nodegroups, err := eks.GetNodeGroups(ctx, &eks.GetNodeGroupsArgs{
 		ClusterName: clusterName,
-	})
+	}, opts...)
 	if err != nil {
 		return
 	}
-
-	nodegroup, err := eks.LookupNodeGroup(ctx, &eks.LookupNodeGroupArgs{
-		ClusterName:   clusterName,
-		NodeGroupName: nodegroups.Names[0],
-	})
-	if err != nil {
+	if len(nodegroups.Names) == 0 {
+		err = errors.New(fmt.Sprintf("no nodegroups discovered in cluster %s", clusterName))
 		return
 	}
 
-	roleArn = nodegroup.NodeRoleArn
+	seen := map[string]bool{}
+	for _, nodegroupName := range nodegroups.Names {
+		nodegroup, err := eks.LookupNodeGroup(ctx, &eks.LookupNodeGroupArgs{
+			ClusterName:   clusterName,
+			NodeGroupName: nodegroupName,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[nodegroup.NodeRoleArn] {
+			seen[nodegroup.NodeRoleArn] = true
+			roleArns = append(roleArns, nodegroup.NodeRoleArn)
+		}
+	}
 	return
 }
 
-func discoverSSORole(ctx *pulumi.Context, permissionSetName string) (roleArn string, err error) {
-	ssoRoleRegex := fmt.Sprintf("AWSReservedSSO_%s_.*", permissionSetName)
+func discoverSSORoles(ctx *pulumi.Context, config SSORolePermissionSetInput, opts ...pulumi.InvokeOption) (roleArns []string, err error) {
+	ssoRoleRegex := fmt.Sprintf("AWSReservedSSO_%s_.*", config.Name)
 
 	discoverSSORole, err := iam.GetRoles(ctx, &iam.GetRolesArgs{
 		NameRegex:  pulumi.StringRef(ssoRoleRegex),
 		PathPrefix: &ssoRolePathPrefix,
-	})
+	}, opts...)
 	if err != nil {
 		return
 	}
 
-	// fail if we don't discover just 1 role
-	if len(discoverSSORole.Arns) != 1 {
-		err = errors.New(fmt.Sprintf(
-			"admin role auto discovery failed, discovered %d",
-			len(discoverSSORole.Arns),
-		))
+	// fail if we don't discover any roles
+	if len(discoverSSORole.Arns) == 0 {
+		err = errors.New(fmt.Sprintf("sso role auto discovery failed for %s, discovered 0", config.Name))
 		return
 	}
 
-	roleArn = discoverSSORole.Arns[0]
+	switch config.MultipleMatchStrategy {
+	case "", SSORoleMatchError:
+		// fail if we don't discover just 1 role
+		if len(discoverSSORole.Arns) != 1 {
+			err = errors.New(fmt.Sprintf(
+				"sso role auto discovery failed for %s, discovered %d",
+				config.Name,
+				len(discoverSSORole.Arns),
+			))
+			return
+		}
+		roleArns = discoverSSORole.Arns
+	case SSORoleMatchNewest:
+		var roleArn string
+		roleArn, err = newestRole(ctx, discoverSSORole.Arns, opts...)
+		roleArns = []string{roleArn}
+	case SSORoleMatchSuffix:
+		if config.RoleSuffix == "" {
+			err = errors.New(fmt.Sprintf("sso role suffix not supplied for %s", config.Name))
+			return
+		}
+		roleName := fmt.Sprintf("AWSReservedSSO_%s_%s", config.Name, config.RoleSuffix)
+		for _, roleArn := range discoverSSORole.Arns {
+			if arnToUsername(roleArn) == roleName {
+				roleArns = []string{roleArn}
+				return
+			}
+		}
+		err = errors.New(fmt.Sprintf("sso role %s not discovered", roleName))
+	case SSORoleMatchAll:
+		roleArns = discoverSSORole.Arns
+	default:
+		err = errors.New(fmt.Sprintf("unknown sso role multiple match strategy: %s", config.MultipleMatchStrategy))
+	}
+	return
+}
+
+// finds the most recently created role of the given roles
+func newestRole(ctx *pulumi.Context, arns []string, opts ...pulumi.InvokeOption) (roleArn string, err error) {
+	var newest time.Time
+	for _, arn := range arns {
+		role, err := iam.LookupRole(ctx, &iam.LookupRoleArgs{
+			Name: arnToUsername(arn),
+		}, opts...)
+		if err != nil {
+			return "", err
+		}
+		createDate, err := time.Parse(time.RFC3339, role.CreateDate)
+		if err != nil {
+			return "", err
+		}
+		if roleArn == "" || createDate.After(newest) {
+			newest = createDate
+			roleArn = arn
+		}
+	}
 	return
 }
 
@@ -260,20 +421,3 @@ func arnToUsername(i string) string {
 	a := strings.Split(i, "/")
 	return a[len(a)-1]
 }
-
-func applyKubernetesManifest(ctx *pulumi.Context, pulumiResourceName string, manifest []byte) error {
-	// write bytes to file
-	tempFileName := fmt.Sprintf("/tmp/%s.yaml", pulumiResourceName)
-	err := os.WriteFile(tempFileName, manifest, 0644)
-	errorutils.LogOnErr(nil, "error writing manifest to file", err)
-	if err != nil {
-		return err
-	}
-	// execute kubectl apply
-	_, err = local.NewCommand(ctx, pulumiResourceName, &local.CommandArgs{
-		Create:   pulumi.String(fmt.Sprintf("kubectl apply -f %s; rm %s", tempFileName, tempFileName)),
-		Triggers: pulumi.ToArrayOutput([]pulumi.Output{pulumi.ToOutput(string(manifest))}),
-	})
-	errorutils.LogOnErr(nil, "error running kubectl apply", err)
-	return err
-}
@@ -0,0 +1,325 @@
+package eks
+
+import (
+	"errors"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/internal/golden"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testSSORolePrefix = "arn:aws:iam::123456789012:role/aws-reserved/sso.amazonaws.com/"
+
+// mocks the aws invokes of the aws-auth configmap, and records the
+// configmaps it creates
+type authConfigMapMocks struct {
+	// node role arns of the cluster's nodegroups, by nodegroup name
+	nodeGroupRoles map[string]string
+	// arns of the discovered sso roles, and their creation dates by role name
+	ssoRoleArns        []string
+	ssoRoleCreateDates map[string]string
+
+	lock       sync.Mutex
+	configMaps map[string]pulumi.MockResourceArgs
+}
+
+func (m *authConfigMapMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if args.TypeToken == "kubernetes:core/v1:ConfigMap" {
+		if m.configMaps == nil {
+			m.configMaps = map[string]pulumi.MockResourceArgs{}
+		}
+		m.configMaps[args.Name] = args
+	}
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (m *authConfigMapMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	switch args.Token {
+	case "aws:eks/getNodeGroups:getNodeGroups":
+		var names []string
+		for name := range m.nodeGroupRoles {
+			names = append(names, name)
+		}
+		// sorted, so that the discovered roles are in a stable order
+		sort.Strings(names)
+		return resource.NewPropertyMapFromMap(map[string]interface{}{"names": names}), nil
+	case "aws:eks/getNodeGroup:getNodeGroup":
+		name := args.Args["nodeGroupName"].StringValue()
+		return resource.NewPropertyMapFromMap(map[string]interface{}{"nodeRoleArn": m.nodeGroupRoles[name]}), nil
+	case "aws:iam/getRoles:getRoles":
+		return resource.NewPropertyMapFromMap(map[string]interface{}{"arns": m.ssoRoleArns}), nil
+	case "aws:iam/getRole:getRole":
+		name := args.Args["name"].StringValue()
+		createDate, ok := m.ssoRoleCreateDates[name]
+		if !ok {
+			return nil, errors.New("role not found: " + name)
+		}
+		return resource.NewPropertyMapFromMap(map[string]interface{}{"name": name, "createDate": createDate}), nil
+	}
+	return nil, errors.New("unexpected invoke: " + args.Token)
+}
+
+// returns the data of the recorded aws-auth configmap
+func (m *authConfigMapMocks) configMapData(t *testing.T, name string) map[string]string {
+	t.Helper()
+	args, ok := m.configMaps[name]
+	if !ok {
+		t.Fatalf("configmap %s was not created", name)
+	}
+	data := map[string]string{}
+	for key, value := range args.Inputs["data"].ObjectValue() {
+		data[string(key)] = value.StringValue()
+	}
+	return data
+}
+
+func runWithMocks(mocks *authConfigMapMocks, program pulumi.RunFunc) error {
+	return pulumi.RunErr(program, pulumi.WithMocks("project", "stack", mocks))
+}
+
+func testAuthConfigMapMocks() *authConfigMapMocks {
+	return &authConfigMapMocks{
+		// nodegroups a and b share a role, which is mapped once
+		nodeGroupRoles: map[string]string{
+			"a": "arn:aws:iam::123456789012:role/eks/nodes-1",
+			"b": "arn:aws:iam::123456789012:role/eks/nodes-1",
+			"c": "arn:aws:iam::123456789012:role/eks/nodes-2",
+		},
+		ssoRoleArns: []string{
+			testSSORolePrefix + "AWSReservedSSO_Admin_1111",
+			testSSORolePrefix + "AWSReservedSSO_Admin_2222",
+		},
+		ssoRoleCreateDates: map[string]string{
+			"AWSReservedSSO_Admin_1111": "2023-01-01T00:00:00Z",
+			"AWSReservedSSO_Admin_2222": "2023-06-01T00:00:00Z",
+		},
+	}
+}
+
+func testAuthConfigMapInput() AuthConfigMapInput {
+	return AuthConfigMapInput{
+		NodeGroupIamRoleAutoDiscover: true,
+		EKSClusterName:               "platform",
+		FargatePodExecutionRoles:     []string{"arn:aws:iam::123456789012:role/fargate"},
+		AutoDiscoverSSORoles: []SSORolePermissionSetInput{
+			{Name: "Admin", PermissionGroups: []string{"system:masters"}, MultipleMatchStrategy: SSORoleMatchAll},
+		},
+		IAMRoles: []IAMIdentityInput{
+			{Arn: "arn:aws:iam::123456789012:role/ci/deployer", PermissionGroups: []string{"deployers"}},
+		},
+		IAMUsers: []IAMIdentityInput{
+			{Arn: "arn:aws:iam::123456789012:user/alice", PermissionGroups: []string{"viewers"}, Username: "alice-admin"},
+		},
+	}
+}
+
+func TestSyncAuthConfigMap(t *testing.T) {
+	mocks := testAuthConfigMapMocks()
+	err := runWithMocks(mocks, func(ctx *pulumi.Context) error {
+		return SyncAuthConfigMap(ctx, testAuthConfigMapInput())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := mocks.configMapData(t, "aws-auth-configmap")
+	golden.Assert(t, "aws-auth-map-roles.yaml", []byte(data["mapRoles"]))
+	golden.Assert(t, "aws-auth-map-users.yaml", []byte(data["mapUsers"]))
+	// the configmap created by EKS is adopted
+	if id := mocks.configMaps["aws-auth-configmap"].ID; id != "kube-system/aws-auth" {
+		t.Errorf("expected the configmap to be imported as kube-system/aws-auth, got '%s'", id)
+	}
+}
+
+func TestSyncAuthConfigMapInitialImport(t *testing.T) {
+	mocks := testAuthConfigMapMocks()
+	input := testAuthConfigMapInput()
+	input.InitialImport = true
+	input.ResourcePrefix = "spoke"
+	err := runWithMocks(mocks, func(ctx *pulumi.Context) error {
+		return SyncAuthConfigMap(ctx, input)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the node roles, as in the configmap created by EKS
+	data := mocks.configMapData(t, "spoke-aws-auth-configmap")
+	golden.Assert(t, "aws-auth-map-roles-initial-import.yaml", []byte(data["mapRoles"]))
+	if _, ok := data["mapUsers"]; ok {
+		t.Error("expected mapUsers to be omitted")
+	}
+}
+
+func TestSyncAuthConfigMapCreate(t *testing.T) {
+	mocks := testAuthConfigMapMocks()
+	input := testAuthConfigMapInput()
+	input.CreateConfigMap = true
+	err := runWithMocks(mocks, func(ctx *pulumi.Context) error {
+		return SyncAuthConfigMap(ctx, input)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := mocks.configMaps["aws-auth-configmap"].ID; id != "" {
+		t.Errorf("expected the configmap to be created, got import of '%s'", id)
+	}
+}
+
+func TestSyncAuthConfigMapNodeRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		input AuthConfigMapInput
+		err   string
+	}{
+		{
+			name:  "roles combined",
+			input: AuthConfigMapInput{NodeGroupIamRole: "arn:aws:iam::123456789012:role/a", NodeGroupIamRoles: []string{"arn:aws:iam::123456789012:role/b"}},
+		},
+		{
+			name:  "no roles",
+			input: AuthConfigMapInput{},
+			err:   "Node Group IAM Role not supplied, auto discover not enabled",
+		},
+		{
+			name:  "auto discover without cluster",
+			input: AuthConfigMapInput{NodeGroupIamRoleAutoDiscover: true},
+			err:   "EKS cluster name not supplied",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mocks := testAuthConfigMapMocks()
+			err := runWithMocks(mocks, func(ctx *pulumi.Context) error {
+				return SyncAuthConfigMap(ctx, test.input)
+			})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			mapRoles := mocks.configMapData(t, "aws-auth-configmap")["mapRoles"]
+			if strings.Count(mapRoles, "rolearn:") != 2 {
+				t.Errorf("expected both node roles to be mapped, got:\n%s", mapRoles)
+			}
+		})
+	}
+}
+
+func TestDiscoverSSORoles(t *testing.T) {
+	bothRoles := testAuthConfigMapMocks().ssoRoleArns
+	tests := []struct {
+		name     string
+		arns     []string
+		config   SSORolePermissionSetInput
+		roleArns []string
+		err      string
+	}{
+		{
+			name:     "single match",
+			arns:     []string{testSSORolePrefix + "AWSReservedSSO_Admin_1111"},
+			config:   SSORolePermissionSetInput{Name: "Admin"},
+			roleArns: []string{testSSORolePrefix + "AWSReservedSSO_Admin_1111"},
+		},
+		{
+			name:   "no match",
+			config: SSORolePermissionSetInput{Name: "Admin"},
+			err:    "sso role auto discovery failed for Admin, discovered 0",
+		},
+		{
+			name:   "multiple matches fail by default",
+			arns:   bothRoles,
+			config: SSORolePermissionSetInput{Name: "Admin"},
+			err:    "sso role auto discovery failed for Admin, discovered 2",
+		},
+		{
+			name:   "error strategy",
+			arns:   bothRoles,
+			config: SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: SSORoleMatchError},
+			err:    "discovered 2",
+		},
+		{
+			name:     "newest strategy",
+			arns:     bothRoles,
+			config:   SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: SSORoleMatchNewest},
+			roleArns: []string{testSSORolePrefix + "AWSReservedSSO_Admin_2222"},
+		},
+		{
+			name:     "suffix strategy",
+			arns:     bothRoles,
+			config:   SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: SSORoleMatchSuffix, RoleSuffix: "1111"},
+			roleArns: []string{testSSORolePrefix + "AWSReservedSSO_Admin_1111"},
+		},
+		{
+			name:   "suffix strategy without suffix",
+			arns:   bothRoles,
+			config: SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: SSORoleMatchSuffix},
+			err:    "sso role suffix not supplied for Admin",
+		},
+		{
+			name:   "suffix strategy without match",
+			arns:   bothRoles,
+			config: SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: SSORoleMatchSuffix, RoleSuffix: "3333"},
+			err:    "sso role AWSReservedSSO_Admin_3333 not discovered",
+		},
+		{
+			name:     "all strategy",
+			arns:     bothRoles,
+			config:   SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: SSORoleMatchAll},
+			roleArns: bothRoles,
+		},
+		{
+			name:   "unknown strategy",
+			arns:   bothRoles,
+			config: SSORolePermissionSetInput{Name: "Admin", MultipleMatchStrategy: "oldest"},
+			err:    "unknown sso role multiple match strategy: oldest",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mocks := testAuthConfigMapMocks()
+			mocks.ssoRoleArns = test.arns
+			var roleArns []string
+			err := runWithMocks(mocks, func(ctx *pulumi.Context) (err error) {
+				roleArns, err = discoverSSORoles(ctx, test.config)
+				return err
+			})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(roleArns, test.roleArns) {
+				t.Errorf("expected roles %v, got %v", test.roleArns, roleArns)
+			}
+		})
+	}
+}
+
+func TestRemoveArnPath(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:iam::123456789012:role/nodes":          "arn:aws:iam::123456789012:role/nodes",
+		"arn:aws:iam::123456789012:role/eks/nodes":      "arn:aws:iam::123456789012:role/nodes",
+		testSSORolePrefix + "AWSReservedSSO_Admin_1111": "arn:aws:iam::123456789012:role/AWSReservedSSO_Admin_1111",
+		"arn:aws:iam::123456789012:user/people/alice":   "arn:aws:iam::123456789012:user/alice",
+	}
+	for arn, expected := range tests {
+		if actual := removeArnPath(arn); actual != expected {
+			t.Errorf("expected %s without path to be %s, got %s", arn, expected, actual)
+		}
+	}
+}
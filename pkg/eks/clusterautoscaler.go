@@ -0,0 +1,75 @@
+package eks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ClusterAutoscalerInput struct {
+	EKSClusterName string `json:"eks-cluster-name" validate:"required"`
+
+	// optional namespace and service account of the autoscaler, default to
+	// "kube-system" and "cluster-autoscaler"
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"service-account"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type ClusterAutoscalerOutput struct {
+	RoleArn pulumi.StringOutput
+	// kubernetes minor version of the cluster, i.e. "1.24"
+	KubernetesVersion string
+}
+
+// SyncClusterAutoscaler creates the IRSA role of the cluster-autoscaler, allowed to scale the auto scaling groups
+// tagged with k8s.io/cluster-autoscaler/<cluster name>, as node groups of the cluster are. The autoscaler itself is
+// not installed.
+func SyncClusterAutoscaler(ctx *pulumi.Context, config ClusterAutoscalerInput, opts ...pulumi.ResourceOption) (ClusterAutoscalerOutput, error) {
+	var output ClusterAutoscalerOutput
+	if config.EKSClusterName == "" {
+		return output, errors.New("EKS cluster name not supplied, cannot create cluster-autoscaler resources")
+	}
+
+	namespace := "kube-system"
+	if config.Namespace != "" {
+		namespace = config.Namespace
+	}
+	serviceAccount := "cluster-autoscaler"
+	if config.ServiceAccount != "" {
+		serviceAccount = config.ServiceAccount
+	}
+
+	cluster, err := eks.LookupCluster(ctx, &eks.LookupClusterArgs{
+		Name: config.EKSClusterName,
+	})
+	if err != nil {
+		return output, err
+	}
+	output.KubernetesVersion = cluster.Version
+
+	policy, err := iampolicy.ClusterAutoscalerPolicy(config.EKSClusterName).Json()
+	if err != nil {
+		return output, err
+	}
+
+	role, err := NewIrsaRole(ctx, utils.PrefixedName(config.ResourcePrefix, "cluster-autoscaler-role"), IrsaRoleInput{
+		Name:           fmt.Sprintf("ClusterAutoscalerRole-%s", config.EKSClusterName),
+		EKSClusterName: config.EKSClusterName,
+		Namespace:      namespace,
+		ServiceAccount: serviceAccount,
+		InlinePolicy:   policy,
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+	output.RoleArn = role.Arn
+
+	return output, nil
+}
@@ -0,0 +1,94 @@
+package eks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/efs"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type EfsInput struct {
+	// cluster whose nodes mount the filesystem, its vpc and cluster security
+	// group are used for the mount targets
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// subnets to create mount targets in, one per availability zone, i.e. the
+	// private subnet outputs of the vpc
+	SubnetIds []string `json:"subnet-ids"`
+
+	// optional, one of generalPurpose (default) or maxIO
+	PerformanceMode string `json:"performance-mode" validate:"oneof=generalPurpose|maxIO"`
+
+	// optional protection of the filesystem against deletion
+	utils.ProtectionInput
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+// SyncEfsFilesystem creates an encrypted EFS filesystem for the cluster, with a mount target in each given subnet and a
+// security group that allows NFS from the cluster security group. The EFS CSI driver is installed separately, i.e. as
+// the aws-efs-csi-driver eks addon.
+func SyncEfsFilesystem(ctx *pulumi.Context, config EfsInput, opts ...pulumi.ResourceOption) (*efs.FileSystem, error) {
+	if config.EKSClusterName == "" {
+		return nil, errors.New("EKS cluster name not supplied, cannot create EFS filesystem")
+	}
+	if len(config.SubnetIds) == 0 {
+		return nil, errors.New("EFS filesystem requires subnets for its mount targets")
+	}
+	performanceMode := "generalPurpose"
+	if config.PerformanceMode != "" {
+		performanceMode = config.PerformanceMode
+	}
+
+	cluster, err := eks.LookupCluster(ctx, &eks.LookupClusterArgs{
+		Name: config.EKSClusterName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fileSystem, err := efs.NewFileSystem(ctx, utils.PrefixedName(config.ResourcePrefix, "efs-filesystem"), &efs.FileSystemArgs{
+		Encrypted:       pulumi.Bool(true),
+		PerformanceMode: pulumi.String(performanceMode),
+		Tags: pulumi.StringMap{
+			"Name": pulumi.String(fmt.Sprintf("%s-efs", config.EKSClusterName)),
+		},
+	}, utils.ProtectionOpts(config.ProtectionInput, opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	securityGroup, err := ec2.NewSecurityGroup(ctx, utils.PrefixedName(config.ResourcePrefix, "efs-security-group"), &ec2.SecurityGroupArgs{
+		Description: pulumi.String(fmt.Sprintf("NFS access to the EFS filesystem of %s", config.EKSClusterName)),
+		VpcId:       pulumi.String(cluster.VpcConfig.VpcId),
+		Ingress: ec2.SecurityGroupIngressArray{
+			ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int(2049),
+				ToPort:         pulumi.Int(2049),
+				SecurityGroups: pulumi.ToStringArray([]string{cluster.VpcConfig.ClusterSecurityGroupId}),
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subnetId := range config.SubnetIds {
+		_, err = efs.NewMountTarget(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("efs-mount-target-%s", subnetId)), &efs.MountTargetArgs{
+			FileSystemId:   fileSystem.ID(),
+			SubnetId:       pulumi.String(subnetId),
+			SecurityGroups: pulumi.StringArray{securityGroup.ID()},
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fileSystem, nil
+}
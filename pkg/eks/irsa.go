@@ -0,0 +1,200 @@
+package eks
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"net"
+	"strings"
+	"time"
+)
+
+type IrsaRoleInput struct {
+	// name of the IAM role to create
+	Name string `json:"name"`
+
+	// cluster whose OIDC provider is trusted by the role
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// service account allowed to assume the role
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"service-account"`
+
+	// optional list of managed policy arns to attach to the role
+	PolicyArns []string `json:"policy-arns"`
+
+	// optional inline policy document
+	InlinePolicy string `json:"inline-policy"`
+
+	// optional aws provider of the cluster's account and region, the default
+	// provider is used when not set
+	AwsProvider *aws.Provider
+
+	// optional aws provider of another account to create the role in, for
+	// workloads accessing resources of a shared account. the account must
+	// have an OIDC provider of the cluster's issuer, see
+	// NewCrossAccountOIDCProvider
+	RoleAwsProvider *aws.Provider
+}
+
+// NewIrsaRole creates an IAM role that can be assumed by the given kubernetes service account through the cluster's
+// OIDC provider (IAM roles for service accounts). The OIDC provider must already exist for the cluster, and in the
+// account of the RoleAwsProvider for cross-account roles.
+func NewIrsaRole(ctx *pulumi.Context, pulumiResourceName string, input IrsaRoleInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if input.Namespace == "" || input.ServiceAccount == "" {
+		return nil, errors.New("IRSA role requires a namespace and service account")
+	}
+
+	roleProvider := input.AwsProvider
+	if input.RoleAwsProvider != nil {
+		roleProvider = input.RoleAwsProvider
+	}
+	assumeRolePolicy, err := irsaAssumeRolePolicy(ctx, input.EKSClusterName, input.Namespace, input.ServiceAccount, input.AwsProvider, roleProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	roleArgs := &iam.RoleArgs{
+		Name:              pulumi.String(input.Name),
+		AssumeRolePolicy:  pulumi.String(assumeRolePolicy),
+		ManagedPolicyArns: pulumi.ToStringArray(input.PolicyArns),
+	}
+	if input.InlinePolicy != "" {
+		roleArgs.InlinePolicies = iam.RoleInlinePolicyArray{
+			iam.RoleInlinePolicyArgs{
+				Name:   pulumi.String(input.Name),
+				Policy: pulumi.String(input.InlinePolicy),
+			},
+		}
+	}
+
+	return iam.NewRole(ctx, pulumiResourceName, roleArgs, awsResourceOpts(roleProvider, opts)...)
+}
+
+// renders the trust policy of an IRSA role, trusting the OIDC provider of
+// the cluster's issuer in the account of the role provider
+func irsaAssumeRolePolicy(ctx *pulumi.Context, clusterName string, namespace string, serviceAccount string, clusterProvider *aws.Provider, roleProvider *aws.Provider) (string, error) {
+	issuer, err := discoverOIDCIssuer(ctx, clusterName, awsInvokeOpts(clusterProvider)...)
+	if err != nil {
+		return "", err
+	}
+	providerArn, err := oidcProviderArn(ctx, issuer, awsInvokeOpts(roleProvider)...)
+	if err != nil {
+		return "", err
+	}
+
+	return iampolicy.NewDocument(
+		iampolicy.Allow("sts:AssumeRoleWithWebIdentity").
+			For("Federated", providerArn).
+			When("StringEquals", fmt.Sprintf("%s:sub", issuer), fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount)).
+			When("StringEquals", fmt.Sprintf("%s:aud", issuer), "sts.amazonaws.com"),
+	).Json()
+}
+
+type CrossAccountOIDCProviderInput struct {
+	// cluster whose OIDC issuer is registered in the other account
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// optional aws provider of the cluster's account and region, the default
+	// provider is used when not set
+	AwsProvider *aws.Provider
+	// aws provider of the account to register the issuer in
+	RoleAwsProvider *aws.Provider
+
+	// optional sha1 thumbprints of the issuer's root certificate, read from
+	// the issuer's TLS chain when not set
+	Thumbprints []string `json:"thumbprints"`
+}
+
+// NewCrossAccountOIDCProvider registers the OIDC issuer of a cluster as an IAM OIDC provider of another account, so
+// that IRSA roles of that account can trust the cluster's service accounts. The thumbprint of the issuer's root
+// certificate is read from its TLS chain unless supplied. There can only be one provider per issuer and account,
+// shared by all cross-account roles.
+func NewCrossAccountOIDCProvider(ctx *pulumi.Context, pulumiResourceName string, input CrossAccountOIDCProviderInput, opts ...pulumi.ResourceOption) (*iam.OpenIdConnectProvider, error) {
+	if input.RoleAwsProvider == nil {
+		return nil, errors.New("role aws provider not supplied, cannot create cross-account OIDC provider")
+	}
+	issuer, err := discoverOIDCIssuer(ctx, input.EKSClusterName, awsInvokeOpts(input.AwsProvider)...)
+	if err != nil {
+		return nil, err
+	}
+	thumbprints := input.Thumbprints
+	if len(thumbprints) == 0 {
+		thumbprint, err := oidcIssuerThumbprint(issuer)
+		if err != nil {
+			return nil, err
+		}
+		thumbprints = []string{thumbprint}
+	}
+
+	return iam.NewOpenIdConnectProvider(ctx, pulumiResourceName, &iam.OpenIdConnectProviderArgs{
+		Url:             pulumi.String("https://" + issuer),
+		ClientIdLists:   pulumi.ToStringArray([]string{"sts.amazonaws.com"}),
+		ThumbprintLists: pulumi.ToStringArray(thumbprints),
+	}, awsResourceOpts(input.RoleAwsProvider, opts)...)
+}
+
+// how long reading the TLS chain of an OIDC issuer may take, so that an
+// unreachable issuer fails the deployment instead of hanging it
+const oidcIssuerDialTimeout = 10 * time.Second
+
+// returns the sha1 thumbprint of the root certificate that the issuer's host
+// presents, as IAM expects of OIDC providers
+func oidcIssuerThumbprint(issuer string) (string, error) {
+	host := strings.SplitN(issuer, "/", 2)[0]
+	// the dialer's timeout covers both the connection and the TLS handshake
+	dialer := &net.Dialer{Timeout: oidcIssuerDialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host+":443", &tls.Config{ServerName: host})
+	if err != nil {
+		return "", fmt.Errorf("reading the TLS chain of OIDC issuer %s: %v", issuer, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(oidcIssuerDialTimeout)); err != nil {
+		return "", fmt.Errorf("reading the TLS chain of OIDC issuer %s: %v", issuer, err)
+	}
+	chains := conn.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return "", fmt.Errorf("OIDC issuer %s presented no verified certificate chain", issuer)
+	}
+	root := chains[0][len(chains[0])-1]
+	return fmt.Sprintf("%x", sha1.Sum(root.Raw)), nil
+}
+
+// looks up the cluster's OIDC issuer. the returned issuer has the https://
+// scheme removed, as used in trust policy condition keys
+func discoverOIDCIssuer(ctx *pulumi.Context, clusterName string, opts ...pulumi.InvokeOption) (string, error) {
+	if clusterName == "" {
+		return "", errors.New("EKS cluster name not supplied, cannot discover OIDC provider")
+	}
+
+	cluster, err := eks.LookupCluster(ctx, &eks.LookupClusterArgs{
+		Name: clusterName,
+	}, opts...)
+	if err != nil {
+		return "", err
+	}
+	if len(cluster.Identities) == 0 || len(cluster.Identities[0].Oidcs) == 0 {
+		return "", fmt.Errorf("EKS cluster %s has no OIDC issuer", clusterName)
+	}
+	return strings.TrimPrefix(cluster.Identities[0].Oidcs[0].Issuer, "https://"), nil
+}
+
+// derives the arn of the IAM OIDC provider of an issuer in the account of
+// the invoke options
+func oidcProviderArn(ctx *pulumi.Context, issuer string, opts ...pulumi.InvokeOption) (string, error) {
+	callerIdentity, err := aws.GetCallerIdentity(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	partition, err := aws.GetPartition(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:oidc-provider/%s", partition.Partition, callerIdentity.AccountId, issuer), nil
+}
@@ -0,0 +1,256 @@
+package eks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/sqs"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type KarpenterInput struct {
+	EKSClusterName string `json:"eks-cluster-name" validate:"required"`
+
+	// optional namespace and service account of the karpenter controller,
+	// both default to "karpenter"
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"service-account"`
+
+	// optional subnets and security groups to tag for karpenter discovery
+	SubnetIds        []string `json:"subnet-ids"`
+	SecurityGroupIds []string `json:"security-group-ids"`
+
+	// optional, disables the interruption handling queue and event rules
+	DisableInterruptionHandling bool `json:"disable-interruption-handling"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type KarpenterOutput struct {
+	ControllerRoleArn   pulumi.StringOutput
+	NodeRoleArn         pulumi.StringOutput
+	InstanceProfileName pulumi.StringOutput
+	// empty when interruption handling is disabled
+	InterruptionQueueName pulumi.StringOutput
+}
+
+// events that karpenter handles from its interruption queue
+// https://karpenter.sh/docs/concepts/disruption/#interruption
+var karpenterInterruptionEvents = map[string]map[string]interface{}{
+	"health-event": {
+		"source":      []string{"aws.health"},
+		"detail-type": []string{"AWS Health Event"},
+	},
+	"spot-interruption": {
+		"source":      []string{"aws.ec2"},
+		"detail-type": []string{"EC2 Spot Instance Interruption Warning"},
+	},
+	"rebalance": {
+		"source":      []string{"aws.ec2"},
+		"detail-type": []string{"EC2 Instance Rebalance Recommendation"},
+	},
+	"instance-state-change": {
+		"source":      []string{"aws.ec2"},
+		"detail-type": []string{"EC2 Instance State-change Notification"},
+	},
+}
+
+// SyncKarpenter creates the AWS infrastructure that karpenter needs: the controller IRSA role, the node IAM role and
+// instance profile, the interruption handling queue with its event rules, and karpenter.sh/discovery tags on the given
+// subnets and security groups. Karpenter itself is not installed. The node role must also be mapped in the aws-auth
+// configmap so that nodes can join the cluster.
+func SyncKarpenter(ctx *pulumi.Context, config KarpenterInput, opts ...pulumi.ResourceOption) (KarpenterOutput, error) {
+	var output KarpenterOutput
+	if config.EKSClusterName == "" {
+		return output, errors.New("EKS cluster name not supplied, cannot create karpenter resources")
+	}
+
+	namespace := "karpenter"
+	if config.Namespace != "" {
+		namespace = config.Namespace
+	}
+	serviceAccount := "karpenter"
+	if config.ServiceAccount != "" {
+		serviceAccount = config.ServiceAccount
+	}
+
+	// node role and instance profile used by provisioned nodes
+	nodeAssumeRolePolicy, err := iampolicy.NewDocument(
+		iampolicy.Allow("sts:AssumeRole").For("Service", "ec2.amazonaws.com"),
+	).Json()
+	if err != nil {
+		return output, err
+	}
+	nodeRole, err := iam.NewRole(ctx, utils.PrefixedName(config.ResourcePrefix, "karpenter-node-role"), &iam.RoleArgs{
+		Name:             pulumi.String(fmt.Sprintf("KarpenterNodeRole-%s", config.EKSClusterName)),
+		AssumeRolePolicy: pulumi.String(nodeAssumeRolePolicy),
+		ManagedPolicyArns: pulumi.ToStringArray([]string{
+			"arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy",
+			"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy",
+			"arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
+			"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
+		}),
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+	instanceProfile, err := iam.NewInstanceProfile(ctx, utils.PrefixedName(config.ResourcePrefix, "karpenter-node-instance-profile"), &iam.InstanceProfileArgs{
+		Name: pulumi.String(fmt.Sprintf("KarpenterNodeInstanceProfile-%s", config.EKSClusterName)),
+		Role: nodeRole.Name,
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+
+	// interruption handling queue, fed by eventbridge rules
+	queueArn := pulumi.String("").ToStringOutput()
+	output.InterruptionQueueName = pulumi.String("").ToStringOutput()
+	if !config.DisableInterruptionHandling {
+		queue, err := syncKarpenterInterruptionQueue(ctx, config, opts...)
+		if err != nil {
+			return output, err
+		}
+		queueArn = queue.Arn
+		output.InterruptionQueueName = queue.Name
+	}
+
+	// controller role, assumed by the karpenter service account
+	controllerRole, err := NewIrsaRole(ctx, utils.PrefixedName(config.ResourcePrefix, "karpenter-controller-role"), IrsaRoleInput{
+		Name:           fmt.Sprintf("KarpenterControllerRole-%s", config.EKSClusterName),
+		EKSClusterName: config.EKSClusterName,
+		Namespace:      namespace,
+		ServiceAccount: serviceAccount,
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+	_, err = iam.NewRolePolicy(ctx, utils.PrefixedName(config.ResourcePrefix, "karpenter-controller-policy"), &iam.RolePolicyArgs{
+		Role: controllerRole.Name,
+		Policy: pulumi.All(nodeRole.Arn, queueArn).ApplyT(func(args []interface{}) (string, error) {
+			return karpenterControllerPolicy(config.EKSClusterName, args[0].(string), args[1].(string))
+		}).(pulumi.StringOutput),
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+
+	// tag subnets and security groups so karpenter can discover them
+	for _, subnetId := range config.SubnetIds {
+		_, err = ec2.NewTag(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("karpenter-discovery-%s", subnetId)), &ec2.TagArgs{
+			ResourceId: pulumi.String(subnetId),
+			Key:        pulumi.String("karpenter.sh/discovery"),
+			Value:      pulumi.String(config.EKSClusterName),
+		}, opts...)
+		if err != nil {
+			return output, err
+		}
+	}
+	for _, securityGroupId := range config.SecurityGroupIds {
+		_, err = ec2.NewTag(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("karpenter-discovery-%s", securityGroupId)), &ec2.TagArgs{
+			ResourceId: pulumi.String(securityGroupId),
+			Key:        pulumi.String("karpenter.sh/discovery"),
+			Value:      pulumi.String(config.EKSClusterName),
+		}, opts...)
+		if err != nil {
+			return output, err
+		}
+	}
+
+	output.ControllerRoleArn = controllerRole.Arn
+	output.NodeRoleArn = nodeRole.Arn
+	output.InstanceProfileName = instanceProfile.Name
+	return output, nil
+}
+
+func syncKarpenterInterruptionQueue(ctx *pulumi.Context, config KarpenterInput, opts ...pulumi.ResourceOption) (*sqs.Queue, error) {
+	queue, err := sqs.NewQueue(ctx, utils.PrefixedName(config.ResourcePrefix, "karpenter-interruption-queue"), &sqs.QueueArgs{
+		Name:                    pulumi.String(fmt.Sprintf("karpenter-%s", config.EKSClusterName)),
+		MessageRetentionSeconds: pulumi.Int(300),
+		SqsManagedSseEnabled:    pulumi.Bool(true),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// allow eventbridge to deliver to the queue
+	_, err = sqs.NewQueuePolicy(ctx, utils.PrefixedName(config.ResourcePrefix, "karpenter-interruption-queue-policy"), &sqs.QueuePolicyArgs{
+		QueueUrl: queue.Url,
+		Policy: queue.Arn.ApplyT(func(arn string) (string, error) {
+			return iampolicy.NewDocument(
+				iampolicy.Allow("sqs:SendMessage").
+					For("Service", "events.amazonaws.com", "sqs.amazonaws.com").
+					On(arn),
+			).Json()
+		}).(pulumi.StringOutput),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, pattern := range karpenterInterruptionEvents {
+		eventPattern, err := json.Marshal(pattern)
+		if err != nil {
+			return nil, err
+		}
+		rule, err := cloudwatch.NewEventRule(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("karpenter-%s-rule", name)), &cloudwatch.EventRuleArgs{
+			EventPattern: pulumi.String(string(eventPattern)),
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		_, err = cloudwatch.NewEventTarget(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("karpenter-%s-target", name)), &cloudwatch.EventTargetArgs{
+			Rule:     rule.Name,
+			TargetId: pulumi.String("KarpenterInterruptionQueueTarget"),
+			Arn:      queue.Arn,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return queue, nil
+}
+
+// karpenter controller permissions, scoped where practical
+// https://karpenter.sh/docs/reference/cloudformation/
+func karpenterControllerPolicy(clusterName string, nodeRoleArn string, queueArn string) (string, error) {
+	statements := []iampolicy.Statement{
+		iampolicy.Allow(
+			"ec2:CreateFleet",
+			"ec2:CreateLaunchTemplate",
+			"ec2:CreateTags",
+			"ec2:DeleteLaunchTemplate",
+			"ec2:DescribeAvailabilityZones",
+			"ec2:DescribeImages",
+			"ec2:DescribeInstances",
+			"ec2:DescribeInstanceTypeOfferings",
+			"ec2:DescribeInstanceTypes",
+			"ec2:DescribeLaunchTemplates",
+			"ec2:DescribeSecurityGroups",
+			"ec2:DescribeSpotPriceHistory",
+			"ec2:DescribeSubnets",
+			"ec2:RunInstances",
+			"ec2:TerminateInstances",
+			"pricing:GetProducts",
+			"ssm:GetParameter",
+		).On("*"),
+		iampolicy.Allow("iam:PassRole").On(nodeRoleArn),
+		iampolicy.Allow("eks:DescribeCluster").On(fmt.Sprintf("arn:*:eks:*:*:cluster/%s", clusterName)),
+	}
+	if queueArn != "" {
+		statements = append(statements, iampolicy.Allow(
+			"sqs:DeleteMessage",
+			"sqs:GetQueueUrl",
+			"sqs:GetQueueAttributes",
+			"sqs:ReceiveMessage",
+		).On(queueArn))
+	}
+	return iampolicy.NewDocument(statements...).Json()
+}
@@ -0,0 +1,119 @@
+package eks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v2"
+)
+
+type KubeconfigInput struct {
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// optional, one of "aws-cli" (default) which uses `aws eks get-token`, or
+	// "aws-iam-authenticator"
+	AuthMethod string `json:"auth-method"`
+
+	// optional role to assume when authenticating to the cluster
+	KubeConfigAssumeRoleArn string `json:"kubeconfig-assume-role-arn"`
+
+	// optional aws profile to use when authenticating to the cluster
+	KubeConfigAwsProfile string `json:"kubeconfig-aws-profile"`
+}
+
+// kubeconfig auth methods
+const (
+	KubeconfigAuthAwsCli              = "aws-cli"
+	KubeconfigAuthAwsIamAuthenticator = "aws-iam-authenticator"
+)
+
+// GetKubeconfig renders a kubeconfig for the cluster from its endpoint and certificate authority, authenticating with
+// exec auth, and returns it along with a kubernetes provider configured with it, for use by downstream modules.
+func GetKubeconfig(ctx *pulumi.Context, pulumiResourceName string, config KubeconfigInput, opts ...pulumi.ResourceOption) (pulumi.StringOutput, *kubernetes.Provider, error) {
+	var kubeconfig pulumi.StringOutput
+	if config.EKSClusterName == "" {
+		return kubeconfig, nil, errors.New("EKS cluster name not supplied, cannot render kubeconfig")
+	}
+
+	cluster := eks.LookupClusterOutput(ctx, eks.LookupClusterOutputArgs{
+		Name: pulumi.String(config.EKSClusterName),
+	})
+	kubeconfig = pulumi.All(cluster.Endpoint(), cluster.CertificateAuthority().Data()).ApplyT(func(args []interface{}) (string, error) {
+		return renderKubeconfig(config, args[0].(string), args[1].(string))
+	}).(pulumi.StringOutput)
+
+	provider, err := kubernetes.NewProvider(ctx, pulumiResourceName, &kubernetes.ProviderArgs{
+		Kubeconfig: kubeconfig,
+	}, opts...)
+	return kubeconfig, provider, err
+}
+
+func renderKubeconfig(config KubeconfigInput, endpoint string, certificateAuthorityData string) (string, error) {
+	var command string
+	var args []string
+	switch config.AuthMethod {
+	case "", KubeconfigAuthAwsCli:
+		command = "aws"
+		args = []string{"eks", "get-token", "--cluster-name", config.EKSClusterName}
+		if config.KubeConfigAssumeRoleArn != "" {
+			args = append(args, "--role-arn", config.KubeConfigAssumeRoleArn)
+		}
+	case KubeconfigAuthAwsIamAuthenticator:
+		command = "aws-iam-authenticator"
+		args = []string{"token", "-i", config.EKSClusterName}
+		if config.KubeConfigAssumeRoleArn != "" {
+			args = append(args, "-r", config.KubeConfigAssumeRoleArn)
+		}
+	default:
+		return "", errors.New(fmt.Sprintf("unknown kubeconfig auth method: %s", config.AuthMethod))
+	}
+
+	exec := map[string]interface{}{
+		"apiVersion": "client.authentication.k8s.io/v1beta1",
+		"command":    command,
+		"args":       args,
+	}
+	if config.KubeConfigAwsProfile != "" {
+		exec["env"] = []map[string]string{
+			{
+				"name":  "AWS_PROFILE",
+				"value": config.KubeConfigAwsProfile,
+			},
+		}
+	}
+
+	kubeconfig, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": config.EKSClusterName,
+		"clusters": []map[string]interface{}{
+			{
+				"name": config.EKSClusterName,
+				"cluster": map[string]interface{}{
+					"server":                     endpoint,
+					"certificate-authority-data": certificateAuthorityData,
+				},
+			},
+		},
+		"contexts": []map[string]interface{}{
+			{
+				"name": config.EKSClusterName,
+				"context": map[string]interface{}{
+					"cluster": config.EKSClusterName,
+					"user":    config.EKSClusterName,
+				},
+			},
+		},
+		"users": []map[string]interface{}{
+			{
+				"name": config.EKSClusterName,
+				"user": map[string]interface{}{
+					"exec": exec,
+				},
+			},
+		},
+	})
+	return string(kubeconfig), err
+}
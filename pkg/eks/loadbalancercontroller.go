@@ -0,0 +1,96 @@
+package eks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type LoadBalancerControllerInput struct {
+	EKSClusterName string `json:"eks-cluster-name" validate:"required"`
+
+	// optional namespace and service account of the controller, default to
+	// "kube-system" and "aws-load-balancer-controller"
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"service-account"`
+
+	// optional subnets to tag for load balancer discovery, i.e. the public and
+	// private subnet outputs of the vpc
+	PublicSubnetIds  []string `json:"public-subnet-ids"`
+	PrivateSubnetIds []string `json:"private-subnet-ids"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type LoadBalancerControllerOutput struct {
+	ControllerRoleArn pulumi.StringOutput
+}
+
+// SyncLoadBalancerController creates the AWS infrastructure that the aws-load-balancer-controller needs: the controller
+// IRSA role, and the kubernetes.io/role tags on the given subnets that the controller discovers subnets with. The
+// controller itself is not installed.
+func SyncLoadBalancerController(ctx *pulumi.Context, config LoadBalancerControllerInput, opts ...pulumi.ResourceOption) (LoadBalancerControllerOutput, error) {
+	var output LoadBalancerControllerOutput
+	if config.EKSClusterName == "" {
+		return output, errors.New("EKS cluster name not supplied, cannot create load balancer controller resources")
+	}
+
+	namespace := "kube-system"
+	if config.Namespace != "" {
+		namespace = config.Namespace
+	}
+	serviceAccount := "aws-load-balancer-controller"
+	if config.ServiceAccount != "" {
+		serviceAccount = config.ServiceAccount
+	}
+
+	policy, err := iampolicy.LoadBalancerControllerPolicy().Json()
+	if err != nil {
+		return output, err
+	}
+	role, err := NewIrsaRole(ctx, utils.PrefixedName(config.ResourcePrefix, "load-balancer-controller-role"), IrsaRoleInput{
+		Name:           fmt.Sprintf("LoadBalancerControllerRole-%s", config.EKSClusterName),
+		EKSClusterName: config.EKSClusterName,
+		Namespace:      namespace,
+		ServiceAccount: serviceAccount,
+		InlinePolicy:   policy,
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+
+	// tag subnets so the controller can discover them, internet facing load
+	// balancers are placed in public subnets and internal ones in private
+	subnetRoles := map[string][]string{
+		"elb":          config.PublicSubnetIds,
+		"internal-elb": config.PrivateSubnetIds,
+	}
+	for _, subnetRole := range []string{"elb", "internal-elb"} {
+		for _, subnetId := range subnetRoles[subnetRole] {
+			_, err = ec2.NewTag(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("load-balancer-controller-%s-%s", subnetRole, subnetId)), &ec2.TagArgs{
+				ResourceId: pulumi.String(subnetId),
+				Key:        pulumi.String(fmt.Sprintf("kubernetes.io/role/%s", subnetRole)),
+				Value:      pulumi.String("1"),
+			}, opts...)
+			if err != nil {
+				return output, err
+			}
+			_, err = ec2.NewTag(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("load-balancer-controller-cluster-%s", subnetId)), &ec2.TagArgs{
+				ResourceId: pulumi.String(subnetId),
+				Key:        pulumi.String(fmt.Sprintf("kubernetes.io/cluster/%s", config.EKSClusterName)),
+				Value:      pulumi.String("shared"),
+			}, opts...)
+			if err != nil {
+				return output, err
+			}
+		}
+	}
+
+	output.ControllerRoleArn = role.Arn
+	return output, nil
+}
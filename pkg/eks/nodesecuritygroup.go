@@ -0,0 +1,91 @@
+package eks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type NodeSecurityGroupRulesInput struct {
+	// cluster whose nodes the rules apply to
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// optional security group of the nodes, defaults to the cluster security
+	// group that managed node groups and karpenter nodes use
+	NodeSecurityGroupId string `json:"node-security-group-id"`
+
+	// ingress rules of the nodes
+	Rules []NodeSecurityGroupRuleInput `json:"rules"`
+
+	// optional aws provider of the cluster's account and region, the default
+	// provider is used when not set
+	AwsProvider *aws.Provider
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type NodeSecurityGroupRuleInput struct {
+	// name of the rule, unique within the stack
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	// tcp or udp
+	Protocol string `json:"protocol" validate:"oneof=tcp|udp"`
+	Port     int    `json:"port" validate:"required"`
+	// optional, allows the port from the control plane, i.e. for admission
+	// webhooks, instead of from the other nodes
+	FromControlPlane bool `json:"from-control-plane"`
+}
+
+// SyncNodeSecurityGroupRules allows the given ports on the nodes of the cluster, either from the other nodes or from
+// the control plane, i.e. for CNI health checks, tunnels, and admission webhooks of add-ons. Rules already covered by
+// the cluster security group are harmless, so they can be created for any node setup.
+func SyncNodeSecurityGroupRules(ctx *pulumi.Context, config NodeSecurityGroupRulesInput, opts ...pulumi.ResourceOption) ([]pulumi.Resource, error) {
+	if config.EKSClusterName == "" {
+		return nil, errors.New("EKS cluster name not supplied, cannot create node security group rules")
+	}
+
+	cluster, err := eks.LookupCluster(ctx, &eks.LookupClusterArgs{
+		Name: config.EKSClusterName,
+	}, awsInvokeOpts(config.AwsProvider)...)
+	if err != nil {
+		return nil, err
+	}
+	clusterSecurityGroupId := cluster.VpcConfig.ClusterSecurityGroupId
+	nodeSecurityGroupId := clusterSecurityGroupId
+	if config.NodeSecurityGroupId != "" {
+		nodeSecurityGroupId = config.NodeSecurityGroupId
+	}
+
+	var resources []pulumi.Resource
+	for _, rule := range config.Rules {
+		protocol := "tcp"
+		if rule.Protocol != "" {
+			protocol = rule.Protocol
+		}
+		args := &ec2.SecurityGroupRuleArgs{
+			Type:            pulumi.String("ingress"),
+			Description:     pulumi.String(rule.Description),
+			SecurityGroupId: pulumi.String(nodeSecurityGroupId),
+			Protocol:        pulumi.String(protocol),
+			FromPort:        pulumi.Int(rule.Port),
+			ToPort:          pulumi.Int(rule.Port),
+		}
+		if rule.FromControlPlane {
+			args.SourceSecurityGroupId = pulumi.String(clusterSecurityGroupId)
+		} else {
+			args.Self = pulumi.Bool(true)
+		}
+		securityGroupRule, err := ec2.NewSecurityGroupRule(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("node-security-group-rule-%s", rule.Name)), args, awsResourceOpts(config.AwsProvider, opts)...)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, securityGroupRule)
+	}
+	return resources, nil
+}
@@ -0,0 +1,25 @@
+package eks
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// returns the options that run lookups of existing aws resources with the
+// given provider, i.e. one of another account or region. lookups use the
+// default provider when it is nil
+func awsInvokeOpts(provider *aws.Provider) []pulumi.InvokeOption {
+	if provider == nil {
+		return nil
+	}
+	return []pulumi.InvokeOption{pulumi.Provider(provider)}
+}
+
+// returns the options with the given aws provider appended, the options are
+// returned as is when it is nil
+func awsResourceOpts(provider *aws.Provider, opts []pulumi.ResourceOption) []pulumi.ResourceOption {
+	if provider == nil {
+		return opts
+	}
+	return append(append([]pulumi.ResourceOption{}, opts...), pulumi.Provider(provider))
+}
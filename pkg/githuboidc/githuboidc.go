@@ -0,0 +1,175 @@
+package githuboidc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/s3"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	githubOidcUrl    = "https://token.actions.githubusercontent.com"
+	githubOidcIssuer = "token.actions.githubusercontent.com"
+)
+
+// thumbprints of the github actions token issuer certificates, no longer
+// verified by IAM for github but still required by the provider resource
+var githubOidcThumbprints = []string{
+	"6938fd4d98bab03faadb97b34396831e3780aea1",
+	"1c58a3a8518e8759bf075b76b750d4f2df264fcd",
+}
+
+type GithubOidcInput struct {
+	// optional arn of an existing github OIDC provider, there can only be one
+	// per account. the provider is created when not supplied
+	ProviderArn string `json:"provider-arn"`
+
+	// roles that github actions workflows may assume
+	Roles []GithubRoleInput `json:"roles"`
+
+	// optional protection of the OIDC provider against deletion, other
+	// stacks of the account may depend on it
+	utils.ProtectionInput
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// more than once in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type GithubRoleInput struct {
+	// name of the IAM role to create
+	Name string `json:"name"`
+	// repository allowed to assume the role, i.e. "catalystcommunity/app"
+	Repository string `json:"repository"`
+	// optional branches and environments the workflows must run for, any ref
+	// of the repository may assume the role when neither are supplied
+	Branches     []string `json:"branches"`
+	Environments []string `json:"environments"`
+
+	// optional ecr repositories the role may push images to
+	EcrRepositoryArns []string `json:"ecr-repository-arns"`
+	// optional eks clusters the role may describe, i.e. for aws eks
+	// update-kubeconfig
+	EksClusterArns []string `json:"eks-cluster-arns"`
+	// optional buckets the role may deploy objects to
+	S3BucketNames []string `json:"s3-bucket-names"`
+	// optional additional managed policies
+	PolicyArns []string `json:"policy-arns"`
+}
+
+type GithubOidcOutput struct {
+	ProviderArn pulumi.StringOutput
+	// arns of the created roles, by role name
+	RoleArns map[string]pulumi.StringOutput
+}
+
+// SyncGithubOidc creates the github actions OIDC provider and IAM roles scoped to repositories and branches, so that
+// workflows can authenticate to AWS without long lived keys.
+func SyncGithubOidc(ctx *pulumi.Context, config GithubOidcInput, opts ...pulumi.ResourceOption) (GithubOidcOutput, error) {
+	output := GithubOidcOutput{
+		RoleArns: map[string]pulumi.StringOutput{},
+	}
+	if config.ProviderArn != "" {
+		output.ProviderArn = pulumi.String(config.ProviderArn).ToStringOutput()
+	} else {
+		provider, err := iam.NewOpenIdConnectProvider(ctx, utils.PrefixedName(config.ResourcePrefix, "github-oidc-provider"), &iam.OpenIdConnectProviderArgs{
+			Url:             pulumi.String(githubOidcUrl),
+			ClientIdLists:   pulumi.ToStringArray([]string{"sts.amazonaws.com"}),
+			ThumbprintLists: pulumi.ToStringArray(githubOidcThumbprints),
+		}, utils.ProtectionOpts(config.ProtectionInput, opts)...)
+		if err != nil {
+			return output, err
+		}
+		output.ProviderArn = provider.Arn
+	}
+
+	for _, roleConfig := range config.Roles {
+		role, err := newGithubRole(ctx, config.ResourcePrefix, output.ProviderArn, roleConfig, opts...)
+		if err != nil {
+			return output, err
+		}
+		output.RoleArns[roleConfig.Name] = role.Arn
+	}
+
+	return output, nil
+}
+
+func newGithubRole(ctx *pulumi.Context, resourcePrefix string, providerArn pulumi.StringOutput, config GithubRoleInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if config.Name == "" || config.Repository == "" {
+		return nil, errors.New("github role requires a name and repository")
+	}
+
+	var subjects []string
+	for _, branch := range config.Branches {
+		subjects = append(subjects, fmt.Sprintf("repo:%s:ref:refs/heads/%s", config.Repository, branch))
+	}
+	for _, environment := range config.Environments {
+		subjects = append(subjects, fmt.Sprintf("repo:%s:environment:%s", config.Repository, environment))
+	}
+	if len(subjects) == 0 {
+		subjects = []string{fmt.Sprintf("repo:%s:*", config.Repository)}
+	}
+
+	assumeRolePolicy := providerArn.ApplyT(func(providerArn string) (string, error) {
+		return iampolicy.NewDocument(
+			iampolicy.Allow("sts:AssumeRoleWithWebIdentity").
+				For("Federated", providerArn).
+				When("StringEquals", githubOidcIssuer+":aud", "sts.amazonaws.com").
+				When("StringLike", githubOidcIssuer+":sub", subjects...),
+		).Json()
+	}).(pulumi.StringOutput)
+
+	roleArgs := &iam.RoleArgs{
+		Name:              pulumi.String(config.Name),
+		AssumeRolePolicy:  assumeRolePolicy,
+		ManagedPolicyArns: pulumi.ToStringArray(config.PolicyArns),
+	}
+	inlinePolicy, err := githubRolePolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	if inlinePolicy != "" {
+		roleArgs.InlinePolicies = iam.RoleInlinePolicyArray{
+			iam.RoleInlinePolicyArgs{
+				Name:   pulumi.String(config.Name),
+				Policy: pulumi.String(inlinePolicy),
+			},
+		}
+	}
+
+	return iam.NewRole(ctx, utils.PrefixedName(resourcePrefix, fmt.Sprintf("github-role-%s", config.Name)), roleArgs, opts...)
+}
+
+// renders the inline policy of the ecr push, eks describe, and s3 deploy
+// grants of a role, empty when there are none
+func githubRolePolicy(config GithubRoleInput) (string, error) {
+	var statements []iampolicy.Statement
+	if len(config.EcrRepositoryArns) != 0 {
+		statements = append(statements,
+			iampolicy.Allow("ecr:GetAuthorizationToken").On("*"),
+			iampolicy.Allow(
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:BatchGetImage",
+				"ecr:CompleteLayerUpload",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:InitiateLayerUpload",
+				"ecr:PutImage",
+				"ecr:UploadLayerPart",
+			).On(config.EcrRepositoryArns...),
+		)
+	}
+	if len(config.EksClusterArns) != 0 {
+		statements = append(statements, iampolicy.Allow("eks:DescribeCluster").On(config.EksClusterArns...))
+	}
+	for _, bucketName := range config.S3BucketNames {
+		statements = append(statements, s3.BucketAccessStatements(bucketName, "", false)...)
+	}
+	if len(statements) == 0 {
+		return "", nil
+	}
+
+	return iampolicy.NewDocument(statements...).Json()
+}
@@ -0,0 +1,193 @@
+package iampolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// the only policy language version with policy variables
+const Version = "2012-10-17"
+
+// statement effects
+const (
+	EffectAllow = "Allow"
+	EffectDeny  = "Deny"
+)
+
+// Document is an IAM policy document. Its fields, like those of Statement, are in alphabetical order, so that documents
+// marshal like the maps the modules used to build policies from and existing policies do not change.
+type Document struct {
+	Statement []Statement `json:"Statement"`
+	Version   string      `json:"Version"`
+}
+
+// Statement is a statement of a policy document
+type Statement struct {
+	Action    StringList            `json:"Action"`
+	Condition Condition             `json:"Condition,omitempty"`
+	Effect    string                `json:"Effect"`
+	Principal map[string]StringList `json:"Principal,omitempty"`
+	Resource  StringList            `json:"Resource,omitempty"`
+	Sid       string                `json:"Sid,omitempty"`
+}
+
+// Condition maps condition operators, i.e. StringEquals, to the condition keys and values they compare
+type Condition map[string]map[string]StringList
+
+// StringList is a list of strings that marshals a single string as a string, i.e. the action of a statement with one
+// action
+type StringList []string
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var value string
+	if json.Unmarshal(data, &value) == nil {
+		*l = StringList{value}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// NewDocument creates a policy document of the given statements
+func NewDocument(statements ...Statement) Document {
+	return Document{
+		Version:   Version,
+		Statement: statements,
+	}
+}
+
+// Allow creates a statement allowing the given actions, see Statement.On for its resources
+func Allow(actions ...string) Statement {
+	return Statement{
+		Effect: EffectAllow,
+		Action: actions,
+	}
+}
+
+// Deny creates a statement denying the given actions, see Statement.On for its resources
+func Deny(actions ...string) Statement {
+	return Statement{
+		Effect: EffectDeny,
+		Action: actions,
+	}
+}
+
+// On returns a copy of the statement that applies to the given resources
+func (s Statement) On(resources ...string) Statement {
+	s.Resource = append(append(StringList{}, s.Resource...), resources...)
+	return s
+}
+
+// For returns a copy of the statement that applies to the given principals of a type, i.e. "AWS", "Service", or
+// "Federated"
+func (s Statement) For(principalType string, principals ...string) Statement {
+	principal := map[string]StringList{}
+	for key, values := range s.Principal {
+		principal[key] = values
+	}
+	principal[principalType] = append(append(StringList{}, principal[principalType]...), principals...)
+	s.Principal = principal
+	return s
+}
+
+// When returns a copy of the statement with a condition comparing the key with the operator to any of the values,
+// i.e. When("StringEquals", "aws:ResourceTag/owner", "platform")
+func (s Statement) When(operator string, key string, values ...string) Statement {
+	condition := Condition{}
+	for op, keys := range s.Condition {
+		condition[op] = map[string]StringList{}
+		for k, v := range keys {
+			condition[op][k] = v
+		}
+	}
+	if condition[operator] == nil {
+		condition[operator] = map[string]StringList{}
+	}
+	condition[operator][key] = values
+	s.Condition = condition
+	return s
+}
+
+// WithSid returns a copy of the statement with the given statement id
+func (s Statement) WithSid(sid string) Statement {
+	s.Sid = sid
+	return s
+}
+
+var (
+	// service prefix and action name, which may contain wildcards
+	actionPattern = regexp.MustCompile(`^[a-z0-9-]+:[A-Za-z0-9*?]+$`)
+	// partition, service, region, account, and resource of an arn, region and
+	// account may be empty or wildcards
+	arnPattern = regexp.MustCompile(`^arn:(aws|aws-cn|aws-us-gov|\*):[a-z0-9-*]+:[a-z0-9-*]*:(\d{12}|\*|aws)?:.+$`)
+	sidPattern = regexp.MustCompile(`^[A-Za-z0-9]*$`)
+)
+
+// Validate checks the version, effects, action and resource formats, and conditions of the document
+func (d Document) Validate() error {
+	if d.Version != Version {
+		return fmt.Errorf("policy version must be %s, got '%s'", Version, d.Version)
+	}
+	if len(d.Statement) == 0 {
+		return errors.New("policy has no statements")
+	}
+	for i, statement := range d.Statement {
+		err := statement.validate()
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s Statement) validate() error {
+	if s.Effect != EffectAllow && s.Effect != EffectDeny {
+		return fmt.Errorf("effect must be %s or %s, got '%s'", EffectAllow, EffectDeny, s.Effect)
+	}
+	if !sidPattern.MatchString(s.Sid) {
+		return fmt.Errorf("sid must be alphanumeric, got '%s'", s.Sid)
+	}
+	if len(s.Action) == 0 {
+		return errors.New("statement has no actions")
+	}
+	for _, action := range s.Action {
+		if action != "*" && !actionPattern.MatchString(action) {
+			return fmt.Errorf("invalid action '%s', expected service:Action", action)
+		}
+	}
+	// trust policies have principals instead of resources
+	if len(s.Resource) == 0 && len(s.Principal) == 0 {
+		return errors.New("statement has no resources")
+	}
+	for _, resource := range s.Resource {
+		if resource != "*" && !arnPattern.MatchString(resource) {
+			return fmt.Errorf("invalid resource '%s', expected * or an arn", resource)
+		}
+	}
+	for operator, keys := range s.Condition {
+		for key, values := range keys {
+			if len(values) == 0 {
+				return fmt.Errorf("condition %s of %s has no values", operator, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Json validates and marshals the document
+func (d Document) Json() (string, error) {
+	err := d.Validate()
+	if err != nil {
+		return "", err
+	}
+	policy, err := json.Marshal(d)
+	return string(policy), err
+}
@@ -0,0 +1,160 @@
+package iampolicy
+
+import (
+	"encoding/json"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/internal/golden"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		document Document
+		err      string
+	}{
+		{
+			name:     "valid",
+			document: NewDocument(Allow("s3:GetObject").On("arn:aws:s3:::bucket/*")),
+		},
+		{
+			name:     "wildcards",
+			document: NewDocument(Allow("*").On("*"), Deny("ec2:Describe*").On("arn:aws:ec2:*:*:instance/*")),
+		},
+		{
+			name:     "trust policy without resources",
+			document: NewDocument(Allow("sts:AssumeRole").For("Service", "ec2.amazonaws.com")),
+		},
+		{
+			name:     "version",
+			document: Document{Version: "2008-10-17", Statement: []Statement{Allow("s3:GetObject").On("*")}},
+			err:      "policy version must be 2012-10-17",
+		},
+		{
+			name:     "no statements",
+			document: NewDocument(),
+			err:      "policy has no statements",
+		},
+		{
+			name:     "effect",
+			document: NewDocument(Statement{Effect: "allow", Action: StringList{"s3:GetObject"}, Resource: StringList{"*"}}),
+			err:      "statement 0: effect must be Allow or Deny",
+		},
+		{
+			name:     "sid",
+			document: NewDocument(Allow("s3:GetObject").On("*").WithSid("read-objects")),
+			err:      "sid must be alphanumeric",
+		},
+		{
+			name:     "no actions",
+			document: NewDocument(Allow().On("*")),
+			err:      "statement has no actions",
+		},
+		{
+			name:     "action",
+			document: NewDocument(Allow("s3:GetObject", "GetObject").On("*")),
+			err:      "invalid action 'GetObject'",
+		},
+		{
+			name:     "no resources",
+			document: NewDocument(Allow("s3:GetObject").On("*"), Allow("s3:PutObject")),
+			err:      "statement 1: statement has no resources",
+		},
+		{
+			name:     "resource",
+			document: NewDocument(Allow("s3:GetObject").On("bucket/*")),
+			err:      "invalid resource 'bucket/*'",
+		},
+		{
+			name:     "condition without values",
+			document: NewDocument(Allow("s3:GetObject").On("*").When("StringEquals", "aws:ResourceTag/owner")),
+			err:      "condition StringEquals of aws:ResourceTag/owner has no values",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.document.Validate()
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestJsonValidates(t *testing.T) {
+	_, err := NewDocument(Allow("s3:GetObject")).Json()
+	if err == nil {
+		t.Fatal("expected an invalid document to fail")
+	}
+}
+
+func TestStatementBuildersCopy(t *testing.T) {
+	base := Allow("s3:GetObject").On("arn:aws:s3:::a").When("StringEquals", "aws:PrincipalTag/team", "platform")
+	derived := base.On("arn:aws:s3:::b").When("StringEquals", "aws:ResourceTag/team", "platform").For("AWS", "arn:aws:iam::123456789012:root")
+
+	if len(base.Resource) != 1 || len(base.Condition["StringEquals"]) != 1 || base.Principal != nil {
+		t.Fatalf("builders modified the statement they were called on: %+v", base)
+	}
+	if len(derived.Resource) != 2 || len(derived.Condition["StringEquals"]) != 2 || len(derived.Principal["AWS"]) != 1 {
+		t.Fatalf("unexpected derived statement: %+v", derived)
+	}
+}
+
+func TestStringList(t *testing.T) {
+	tests := []struct {
+		list StringList
+		json string
+	}{
+		{StringList{"s3:GetObject"}, `"s3:GetObject"`},
+		{StringList{"s3:GetObject", "s3:PutObject"}, `["s3:GetObject","s3:PutObject"]`},
+	}
+	for _, test := range tests {
+		bytes, err := json.Marshal(test.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes) != test.json {
+			t.Errorf("expected %s, got %s", test.json, bytes)
+		}
+		var list StringList
+		if err := json.Unmarshal(bytes, &list); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(list, ",") != strings.Join(test.list, ",") {
+			t.Errorf("expected %v after a round trip, got %v", test.list, list)
+		}
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	policies := map[string]Document{
+		"cluster-autoscaler.json":       ClusterAutoscalerPolicy("platform"),
+		"external-dns-route53.json":     ExternalDnsRoute53Policy([]string{"Z1", "Z2"}),
+		"cert-manager-route53.json":     CertManagerRoute53Policy([]string{"Z1"}),
+		"ebs-csi-driver.json":           EbsCsiDriverPolicy([]string{"arn:aws:kms:us-east-1:123456789012:key/key"}),
+		"load-balancer-controller.json": LoadBalancerControllerPolicy(),
+	}
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			policyJson, err := policy.Json()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var indented map[string]interface{}
+			if err := json.Unmarshal([]byte(policyJson), &indented); err != nil {
+				t.Fatal(err)
+			}
+			bytes, err := json.MarshalIndent(indented, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.Assert(t, name, append(bytes, '\n'))
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package iampolicy
+
+import "fmt"
+
+// hostedZoneArns returns the arns of route53 hosted zones
+func hostedZoneArns(hostedZoneIds []string) []string {
+	var arns []string
+	for _, hostedZoneId := range hostedZoneIds {
+		arns = append(arns, fmt.Sprintf("arn:aws:route53:::hostedzone/%s", hostedZoneId))
+	}
+	return arns
+}
+
+// ClusterAutoscalerPolicy allows the cluster-autoscaler to scale the auto scaling groups tagged with
+// k8s.io/cluster-autoscaler/<cluster name>, as node groups of the cluster are
+func ClusterAutoscalerPolicy(clusterName string) Document {
+	return NewDocument(
+		Allow(
+			"autoscaling:DescribeAutoScalingGroups",
+			"autoscaling:DescribeAutoScalingInstances",
+			"autoscaling:DescribeLaunchConfigurations",
+			"autoscaling:DescribeScalingActivities",
+			"autoscaling:DescribeTags",
+			"ec2:DescribeImages",
+			"ec2:DescribeInstanceTypes",
+			"ec2:DescribeLaunchTemplateVersions",
+			"ec2:GetInstanceTypesFromInstanceRequirements",
+			"eks:DescribeNodegroup",
+		).On("*"),
+		Allow(
+			"autoscaling:SetDesiredCapacity",
+			"autoscaling:TerminateInstanceInAutoScalingGroup",
+		).On("*").When("StringEquals", fmt.Sprintf("aws:ResourceTag/k8s.io/cluster-autoscaler/%s", clusterName), "owned"),
+	)
+}
+
+// ExternalDnsRoute53Policy allows external-dns to change the records of the given hosted zones
+func ExternalDnsRoute53Policy(hostedZoneIds []string) Document {
+	return NewDocument(
+		Allow("route53:ChangeResourceRecordSets").On(hostedZoneArns(hostedZoneIds)...),
+		Allow(
+			"route53:ListHostedZones",
+			"route53:ListResourceRecordSets",
+			"route53:ListTagsForResource",
+		).On("*"),
+	)
+}
+
+// CertManagerRoute53Policy allows cert-manager to solve dns01 challenges in the given hosted zones
+func CertManagerRoute53Policy(hostedZoneIds []string) Document {
+	return NewDocument(
+		Allow("route53:GetChange").On("arn:aws:route53:::change/*"),
+		Allow(
+			"route53:ChangeResourceRecordSets",
+			"route53:ListResourceRecordSets",
+		).On(hostedZoneArns(hostedZoneIds)...),
+		Allow("route53:ListHostedZonesByName").On("*"),
+	)
+}
+
+// EbsCsiDriverPolicy allows the ebs csi driver to manage the volumes and snapshots it creates, like the
+// AmazonEBSCSIDriverPolicy managed policy, and to use the given kms keys for encrypted volumes. Deletes are limited to
+// volumes and snapshots tagged by the driver.
+func EbsCsiDriverPolicy(kmsKeyArns []string) Document {
+	statements := []Statement{
+		Allow(
+			"ec2:CreateSnapshot",
+			"ec2:AttachVolume",
+			"ec2:DetachVolume",
+			"ec2:ModifyVolume",
+			"ec2:DescribeAvailabilityZones",
+			"ec2:DescribeInstances",
+			"ec2:DescribeSnapshots",
+			"ec2:DescribeTags",
+			"ec2:DescribeVolumes",
+			"ec2:DescribeVolumesModifications",
+		).On("*"),
+		Allow("ec2:CreateTags").On(
+			"arn:aws:ec2:*:*:volume/*",
+			"arn:aws:ec2:*:*:snapshot/*",
+		).When("StringEquals", "ec2:CreateAction", "CreateVolume", "CreateSnapshot"),
+		Allow("ec2:DeleteTags").On(
+			"arn:aws:ec2:*:*:volume/*",
+			"arn:aws:ec2:*:*:snapshot/*",
+		),
+		Allow("ec2:CreateVolume").On("*").When("StringLike", "aws:RequestTag/ebs.csi.aws.com/cluster", "true"),
+		Allow("ec2:CreateVolume").On("*").When("StringLike", "aws:RequestTag/CSIVolumeName", "*"),
+		Allow("ec2:DeleteVolume").On("*").When("StringLike", "ec2:ResourceTag/ebs.csi.aws.com/cluster", "true"),
+		Allow("ec2:DeleteVolume").On("*").When("StringLike", "ec2:ResourceTag/CSIVolumeName", "*"),
+		Allow("ec2:DeleteVolume").On("*").When("StringLike", "ec2:ResourceTag/kubernetes.io/created-for/pvc/name", "*"),
+		Allow("ec2:DeleteSnapshot").On("*").When("StringLike", "ec2:ResourceTag/CSIVolumeSnapshotName", "*"),
+		Allow("ec2:DeleteSnapshot").On("*").When("StringLike", "ec2:ResourceTag/ebs.csi.aws.com/cluster", "true"),
+	}
+	if len(kmsKeyArns) != 0 {
+		statements = append(statements,
+			Allow(
+				"kms:CreateGrant",
+				"kms:ListGrants",
+				"kms:RevokeGrant",
+			).On(kmsKeyArns...).When("Bool", "kms:GrantIsForAWSResource", "true"),
+			Allow(
+				"kms:Encrypt",
+				"kms:Decrypt",
+				"kms:ReEncrypt*",
+				"kms:GenerateDataKey*",
+				"kms:DescribeKey",
+			).On(kmsKeyArns...),
+		)
+	}
+	return NewDocument(statements...)
+}
+
+// LoadBalancerControllerPolicy allows the aws-load-balancer-controller to manage the load balancers, target groups,
+// and security groups it creates, see
+// https://github.com/kubernetes-sigs/aws-load-balancer-controller/blob/main/docs/install/iam_policy.json
+func LoadBalancerControllerPolicy() Document {
+	clusterTagNull := func(statement Statement) Statement {
+		return statement.
+			When("Null", "aws:RequestTag/elbv2.k8s.aws/cluster", "true").
+			When("Null", "aws:ResourceTag/elbv2.k8s.aws/cluster", "false")
+	}
+	loadBalancerArns := []string{
+		"arn:aws:elasticloadbalancing:*:*:targetgroup/*/*",
+		"arn:aws:elasticloadbalancing:*:*:loadbalancer/net/*/*",
+		"arn:aws:elasticloadbalancing:*:*:loadbalancer/app/*/*",
+	}
+	return NewDocument(
+		Allow("iam:CreateServiceLinkedRole").On("*").
+			When("StringEquals", "iam:AWSServiceName", "elasticloadbalancing.amazonaws.com"),
+		Allow(
+			"ec2:DescribeAccountAttributes",
+			"ec2:DescribeAddresses",
+			"ec2:DescribeAvailabilityZones",
+			"ec2:DescribeInternetGateways",
+			"ec2:DescribeVpcs",
+			"ec2:DescribeVpcPeeringConnections",
+			"ec2:DescribeSubnets",
+			"ec2:DescribeSecurityGroups",
+			"ec2:DescribeInstances",
+			"ec2:DescribeNetworkInterfaces",
+			"ec2:DescribeTags",
+			"ec2:GetCoipPoolUsage",
+			"ec2:DescribeCoipPools",
+			"elasticloadbalancing:DescribeLoadBalancers",
+			"elasticloadbalancing:DescribeLoadBalancerAttributes",
+			"elasticloadbalancing:DescribeListeners",
+			"elasticloadbalancing:DescribeListenerCertificates",
+			"elasticloadbalancing:DescribeSSLPolicies",
+			"elasticloadbalancing:DescribeRules",
+			"elasticloadbalancing:DescribeTargetGroups",
+			"elasticloadbalancing:DescribeTargetGroupAttributes",
+			"elasticloadbalancing:DescribeTargetHealth",
+			"elasticloadbalancing:DescribeTags",
+		).On("*"),
+		Allow(
+			"cognito-idp:DescribeUserPoolClient",
+			"acm:ListCertificates",
+			"acm:DescribeCertificate",
+			"iam:ListServerCertificates",
+			"iam:GetServerCertificate",
+			"waf-regional:GetWebACL",
+			"waf-regional:GetWebACLForResource",
+			"waf-regional:AssociateWebACL",
+			"waf-regional:DisassociateWebACL",
+			"wafv2:GetWebACL",
+			"wafv2:GetWebACLForResource",
+			"wafv2:AssociateWebACL",
+			"wafv2:DisassociateWebACL",
+			"shield:GetSubscriptionState",
+			"shield:DescribeProtection",
+			"shield:CreateProtection",
+			"shield:DeleteProtection",
+		).On("*"),
+		Allow(
+			"ec2:AuthorizeSecurityGroupIngress",
+			"ec2:RevokeSecurityGroupIngress",
+		).On("*"),
+		Allow("ec2:CreateSecurityGroup").On("*"),
+		Allow("ec2:CreateTags").On("arn:aws:ec2:*:*:security-group/*").
+			When("StringEquals", "ec2:CreateAction", "CreateSecurityGroup").
+			When("Null", "aws:RequestTag/elbv2.k8s.aws/cluster", "false"),
+		clusterTagNull(Allow(
+			"ec2:CreateTags",
+			"ec2:DeleteTags",
+		).On("arn:aws:ec2:*:*:security-group/*")),
+		Allow(
+			"ec2:AuthorizeSecurityGroupIngress",
+			"ec2:RevokeSecurityGroupIngress",
+			"ec2:DeleteSecurityGroup",
+		).On("*").When("Null", "aws:ResourceTag/elbv2.k8s.aws/cluster", "false"),
+		Allow(
+			"elasticloadbalancing:CreateLoadBalancer",
+			"elasticloadbalancing:CreateTargetGroup",
+		).On("*").When("Null", "aws:RequestTag/elbv2.k8s.aws/cluster", "false"),
+		Allow(
+			"elasticloadbalancing:CreateListener",
+			"elasticloadbalancing:DeleteListener",
+			"elasticloadbalancing:CreateRule",
+			"elasticloadbalancing:DeleteRule",
+		).On("*"),
+		clusterTagNull(Allow(
+			"elasticloadbalancing:AddTags",
+			"elasticloadbalancing:RemoveTags",
+		).On(loadBalancerArns...)),
+		Allow(
+			"elasticloadbalancing:AddTags",
+			"elasticloadbalancing:RemoveTags",
+		).On(
+			"arn:aws:elasticloadbalancing:*:*:listener/net/*/*/*",
+			"arn:aws:elasticloadbalancing:*:*:listener/app/*/*/*",
+			"arn:aws:elasticloadbalancing:*:*:listener-rule/net/*/*/*",
+			"arn:aws:elasticloadbalancing:*:*:listener-rule/app/*/*/*",
+		),
+		Allow(
+			"elasticloadbalancing:ModifyLoadBalancerAttributes",
+			"elasticloadbalancing:SetIpAddressType",
+			"elasticloadbalancing:SetSecurityGroups",
+			"elasticloadbalancing:SetSubnets",
+			"elasticloadbalancing:DeleteLoadBalancer",
+			"elasticloadbalancing:ModifyTargetGroup",
+			"elasticloadbalancing:ModifyTargetGroupAttributes",
+			"elasticloadbalancing:DeleteTargetGroup",
+		).On("*").When("Null", "aws:ResourceTag/elbv2.k8s.aws/cluster", "false"),
+		Allow("elasticloadbalancing:AddTags").On(loadBalancerArns...).
+			When("StringEquals", "elasticloadbalancing:CreateAction", "CreateTargetGroup", "CreateLoadBalancer").
+			When("Null", "aws:RequestTag/elbv2.k8s.aws/cluster", "false"),
+		Allow(
+			"elasticloadbalancing:RegisterTargets",
+			"elasticloadbalancing:DeregisterTargets",
+		).On("arn:aws:elasticloadbalancing:*:*:targetgroup/*/*"),
+		Allow(
+			"elasticloadbalancing:SetWebAcl",
+			"elasticloadbalancing:ModifyListener",
+			"elasticloadbalancing:AddListenerCertificates",
+			"elasticloadbalancing:RemoveListenerCertificates",
+			"elasticloadbalancing:ModifyRule",
+		).On("*"),
+	)
+}
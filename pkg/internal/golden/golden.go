@@ -0,0 +1,34 @@
+// Package golden compares test output with golden files in the testdata directory of the package under test.
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the tests instead of comparing with them")
+
+// Assert fails the test when the output differs from the golden file testdata/<name>. With go test -update, the
+// golden file is written instead.
+func Assert(t *testing.T, name string, actual []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading golden file %s, run go test -update to create it: %v", path, err)
+	}
+	if string(expected) != string(actual) {
+		t.Errorf("output differs from golden file %s, run go test -update if the change is intended\ngot:\n%s\nwant:\n%s", path, actual, expected)
+	}
+}
@@ -0,0 +1,48 @@
+package kms
+
+import (
+	"errors"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type KeyIrsaRoleInput struct {
+	// the role and the service account allowed to assume it. the role's
+	// inline policy is generated from the key access below
+	eks.IrsaRoleInput
+
+	// keys the service account is granted access to
+	KeyArns []string `json:"key-arns"`
+	// optional, only grants decrypt access
+	DecryptOnly bool `json:"decrypt-only"`
+}
+
+// NewKeyIrsaRole creates an IRSA role granting a kubernetes service account use of KMS keys, i.e. for external-secrets
+// or SOPS. The key policies must allow IAM policies of the account, as those of SyncKey do.
+func NewKeyIrsaRole(ctx *pulumi.Context, pulumiResourceName string, input KeyIrsaRoleInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if len(input.KeyArns) == 0 {
+		return nil, errors.New("key arns not supplied, cannot create key IRSA role")
+	}
+	policy, err := KeyAccessPolicy(input.KeyArns, input.DecryptOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	roleInput := input.IrsaRoleInput
+	roleInput.InlinePolicy = policy
+	return eks.NewIrsaRole(ctx, pulumiResourceName, roleInput, opts...)
+}
+
+// KeyAccessPolicy renders an IAM policy document granting decrypt, and unless decryptOnly encrypt, access to the given
+// keys.
+func KeyAccessPolicy(keyArns []string, decryptOnly bool) (string, error) {
+	actions := encryptDecryptActions
+	if decryptOnly {
+		actions = decryptActions
+	}
+	return iampolicy.NewDocument(
+		iampolicy.Allow(actions...).On(keyArns...),
+	).Json()
+}
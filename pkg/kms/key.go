@@ -0,0 +1,167 @@
+package kms
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/kms"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+type KeyInput struct {
+	// name of the key's alias, with or without the alias/ prefix
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// optional days before a deleted key is destroyed, between 7 and 30,
+	// defaults to 30
+	DeletionWindowInDays int `json:"deletion-window-in-days"`
+	// optional, yearly rotation of the key material is enabled unless
+	// disabled
+	DisableRotation bool `json:"disable-rotation"`
+	// optional, creates a multi-region primary key
+	MultiRegion bool `json:"multi-region"`
+
+	// optional roles that may administer, but not use, the key. the account
+	// root is always allowed, so that IAM policies of the account can grant
+	// access, i.e. to the cluster role for EKS envelope encryption
+	AdminRoleArns []string `json:"admin-role-arns"`
+	// optional roles that may decrypt with the key, i.e. IRSA roles of
+	// external-secrets or applications reading SOPS files
+	DecryptRoleArns []string `json:"decrypt-role-arns"`
+	// optional roles that may encrypt and decrypt with the key, i.e. CI roles
+	// writing SOPS files
+	EncryptDecryptRoleArns []string `json:"encrypt-decrypt-role-arns"`
+
+	Tags map[string]string `json:"tags"`
+
+	// optional protection of the key against deletion, data encrypted with a
+	// destroyed key is lost
+	utils.ProtectionInput
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one key of the same name in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type KeyOutput struct {
+	KeyArn   pulumi.StringOutput
+	KeyId    pulumi.StringOutput
+	AliasArn pulumi.StringOutput
+}
+
+// actions of key users
+var (
+	decryptActions = []string{
+		"kms:Decrypt",
+		"kms:DescribeKey",
+	}
+	encryptDecryptActions = []string{
+		"kms:Encrypt",
+		"kms:Decrypt",
+		"kms:ReEncrypt*",
+		"kms:GenerateDataKey*",
+		"kms:DescribeKey",
+	}
+)
+
+// SyncKey creates a customer managed KMS key with rotation and an alias, and a key policy granting the configured roles
+// administration, decrypt, or encrypt and decrypt access.
+func SyncKey(ctx *pulumi.Context, config KeyInput, opts ...pulumi.ResourceOption) (KeyOutput, error) {
+	var output KeyOutput
+	name := strings.TrimPrefix(config.Name, "alias/")
+	if name == "" {
+		return output, errors.New("key name not supplied, cannot create key")
+	}
+	deletionWindow := 30
+	if config.DeletionWindowInDays != 0 {
+		deletionWindow = config.DeletionWindowInDays
+	}
+	if deletionWindow < 7 || deletionWindow > 30 {
+		return output, fmt.Errorf("deletion window of key %s must be between 7 and 30 days, got %d", name, deletionWindow)
+	}
+
+	policy, err := keyPolicy(ctx, config)
+	if err != nil {
+		return output, err
+	}
+	key, err := kms.NewKey(ctx, utils.PrefixedName(config.ResourcePrefix, name+"-key"), &kms.KeyArgs{
+		Description:          pulumi.String(config.Description),
+		DeletionWindowInDays: pulumi.Int(deletionWindow),
+		EnableKeyRotation:    pulumi.Bool(!config.DisableRotation),
+		MultiRegion:          pulumi.Bool(config.MultiRegion),
+		Policy:               pulumi.String(policy),
+		Tags:                 pulumi.ToStringMap(config.Tags),
+	}, utils.ProtectionOpts(config.ProtectionInput, opts)...)
+	if err != nil {
+		return output, err
+	}
+
+	alias, err := kms.NewAlias(ctx, utils.PrefixedName(config.ResourcePrefix, name+"-alias"), &kms.AliasArgs{
+		Name:        pulumi.String("alias/" + name),
+		TargetKeyId: key.KeyId,
+	}, opts...)
+	if err != nil {
+		return output, err
+	}
+
+	output.KeyArn = key.Arn
+	output.KeyId = key.KeyId
+	output.AliasArn = alias.Arn
+	return output, nil
+}
+
+// renders the key policy, which allows the account root so that IAM policies
+// apply to the key, and the configured roles
+func keyPolicy(ctx *pulumi.Context, config KeyInput) (string, error) {
+	partition, err := aws.GetPartition(ctx)
+	if err != nil {
+		return "", err
+	}
+	callerIdentity, err := aws.GetCallerIdentity(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	statements := []iampolicy.Statement{
+		iampolicy.Allow("kms:*").
+			For("AWS", fmt.Sprintf("arn:%s:iam::%s:root", partition.Partition, callerIdentity.AccountId)).
+			On("*").
+			WithSid("EnableIamPolicies"),
+	}
+	if len(config.AdminRoleArns) != 0 {
+		statements = append(statements, iampolicy.Allow(
+			"kms:Create*",
+			"kms:Describe*",
+			"kms:Enable*",
+			"kms:List*",
+			"kms:Put*",
+			"kms:Update*",
+			"kms:Revoke*",
+			"kms:Disable*",
+			"kms:Get*",
+			"kms:Delete*",
+			"kms:TagResource",
+			"kms:UntagResource",
+			"kms:ScheduleKeyDeletion",
+			"kms:CancelKeyDeletion",
+		).For("AWS", config.AdminRoleArns...).On("*").WithSid("AllowKeyAdministration"))
+	}
+	if len(config.DecryptRoleArns) != 0 {
+		statements = append(statements, iampolicy.Allow(decryptActions...).
+			For("AWS", config.DecryptRoleArns...).
+			On("*").
+			WithSid("AllowDecrypt"))
+	}
+	if len(config.EncryptDecryptRoleArns) != 0 {
+		statements = append(statements, iampolicy.Allow(encryptDecryptActions...).
+			For("AWS", config.EncryptDecryptRoleArns...).
+			On("*").
+			WithSid("AllowEncryptDecrypt"))
+	}
+
+	return iampolicy.NewDocument(statements...).Json()
+}
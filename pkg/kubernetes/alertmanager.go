@@ -0,0 +1,248 @@
+package kubernetes
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+// AlertmanagerConfigInput configures the receivers and routes of the alertmanager installed by kube-prometheus-stack.
+// Webhook urls, routing keys, and passwords reference pulumi config secrets by name, so that they stay secret.
+type AlertmanagerConfigInput struct {
+	// optional, replaces the alertmanager config of the values files
+	Enabled   bool                              `json:"enabled"`
+	Receivers []AlertmanagerReceiverConfigInput `json:"receivers"`
+	// optional, defaults to the first receiver
+	Route AlertmanagerRouteConfigInput `json:"route"`
+}
+
+type AlertmanagerReceiverConfigInput struct {
+	Name      string                             `json:"name" validate:"required"`
+	Slack     []AlertmanagerSlackConfigInput     `json:"slack"`
+	PagerDuty []AlertmanagerPagerDutyConfigInput `json:"pagerduty"`
+	OpsGenie  []AlertmanagerOpsGenieConfigInput  `json:"opsgenie"`
+	Email     []AlertmanagerEmailConfigInput     `json:"email"`
+}
+
+type AlertmanagerSlackConfigInput struct {
+	// name of the pulumi config secret holding the slack webhook url
+	ApiUrlSecretKey string `json:"api-url-secret-key"`
+	Channel         string `json:"channel"`
+	// optional message templates
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	// optional, defaults to true
+	SendResolved *bool `json:"send-resolved"`
+}
+
+type AlertmanagerPagerDutyConfigInput struct {
+	// name of the pulumi config secret holding the events api v2 routing key
+	RoutingKeySecretKey string `json:"routing-key-secret-key"`
+	// optional, defaults to the alertmanager default
+	Severity string `json:"severity"`
+	// optional, defaults to true
+	SendResolved *bool `json:"send-resolved"`
+}
+
+type AlertmanagerOpsGenieConfigInput struct {
+	// name of the pulumi config secret holding the opsgenie api key
+	ApiKeySecretKey string `json:"api-key-secret-key"`
+	// optional, i.e. https://api.eu.opsgenie.com/ for the eu instance
+	ApiUrl   string `json:"api-url"`
+	Priority string `json:"priority"`
+	// optional, defaults to true
+	SendResolved *bool `json:"send-resolved"`
+}
+
+type AlertmanagerEmailConfigInput struct {
+	To   string `json:"to"`
+	From string `json:"from"`
+	// smtp server host and port
+	Smarthost    string `json:"smarthost"`
+	AuthUsername string `json:"auth-username"`
+	// optional name of the pulumi config secret holding the smtp password
+	AuthPasswordSecretKey string `json:"auth-password-secret-key"`
+	// optional, defaults to true
+	RequireTls *bool `json:"require-tls"`
+	// optional, defaults to false
+	SendResolved bool `json:"send-resolved"`
+}
+
+type AlertmanagerRouteConfigInput struct {
+	Receiver string `json:"receiver"`
+	// optional, i.e. ["alertname", "namespace"]
+	GroupBy        []string `json:"group-by"`
+	GroupWait      string   `json:"group-wait"`
+	GroupInterval  string   `json:"group-interval"`
+	RepeatInterval string   `json:"repeat-interval"`
+	// optional, i.e. `severity="critical"`
+	Matchers []string `json:"matchers"`
+	Continue bool     `json:"continue"`
+	// optional child routes
+	Routes []AlertmanagerRouteConfigInput `json:"routes"`
+}
+
+// the receiver of the Watchdog route of the chart's default config, kept so
+// that the default routes stay valid
+const alertmanagerNullReceiver = "null"
+
+// renders the alertmanager config, reading secrets from the given config
+func alertmanagerValues(cfg *config.Config, alertmanagerConfig AlertmanagerConfigInput) (pulumi.Map, error) {
+	if len(alertmanagerConfig.Receivers) == 0 {
+		return nil, errorx.IllegalArgument.New("alertmanager config requires at least one receiver")
+	}
+
+	var receivers pulumi.Array
+	hasNullReceiver := false
+	for _, receiverConfig := range alertmanagerConfig.Receivers {
+		if receiverConfig.Name == "" {
+			return nil, errorx.IllegalArgument.New("alertmanager receiver name not supplied")
+		}
+		if receiverConfig.Name == alertmanagerNullReceiver {
+			hasNullReceiver = true
+		}
+		receiver, err := alertmanagerReceiverValues(cfg, receiverConfig)
+		if err != nil {
+			return nil, err
+		}
+		receivers = append(receivers, receiver)
+	}
+	if !hasNullReceiver {
+		receivers = append(receivers, pulumi.Map{
+			"name": pulumi.String(alertmanagerNullReceiver),
+		})
+	}
+
+	route := alertmanagerConfig.Route
+	if route.Receiver == "" {
+		route.Receiver = alertmanagerConfig.Receivers[0].Name
+	}
+
+	return pulumi.Map{
+		"receivers": receivers,
+		"route":     alertmanagerRouteValues(route),
+	}, nil
+}
+
+func alertmanagerReceiverValues(cfg *config.Config, receiverConfig AlertmanagerReceiverConfigInput) (pulumi.Map, error) {
+	receiver := pulumi.Map{
+		"name": pulumi.String(receiverConfig.Name),
+	}
+
+	var slackConfigs pulumi.Array
+	for _, slack := range receiverConfig.Slack {
+		if slack.ApiUrlSecretKey == "" {
+			return nil, errorx.IllegalArgument.New("slack api url secret key of alertmanager receiver %s not supplied", receiverConfig.Name)
+		}
+		slackConfig := pulumi.Map{
+			"api_url":       cfg.RequireSecret(slack.ApiUrlSecretKey),
+			"channel":       pulumi.String(slack.Channel),
+			"send_resolved": pulumi.Bool(boolOrDefault(slack.SendResolved, true)),
+		}
+		setNonEmpty(slackConfig, "title", slack.Title)
+		setNonEmpty(slackConfig, "text", slack.Text)
+		slackConfigs = append(slackConfigs, slackConfig)
+	}
+	if len(slackConfigs) != 0 {
+		receiver["slack_configs"] = slackConfigs
+	}
+
+	var pagerDutyConfigs pulumi.Array
+	for _, pagerDuty := range receiverConfig.PagerDuty {
+		if pagerDuty.RoutingKeySecretKey == "" {
+			return nil, errorx.IllegalArgument.New("pagerduty routing key secret key of alertmanager receiver %s not supplied", receiverConfig.Name)
+		}
+		pagerDutyConfig := pulumi.Map{
+			"routing_key":   cfg.RequireSecret(pagerDuty.RoutingKeySecretKey),
+			"send_resolved": pulumi.Bool(boolOrDefault(pagerDuty.SendResolved, true)),
+		}
+		setNonEmpty(pagerDutyConfig, "severity", pagerDuty.Severity)
+		pagerDutyConfigs = append(pagerDutyConfigs, pagerDutyConfig)
+	}
+	if len(pagerDutyConfigs) != 0 {
+		receiver["pagerduty_configs"] = pagerDutyConfigs
+	}
+
+	var opsGenieConfigs pulumi.Array
+	for _, opsGenie := range receiverConfig.OpsGenie {
+		if opsGenie.ApiKeySecretKey == "" {
+			return nil, errorx.IllegalArgument.New("opsgenie api key secret key of alertmanager receiver %s not supplied", receiverConfig.Name)
+		}
+		opsGenieConfig := pulumi.Map{
+			"api_key":       cfg.RequireSecret(opsGenie.ApiKeySecretKey),
+			"send_resolved": pulumi.Bool(boolOrDefault(opsGenie.SendResolved, true)),
+		}
+		setNonEmpty(opsGenieConfig, "api_url", opsGenie.ApiUrl)
+		setNonEmpty(opsGenieConfig, "priority", opsGenie.Priority)
+		opsGenieConfigs = append(opsGenieConfigs, opsGenieConfig)
+	}
+	if len(opsGenieConfigs) != 0 {
+		receiver["opsgenie_configs"] = opsGenieConfigs
+	}
+
+	var emailConfigs pulumi.Array
+	for _, email := range receiverConfig.Email {
+		emailConfig := pulumi.Map{
+			"to":            pulumi.String(email.To),
+			"require_tls":   pulumi.Bool(boolOrDefault(email.RequireTls, true)),
+			"send_resolved": pulumi.Bool(email.SendResolved),
+		}
+		setNonEmpty(emailConfig, "from", email.From)
+		setNonEmpty(emailConfig, "smarthost", email.Smarthost)
+		setNonEmpty(emailConfig, "auth_username", email.AuthUsername)
+		if email.AuthPasswordSecretKey != "" {
+			emailConfig["auth_password"] = cfg.RequireSecret(email.AuthPasswordSecretKey)
+		}
+		emailConfigs = append(emailConfigs, emailConfig)
+	}
+	if len(emailConfigs) != 0 {
+		receiver["email_configs"] = emailConfigs
+	}
+
+	return receiver, nil
+}
+
+func alertmanagerRouteValues(routeConfig AlertmanagerRouteConfigInput) pulumi.Map {
+	route := pulumi.Map{}
+	setNonEmpty(route, "receiver", routeConfig.Receiver)
+	setNonEmpty(route, "group_wait", routeConfig.GroupWait)
+	setNonEmpty(route, "group_interval", routeConfig.GroupInterval)
+	setNonEmpty(route, "repeat_interval", routeConfig.RepeatInterval)
+	if len(routeConfig.GroupBy) != 0 {
+		route["group_by"] = pulumi.ToStringArray(routeConfig.GroupBy)
+	}
+	if len(routeConfig.Matchers) != 0 {
+		route["matchers"] = pulumi.ToStringArray(routeConfig.Matchers)
+	}
+	if routeConfig.Continue {
+		route["continue"] = pulumi.Bool(true)
+	}
+	if len(routeConfig.Routes) != 0 {
+		var routes pulumi.Array
+		for _, child := range routeConfig.Routes {
+			routes = append(routes, alertmanagerRouteValues(child))
+		}
+		route["routes"] = routes
+	}
+	return route
+}
+
+func setNonEmpty(values pulumi.Map, key string, value string) {
+	if value != "" {
+		values[key] = pulumi.String(value)
+	}
+}
+
+func boolOrDefault(value *bool, defaultValue bool) bool {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+func stringOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,236 @@
+package kubernetes
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+// AppOfAppsConfig configures an app-of-apps: a root application whose resources are the child applications
+type AppOfAppsConfig struct {
+	// name of the root application
+	Name string `json:"name" validate:"required"`
+	// optional argo-cd namespace and project of the root and child
+	// applications, default to argo-cd and default
+	Namespace string `json:"namespace"`
+	Project   string `json:"project"`
+	// optional, disables automated sync of the root application, which
+	// creates, updates, and prunes the child applications
+	ManualSync bool `json:"manual-sync"`
+	// optional argo-cd version whose Application CRD schema the root and
+	// child applications are validated against, i.e. "v2.6", validation is
+	// skipped when not set
+	SchemaVersion string `json:"schema-version"`
+
+	Applications []AppOfAppsApplicationConfig `json:"applications"`
+}
+
+type AppOfAppsApplicationConfig struct {
+	Name string `json:"name" validate:"required"`
+	// git or helm repository, and the path of a git repository or chart of a
+	// helm repository
+	RepoUrl        string `json:"repo-url" validate:"required"`
+	Path           string `json:"path"`
+	Chart          string `json:"chart"`
+	TargetRevision string `json:"target-revision"`
+	// optional inline helm values, strings may contain <<mySecretValue>>
+	// placeholders, see SyncArgocdApplication
+	Values map[string]interface{} `json:"values"`
+
+	// optional destination namespace, defaults to the name, and cluster,
+	// defaults to the in-cluster server
+	Namespace string `json:"namespace"`
+	Server    string `json:"server"`
+
+	// optional, orders the application among its siblings, lower waves sync
+	// first
+	SyncWave int `json:"sync-wave"`
+	// optional, disables automated sync with pruning and self heal
+	ManualSync bool `json:"manual-sync"`
+}
+
+// the chart that renders the child applications of the root application
+const (
+	appOfAppsChartRepo    = "https://argoproj.github.io/argo-helm"
+	appOfAppsChart        = "argocd-apps"
+	appOfAppsChartVersion = "0.0.9"
+)
+
+// SyncArgocdAppOfApps renders an app-of-apps from config with NewAppOfApps and syncs the root application with
+// SyncArgocdApplication. argo-cd then manages the child applications, so that the whole gitops tree is defined in
+// pulumi config.
+func SyncArgocdAppOfApps(ctx *pulumi.Context, pulumiResourceName string, config AppOfAppsConfig, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	root, err := NewAppOfApps(config)
+	err = logger(ctx, pulumiResourceName).Wrap("error rendering app-of-apps", err)
+	if err != nil {
+		return nil, err
+	}
+	return SyncArgocdApplication(ctx, pulumiResourceName, root, opts...)
+}
+
+// NewAppOfApps renders the root application of an app-of-apps. The child applications are rendered with
+// NewAppOfAppsApplication, validated against the CRD schema of the SchemaVersion if set, and passed to the argocd-apps chart
+// as the root's helm values. The root has the resources finalizer, so deleting it deletes the child applications.
+func NewAppOfApps(config AppOfAppsConfig) (ArgocdApplication, error) {
+	var root ArgocdApplication
+	if config.Name == "" {
+		return root, errorx.IllegalArgument.New("app-of-apps name not supplied")
+	}
+	namespace := stringOrDefault(config.Namespace, "argo-cd")
+	project := stringOrDefault(config.Project, "default")
+
+	var applications []map[string]interface{}
+	for _, applicationConfig := range config.Applications {
+		child, err := NewAppOfAppsApplication(config, applicationConfig)
+		if err != nil {
+			return root, err
+		}
+		if config.SchemaVersion != "" {
+			err = ValidateArgocdApplication(child, config.SchemaVersion)
+			if err != nil {
+				return root, errorx.Decorate(err, "invalid application %s", applicationConfig.Name)
+			}
+		}
+		values, err := appOfAppsChartApplication(child)
+		if err != nil {
+			return root, err
+		}
+		applications = append(applications, values)
+	}
+	values, err := yaml.Marshal(map[string]interface{}{
+		"applications": applications,
+	})
+	if err != nil {
+		return root, err
+	}
+
+	root = ArgocdApplication{
+		ApiVersion: "argoproj.io/v1alpha1",
+		Kind:       "Application",
+		Metadata: map[string]interface{}{
+			"name":      config.Name,
+			"namespace": namespace,
+		},
+		Spec: ArgocdApplicationSpec{
+			Project: project,
+			Source: ArgocdApplicationSpecSource{
+				RepoUrl:        appOfAppsChartRepo,
+				Chart:          appOfAppsChart,
+				TargetRevision: appOfAppsChartVersion,
+				Helm: HelmSource{
+					ReleaseName: config.Name,
+					Values:      string(values),
+				},
+			},
+			Destination: ArgocdApplicationSpecDestination{
+				Server:    "https://kubernetes.default.svc",
+				Namespace: namespace,
+			},
+			SyncPolicy: appOfAppsSyncPolicy(config.ManualSync),
+		},
+		SchemaVersion: config.SchemaVersion,
+	}
+	root.AddResourcesFinalizer(false)
+	return root, nil
+}
+
+// NewAppOfAppsApplication renders a child application of an app-of-apps, in the namespace and project of the root
+func NewAppOfAppsApplication(config AppOfAppsConfig, applicationConfig AppOfAppsApplicationConfig) (ArgocdApplication, error) {
+	var application ArgocdApplication
+	if applicationConfig.Name == "" || applicationConfig.RepoUrl == "" {
+		return application, errorx.IllegalArgument.New("app-of-apps applications require a name and repo url")
+	}
+	if (applicationConfig.Path == "") == (applicationConfig.Chart == "") {
+		return application, errorx.IllegalArgument.New("application %s requires either a path or a chart", applicationConfig.Name)
+	}
+
+	source := ArgocdApplicationSpecSource{
+		RepoUrl:        applicationConfig.RepoUrl,
+		Path:           applicationConfig.Path,
+		Chart:          applicationConfig.Chart,
+		TargetRevision: applicationConfig.TargetRevision,
+	}
+	if len(applicationConfig.Values) != 0 {
+		values, err := yaml.Marshal(applicationConfig.Values)
+		if err != nil {
+			return application, err
+		}
+		source.Helm.Values = string(values)
+	}
+	destination := ArgocdApplicationSpecDestination{
+		Server:    stringOrDefault(applicationConfig.Server, "https://kubernetes.default.svc"),
+		Namespace: stringOrDefault(applicationConfig.Namespace, applicationConfig.Name),
+	}
+
+	application = ArgocdApplication{
+		ApiVersion: "argoproj.io/v1alpha1",
+		Kind:       "Application",
+		Metadata: map[string]interface{}{
+			"name":      applicationConfig.Name,
+			"namespace": stringOrDefault(config.Namespace, "argo-cd"),
+		},
+		Spec: ArgocdApplicationSpec{
+			Project:     stringOrDefault(config.Project, "default"),
+			Source:      source,
+			Destination: destination,
+			SyncPolicy:  appOfAppsSyncPolicy(applicationConfig.ManualSync),
+		},
+	}
+	if applicationConfig.SyncWave != 0 {
+		application.SetSyncWave(applicationConfig.SyncWave)
+	}
+	application.AddResourcesFinalizer(false)
+	return application, nil
+}
+
+// returns automated sync with pruning and self heal, unless manual
+func appOfAppsSyncPolicy(manual bool) ArgocdApplicationSyncPolicy {
+	if manual {
+		return ArgocdApplicationSyncPolicy{}
+	}
+	return ArgocdApplicationSyncPolicy{
+		Automated: SyncPolicyAutomated{
+			Prune:    true,
+			SelfHeal: true,
+		},
+	}
+}
+
+// converts a child application to an entry of the applications list of the
+// argocd-apps chart values, which takes the spec fields along with the
+// name, namespace, annotations, and finalizers of the metadata
+func appOfAppsChartApplication(application ArgocdApplication) (map[string]interface{}, error) {
+	spec, err := yaml.Marshal(application.Spec)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(spec, &values)
+	if err != nil {
+		return nil, err
+	}
+	values["name"] = application.Metadata["name"]
+	values["namespace"] = application.Metadata["namespace"]
+	if annotations, ok := application.Metadata["annotations"]; ok {
+		values["additionalAnnotations"] = annotations
+	}
+	if finalizers, ok := application.Metadata["finalizers"]; ok {
+		values["finalizers"] = finalizers
+	}
+	return values, nil
+}
+
+// syncs the app-of-apps of the k8s config, argo-cd must be installed for the
+// application CRD
+func deployAppOfApps(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if len(bootstrap.K8sConfig.AppOfApps) != 0 && fluxEnabled(bootstrap.K8sConfig) {
+		return nil, errorx.IllegalArgument.New("app-of-apps require the argocd gitops provider")
+	}
+	for _, appOfApps := range bootstrap.K8sConfig.AppOfApps {
+		_, err := SyncArgocdAppOfApps(ctx, bootstrap.ResourceName("app-of-apps-"+appOfApps.Name), appOfApps, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
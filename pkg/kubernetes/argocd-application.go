@@ -3,42 +3,150 @@ package kubernetes
 import (
 	"github.com/catalystcommunity/app-utils-go/errorutils"
 	"github.com/catalystcommunity/pulumi-modules-go/pkg/secrets"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/yaml"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-	"gopkg.in/yaml.v3"
+	goyaml "gopkg.in/yaml.v3"
+	"strconv"
 )
 
 // SyncArgocdApplication takes in a pulumi resource name, an argocd application, and any pulumi options
-// It will replace secrets in the spec.source.helm.values with the configured secrets provider, then sync the resulting yaml to k8s
+// It will validate the application against the CRD schema of its SchemaVersion if set, sync the resulting yaml to k8s,
+// and replace secrets in the spec.source.helm.values with pulumi secret outputs of the configured secrets provider
 func SyncArgocdApplication(ctx *pulumi.Context, pulumiResourceName string, application ArgocdApplication, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
-	// replace secrets in values
-	err := ReplaceSecretsInValues(ctx, &application)
-	errorutils.LogOnErr(nil, "error replacing secrets in values", err)
+	// validate against the crd schema
+	if application.SchemaVersion != "" {
+		err := ValidateArgocdApplication(application, application.SchemaVersion)
+		err = logger(ctx, pulumiResourceName).Wrap("error validating application", err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// marshall application to yaml
+	bytes, err := goyaml.Marshal(application)
+	err = logger(ctx, pulumiResourceName).Wrap("error marshalling application to yaml", err)
 	if err != nil {
 		return nil, err
 	}
-	// marshall application to yaml
-	bytes, err := yaml.Marshal(application)
-	errorutils.LogOnErr(nil, "error marshalling application to yaml", err)
+	// replace secrets in values
+	resolver, err := secretResolverFor(ctx, string(bytes))
+	err = logger(ctx, pulumiResourceName).Wrap("error replacing secrets in values", err)
 	if err != nil {
 		return nil, err
 	}
-	return SyncKubernetesManifest(ctx, pulumiResourceName, bytes, opts...)
+	replaceSecrets := func(state map[string]interface{}, opts ...pulumi.ResourceOption) {
+		replaceSecretsInApplicationValues(resolver, state)
+	}
+	return syncKubernetesManifest(ctx, pulumiResourceName, bytes, []yaml.Transformation{replaceSecrets}, opts...)
 }
 
 // NewApplicationFromBytes transforms yaml formatted byte array into an ArgocdApplication struct
 func NewApplicationFromBytes(bytes []byte) (ArgocdApplication, error) {
 	var application ArgocdApplication
 	// marshall template into map[string]interface{}
-	err := yaml.Unmarshal(bytes, &application)
+	err := goyaml.Unmarshal(bytes, &application)
 	errorutils.LogOnErr(nil, "error marshalling template to application", err)
 	return application, err
 }
 
-// ReplaceSecretsInValues uses a secrets provider to replace templated secret values in the application's helm values string
+// ReplaceSecretsInValues uses a secrets provider to replace templated secret values in the application's helm values
+// strings, of the single source and of every source of a multi-source application
+//
+// Deprecated: the replaced values are plain text in the application's state and diffs. SyncArgocdApplication replaces
+// secrets in the values as pulumi secret outputs instead.
 func ReplaceSecretsInValues(ctx *pulumi.Context, application *ArgocdApplication) (err error) {
 	values, err := secrets.ReplaceSecrets(ctx, application.Spec.Source.Helm.Values)
+	if err != nil {
+		return err
+	}
 	application.Spec.Source.Helm.Values = values
-	return err
+	for i := range application.Spec.Sources {
+		values, err = secrets.ReplaceSecrets(ctx, application.Spec.Sources[i].Helm.Values)
+		if err != nil {
+			return err
+		}
+		application.Spec.Sources[i].Helm.Values = values
+	}
+	return nil
+}
+
+// replaces templated secret values in the helm values strings of a rendered
+// application, of the single source and of every source of a multi-source
+// application, with pulumi secret outputs. meant as a yaml transformation of
+// the application's manifest
+func replaceSecretsInApplicationValues(resolver *secrets.SecretResolver, application map[string]interface{}) {
+	spec, ok := application["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	sources, _ := spec["sources"].([]interface{})
+	for _, source := range append([]interface{}{spec["source"]}, sources...) {
+		source, ok := source.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		helm, ok := source["helm"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if values, ok := helm["values"].(string); ok {
+			helm["values"] = replaceSecretsInValue(resolver, values)
+		}
+	}
+}
+
+// argo-cd annotations and finalizers controlling sync ordering and deletion
+const (
+	ArgocdSyncWaveAnnotation           = "argocd.argoproj.io/sync-wave"
+	ArgocdHookAnnotation               = "argocd.argoproj.io/hook"
+	ArgocdHookDeletePolicyAnnotation   = "argocd.argoproj.io/hook-delete-policy"
+	ArgocdResourcesFinalizer           = "resources-finalizer.argocd.argoproj.io"
+	ArgocdResourcesFinalizerBackground = "resources-finalizer.argocd.argoproj.io/background"
+)
+
+// SetAnnotation sets an annotation on the application's metadata
+func (a *ArgocdApplication) SetAnnotation(key string, value string) {
+	if a.Metadata == nil {
+		a.Metadata = map[string]interface{}{}
+	}
+	annotations, ok := a.Metadata["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = map[string]interface{}{}
+	}
+	annotations[key] = value
+	a.Metadata["annotations"] = annotations
+}
+
+// SetSyncWave orders the application among the other resources synced by a parent app-of-apps, lower waves sync first
+func (a *ArgocdApplication) SetSyncWave(wave int) {
+	a.SetAnnotation(ArgocdSyncWaveAnnotation, strconv.Itoa(wave))
+}
+
+// SetHook makes the application a resource hook of a parent app-of-apps, i.e. PreSync, with an optional delete policy,
+// i.e. HookSucceeded
+func (a *ArgocdApplication) SetHook(hook string, deletePolicy string) {
+	a.SetAnnotation(ArgocdHookAnnotation, hook)
+	if deletePolicy != "" {
+		a.SetAnnotation(ArgocdHookDeletePolicyAnnotation, deletePolicy)
+	}
+}
+
+// AddResourcesFinalizer makes argo-cd delete the application's resources when the application is deleted, in the
+// background if background is set
+func (a *ArgocdApplication) AddResourcesFinalizer(background bool) {
+	finalizer := ArgocdResourcesFinalizer
+	if background {
+		finalizer = ArgocdResourcesFinalizerBackground
+	}
+	if a.Metadata == nil {
+		a.Metadata = map[string]interface{}{}
+	}
+	finalizers, _ := a.Metadata["finalizers"].([]interface{})
+	for _, existing := range finalizers {
+		if existing == finalizer {
+			return
+		}
+	}
+	a.Metadata["finalizers"] = append(finalizers, finalizer)
 }
 
 // ArgocdApplication is a struct that marshalls into valid argocd application yaml. We could use the argo types but we have had
@@ -50,14 +158,38 @@ type ArgocdApplication struct {
 	Kind       string                 `yaml:"kind"`
 	Metadata   map[string]interface{} `yaml:"metadata"`
 	Spec       ArgocdApplicationSpec  `yaml:"spec"`
+
+	// optional argo-cd version whose Application CRD schema SyncArgocdApplication validates against, one of the keys
+	// of templates.ApplicationCRDBytes, i.e. "v2.6". Validation is skipped when not set
+	SchemaVersion string `yaml:"-"`
 }
 
+// ArgocdApplicationSpec holds either a single Source or, with argocd 2.6+, multiple Sources. The singular source is
+// omitted when sources are set
 type ArgocdApplicationSpec struct {
-	Source            ArgocdApplicationSpecSource          `yaml:"source"`
-	Destination       ArgocdApplicationSpecDestination     `yaml:"destination"`
-	Project           string                               `yaml:"project"`
-	SyncPolicy        ArgocdApplicationSyncPolicy          `yaml:"syncPolicy,omitempty"`
-	IgnoreDifferences []ArgocdApplicationIgnoreDifferences `yaml:"ignoreDifferences,omitempty"`
+	Source               ArgocdApplicationSpecSource          `yaml:"source,omitempty"`
+	Sources              []ArgocdApplicationSpecSource        `yaml:"sources,omitempty"`
+	Destination          ArgocdApplicationSpecDestination     `yaml:"destination"`
+	Project              string                               `yaml:"project"`
+	SyncPolicy           ArgocdApplicationSyncPolicy          `yaml:"syncPolicy,omitempty"`
+	IgnoreDifferences    []ArgocdApplicationIgnoreDifferences `yaml:"ignoreDifferences,omitempty"`
+	RevisionHistoryLimit *int64                               `yaml:"revisionHistoryLimit,omitempty"` // argo-cd defaults to 10
+	Info                 []ArgocdApplicationInfo              `yaml:"info,omitempty"`
+}
+
+type ArgocdApplicationInfo struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// MarshalYAML omits the legacy singular source of multi-source applications
+func (s ArgocdApplicationSpec) MarshalYAML() (interface{}, error) {
+	// marshal as a type without methods to avoid recursion
+	type spec ArgocdApplicationSpec
+	if len(s.Sources) != 0 {
+		s.Source = ArgocdApplicationSpecSource{}
+	}
+	return spec(s), nil
 }
 
 type ArgocdApplicationSpecSource struct {
@@ -69,6 +201,9 @@ type ArgocdApplicationSpecSource struct {
 	Directory      DirectorySource `yaml:"directory,omitempty"`
 	Plugin         PluginSource    `yaml:"plugin,omitempty"`
 	Chart          string          `yaml:"chart,omitempty"`
+	// names a source of a multi-source application, so that other sources can reference its files, i.e.
+	// $values/path/to/values.yaml
+	Ref string `yaml:"ref,omitempty"`
 }
 
 type HelmSource struct {
@@ -114,7 +249,7 @@ type DirectorySource struct {
 
 type DirectorySourceJsonnet struct {
 	ExtVars []JsonnetVar `yaml:"extVars,omitempty"`
-	TLAs    []JsonnetVar `yaml:"TLAs,omitempty"`
+	TLAs    []JsonnetVar `yaml:"tlas,omitempty"`
 	Libs    []string     `yaml:"libs,omitempty"`
 }
 
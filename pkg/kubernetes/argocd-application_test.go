@@ -0,0 +1,180 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/internal/golden"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/templates"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// returns the platform application the way the bootstrap configures it
+func testPlatformApplication(t *testing.T) ArgocdApplication {
+	application, err := NewApplicationFromBytes(templates.PlatformApplicationBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := int64(5)
+	application.Spec.Source.TargetRevision = "3.1.0"
+	application.Spec.Source.Helm.Values = "global:\n  clusterName: platform\n"
+	application.Spec.SyncPolicy = ArgocdApplicationSyncPolicy{
+		Automated:   SyncPolicyAutomated{Prune: true, SelfHeal: true},
+		Retry:       SyncPolicyRetry{Limit: 3, Backoff: RetryBackoff{Duration: "5s", Factor: 2, MaxDuration: "3m"}},
+		SyncOptions: []string{"CreateNamespace=true"},
+	}
+	application.Spec.RevisionHistoryLimit = &limit
+	application.AddResourcesFinalizer(false)
+	application.SetSyncWave(-1)
+	return application
+}
+
+// returns an application with a chart from a helm repository and its values
+// from a git repository
+func testMultiSourceApplication() ArgocdApplication {
+	return ArgocdApplication{
+		ApiVersion: "argoproj.io/v1alpha1",
+		Kind:       "Application",
+		Metadata:   map[string]interface{}{"name": "prometheus", "namespace": "argo-cd"},
+		Spec: ArgocdApplicationSpec{
+			// the legacy source is omitted in favour of the sources
+			Source: ArgocdApplicationSpecSource{RepoUrl: "https://example.com/ignored"},
+			Sources: []ArgocdApplicationSpecSource{
+				{
+					RepoUrl:        "https://prometheus-community.github.io/helm-charts",
+					Chart:          "prometheus",
+					TargetRevision: "19.0.0",
+					Helm:           HelmSource{ValueFiles: []string{"$values/prometheus/values.yaml"}},
+				},
+				{
+					RepoUrl:        "https://github.com/catalystcommunity/platform-config.git",
+					TargetRevision: "main",
+					Ref:            "values",
+				},
+			},
+			Destination: ArgocdApplicationSpecDestination{Server: "https://kubernetes.default.svc", Namespace: "monitoring"},
+			Project:     "default",
+			IgnoreDifferences: []ArgocdApplicationIgnoreDifferences{
+				{Group: "apps", Kind: "Deployment", JsonPointers: []string{"/spec/replicas"}},
+			},
+		},
+	}
+}
+
+func TestArgocdApplicationYaml(t *testing.T) {
+	applications := map[string]ArgocdApplication{
+		"platform-application.yaml":     testPlatformApplication(t),
+		"multi-source-application.yaml": testMultiSourceApplication(),
+	}
+	for name, application := range applications {
+		t.Run(name, func(t *testing.T) {
+			bytes, err := yaml.Marshal(application)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.Assert(t, name, bytes)
+
+			// the marshalled application reads back the same, except for the
+			// legacy source omitted for the sources
+			parsed, err := NewApplicationFromBytes(bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(application.Spec.Sources) != 0 {
+				application.Spec.Source = ArgocdApplicationSpecSource{}
+			}
+			if !reflect.DeepEqual(parsed.Spec, application.Spec) {
+				t.Errorf("application spec changed in a round trip:\ngot:  %+v\nwant: %+v", parsed.Spec, application.Spec)
+			}
+		})
+	}
+}
+
+func TestValidateArgocdApplication(t *testing.T) {
+	unknownField := testMultiSourceApplication()
+	unknownField.Spec.Sources[0].Helm.Values = "a: b"
+	unknownField.Metadata["labels"] = map[string]interface{}{"team": "platform"}
+
+	tests := []struct {
+		name        string
+		application ArgocdApplication
+		version     string
+		problems    []string
+	}{
+		{name: "single source v2.4", application: testPlatformApplication(t), version: "v2.4"},
+		{name: "single source v2.6", application: testPlatformApplication(t), version: "v2.6"},
+		{name: "multiple sources v2.6", application: testMultiSourceApplication(), version: "v2.6"},
+		{name: "metadata is not checked", application: unknownField, version: "v2.6"},
+		{
+			name:        "multiple sources v2.4",
+			application: testMultiSourceApplication(),
+			version:     "v2.4",
+			problems: []string{
+				"spec.source: missing required field",
+				"spec.sources: unknown field, the field may be unsupported by this argo-cd version",
+			},
+		},
+		{
+			name:        "unknown version",
+			application: testPlatformApplication(t),
+			version:     "v1.8",
+			problems:    []string{"unknown argo-cd schema version: v1.8 . Please use one of [v2.4 v2.6]"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateArgocdApplication(test.application, test.version)
+			if len(test.problems) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected problems %v, got no error", test.problems)
+			}
+			for _, problem := range test.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("expected problem %q, got %v", problem, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSchemaTypes(t *testing.T) {
+	schema := openAPISchema{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]openAPISchema{
+			"count":    {Type: "integer"},
+			"enabled":  {Type: "boolean"},
+			"port":     {IntOrString: true},
+			"labels":   {Type: "object", AdditionalProperties: &openAPISchema{Type: "string"}},
+			"raw":      {Type: "object", PreserveUnknownFields: true},
+			"selector": {Type: "object", Properties: map[string]openAPISchema{"matchLabels": {Type: "object"}}},
+		},
+	}
+	var value interface{}
+	err := yaml.Unmarshal([]byte(`
+count: "1"
+enabled: true
+port: http
+labels: {team: 1}
+raw: {anything: [1]}
+selector: {matchlabels: {}}
+`), &value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := validateSchema(schema, value, "")
+	expected := []string{
+		"name: missing required field",
+		"count: expected an integer, got string",
+		"labels.team: expected a string, got int",
+		"selector.matchlabels: unknown field, did you mean matchLabels?",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected problems %v, got %v", expected, problems)
+	}
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/app-utils-go/errorutils"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/yaml"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// SyncArgocdApplicationSet takes in a pulumi resource name, an argocd application set, and any pulumi options
+// It will sync the application set's yaml to k8s, replacing secrets in the spec.template.spec.source.helm.values with
+// pulumi secret outputs of the configured secrets provider
+func SyncArgocdApplicationSet(ctx *pulumi.Context, pulumiResourceName string, applicationSet ArgocdApplicationSet, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	// marshall application set to yaml
+	bytes, err := goyaml.Marshal(applicationSet)
+	err = logger(ctx, pulumiResourceName).Wrap("error marshalling application set to yaml", err)
+	if err != nil {
+		return nil, err
+	}
+	// replace secrets in the values of the application template
+	resolver, err := secretResolverFor(ctx, string(bytes))
+	err = logger(ctx, pulumiResourceName).Wrap("error replacing secrets in values", err)
+	if err != nil {
+		return nil, err
+	}
+	replaceSecrets := func(state map[string]interface{}, opts ...pulumi.ResourceOption) {
+		spec, _ := state["spec"].(map[string]interface{})
+		template, _ := spec["template"].(map[string]interface{})
+		replaceSecretsInApplicationValues(resolver, template)
+	}
+	return syncKubernetesManifest(ctx, pulumiResourceName, bytes, []yaml.Transformation{replaceSecrets}, opts...)
+}
+
+// NewApplicationSetFromBytes transforms yaml formatted byte array into an ArgocdApplicationSet struct
+func NewApplicationSetFromBytes(bytes []byte) (ArgocdApplicationSet, error) {
+	var applicationSet ArgocdApplicationSet
+	err := goyaml.Unmarshal(bytes, &applicationSet)
+	errorutils.LogOnErr(nil, "error marshalling template to application set", err)
+	return applicationSet, err
+}
+
+// ArgocdApplicationSet is a struct that marshalls into valid argocd application set yaml, see ArgocdApplication.
+// see spec at https://github.com/argoproj/argo-cd/blob/master/pkg/apis/application/v1alpha1/applicationset_types.go
+type ArgocdApplicationSet struct {
+	ApiVersion string                   `yaml:"apiVersion"`
+	Kind       string                   `yaml:"kind"`
+	Metadata   map[string]interface{}   `yaml:"metadata"`
+	Spec       ArgocdApplicationSetSpec `yaml:"spec"`
+}
+
+type ArgocdApplicationSetSpec struct {
+	GoTemplate bool                            `yaml:"goTemplate,omitempty"`
+	Generators []ApplicationSetGenerator       `yaml:"generators"`
+	Template   ApplicationSetTemplate          `yaml:"template"`
+	SyncPolicy ApplicationSetSyncPolicy        `yaml:"syncPolicy,omitempty"`
+	Strategy   ApplicationSetStrategy          `yaml:"strategy,omitempty"`
+	Ignore     []ApplicationSetIgnoreDiffering `yaml:"ignoreApplicationDifferences,omitempty"`
+}
+
+// ApplicationSetTemplate is the application rendered for every generated parameter set. Parameters are referenced
+// with {{param}}, or {{.param}} in go template mode.
+type ApplicationSetTemplate struct {
+	Metadata map[string]interface{} `yaml:"metadata"`
+	Spec     ArgocdApplicationSpec  `yaml:"spec"`
+}
+
+// ApplicationSetGenerator holds exactly one generator
+type ApplicationSetGenerator struct {
+	List     *ListGenerator         `yaml:"list,omitempty"`
+	Clusters *ClusterGenerator      `yaml:"clusters,omitempty"`
+	Git      *GitGenerator          `yaml:"git,omitempty"`
+	Matrix   *MatrixGenerator       `yaml:"matrix,omitempty"`
+	Selector map[string]interface{} `yaml:"selector,omitempty"`
+}
+
+type ListGenerator struct {
+	Elements []map[string]interface{} `yaml:"elements"`
+	Template *ApplicationSetTemplate  `yaml:"template,omitempty"`
+}
+
+type ClusterGenerator struct {
+	Selector ApplicationSetLabelSelector `yaml:"selector,omitempty"`
+	Values   map[string]string           `yaml:"values,omitempty"`
+	Template *ApplicationSetTemplate     `yaml:"template,omitempty"`
+}
+
+type GitGenerator struct {
+	RepoUrl             string                  `yaml:"repoURL"`
+	Revision            string                  `yaml:"revision,omitempty"`
+	Directories         []GitDirectoryGenerator `yaml:"directories,omitempty"`
+	Files               []GitFileGenerator      `yaml:"files,omitempty"`
+	PathParamPrefix     string                  `yaml:"pathParamPrefix,omitempty"`
+	RequeueAfterSeconds int                     `yaml:"requeueAfterSeconds,omitempty"`
+	Values              map[string]string       `yaml:"values,omitempty"`
+	Template            *ApplicationSetTemplate `yaml:"template,omitempty"`
+}
+
+type GitDirectoryGenerator struct {
+	Path    string `yaml:"path"`
+	Exclude bool   `yaml:"exclude,omitempty"`
+}
+
+type GitFileGenerator struct {
+	Path string `yaml:"path"`
+}
+
+// MatrixGenerator combines the parameters of its two child generators
+type MatrixGenerator struct {
+	Generators []ApplicationSetGenerator `yaml:"generators"`
+	Template   *ApplicationSetTemplate   `yaml:"template,omitempty"`
+}
+
+type ApplicationSetLabelSelector struct {
+	MatchLabels      map[string]string               `yaml:"matchLabels,omitempty"`
+	MatchExpressions []ApplicationSetLabelExpression `yaml:"matchExpressions,omitempty"`
+}
+
+type ApplicationSetLabelExpression struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values,omitempty"`
+}
+
+type ApplicationSetSyncPolicy struct {
+	PreserveResourcesOnDeletion bool   `yaml:"preserveResourcesOnDeletion,omitempty"`
+	ApplicationsSync            string `yaml:"applicationsSync,omitempty"`
+}
+
+type ApplicationSetStrategy struct {
+	Type        string                    `yaml:"type,omitempty"`
+	RollingSync ApplicationSetRollingSync `yaml:"rollingSync,omitempty"`
+}
+
+type ApplicationSetRollingSync struct {
+	Steps []ApplicationSetRolloutStep `yaml:"steps,omitempty"`
+}
+
+type ApplicationSetRolloutStep struct {
+	MatchExpressions []ApplicationSetLabelExpression `yaml:"matchExpressions,omitempty"`
+	MaxUpdate        string                          `yaml:"maxUpdate,omitempty"`
+}
+
+type ApplicationSetIgnoreDiffering struct {
+	Name              string   `yaml:"name,omitempty"`
+	JsonPointers      []string `yaml:"jsonPointers,omitempty"`
+	JQPathExpressions []string `yaml:"jqPathExpressions,omitempty"`
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/app-utils-go/errorutils"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+// SyncArgocdAppProject takes in a pulumi resource name, an argocd app project, and any pulumi options, then syncs the
+// resulting yaml to k8s
+func SyncArgocdAppProject(ctx *pulumi.Context, pulumiResourceName string, project ArgocdAppProject, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	// marshall project to yaml
+	bytes, err := yaml.Marshal(project)
+	err = logger(ctx, pulumiResourceName).Wrap("error marshalling app project to yaml", err)
+	if err != nil {
+		return nil, err
+	}
+	return SyncKubernetesManifest(ctx, pulumiResourceName, bytes, opts...)
+}
+
+// NewAppProjectFromBytes transforms yaml formatted byte array into an ArgocdAppProject struct
+func NewAppProjectFromBytes(bytes []byte) (ArgocdAppProject, error) {
+	var project ArgocdAppProject
+	err := yaml.Unmarshal(bytes, &project)
+	errorutils.LogOnErr(nil, "error marshalling template to app project", err)
+	return project, err
+}
+
+// ArgocdAppProject is a struct that marshalls into valid argocd app project yaml, see ArgocdApplication.
+// see spec at https://github.com/argoproj/argo-cd/blob/master/pkg/apis/application/v1alpha1/app_project_types.go
+type ArgocdAppProject struct {
+	ApiVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Metadata   map[string]interface{} `yaml:"metadata"`
+	Spec       ArgocdAppProjectSpec   `yaml:"spec"`
+}
+
+type ArgocdAppProjectSpec struct {
+	Description                string                             `yaml:"description,omitempty"`
+	SourceRepos                []string                           `yaml:"sourceRepos,omitempty"`
+	SourceNamespaces           []string                           `yaml:"sourceNamespaces,omitempty"`
+	Destinations               []ArgocdApplicationSpecDestination `yaml:"destinations,omitempty"`
+	ClusterResourceWhitelist   []AppProjectGroupKind              `yaml:"clusterResourceWhitelist,omitempty"`
+	ClusterResourceBlacklist   []AppProjectGroupKind              `yaml:"clusterResourceBlacklist,omitempty"`
+	NamespaceResourceWhitelist []AppProjectGroupKind              `yaml:"namespaceResourceWhitelist,omitempty"`
+	NamespaceResourceBlacklist []AppProjectGroupKind              `yaml:"namespaceResourceBlacklist,omitempty"`
+	Roles                      []AppProjectRole                   `yaml:"roles,omitempty"`
+	OrphanedResources          *AppProjectOrphanedResources       `yaml:"orphanedResources,omitempty"`
+	SyncWindows                []AppProjectSyncWindow             `yaml:"syncWindows,omitempty"`
+	SignatureKeys              []AppProjectSignatureKey           `yaml:"signatureKeys,omitempty"`
+}
+
+type AppProjectGroupKind struct {
+	Group string `yaml:"group"`
+	Kind  string `yaml:"kind"`
+}
+
+// AppProjectRole grants its policies to its groups and to the holders of its JWT tokens
+type AppProjectRole struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// casbin policies, i.e. p, proj:my-project:my-role, applications, sync, my-project/*, allow
+	Policies  []string             `yaml:"policies,omitempty"`
+	Groups    []string             `yaml:"groups,omitempty"`
+	JWTTokens []AppProjectJWTToken `yaml:"jwtTokens,omitempty"`
+}
+
+type AppProjectJWTToken struct {
+	IssuedAt  int64  `yaml:"iat"`
+	ExpiresAt int64  `yaml:"exp,omitempty"`
+	ID        string `yaml:"id,omitempty"`
+}
+
+// AppProjectOrphanedResources enables monitoring of resources in the project's destination namespaces that do not
+// belong to an application. Warn defaults to true in argocd, so it is a pointer
+type AppProjectOrphanedResources struct {
+	Warn   *bool                              `yaml:"warn,omitempty"`
+	Ignore []AppProjectOrphanedResourceIgnore `yaml:"ignore,omitempty"`
+}
+
+type AppProjectOrphanedResourceIgnore struct {
+	Group string `yaml:"group,omitempty"`
+	Kind  string `yaml:"kind,omitempty"`
+	Name  string `yaml:"name,omitempty"`
+}
+
+type AppProjectSyncWindow struct {
+	Kind         string   `yaml:"kind"`
+	Schedule     string   `yaml:"schedule"`
+	Duration     string   `yaml:"duration"`
+	Applications []string `yaml:"applications,omitempty"`
+	Namespaces   []string `yaml:"namespaces,omitempty"`
+	Clusters     []string `yaml:"clusters,omitempty"`
+	ManualSync   bool     `yaml:"manualSync,omitempty"`
+	TimeZone     string   `yaml:"timeZone,omitempty"`
+}
+
+type AppProjectSignatureKey struct {
+	KeyID string `yaml:"keyID"`
+}
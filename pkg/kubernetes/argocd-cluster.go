@@ -0,0 +1,129 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/eks"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ArgocdClusterInput struct {
+	// name of the cluster in argo-cd, defaults to the EKS cluster name
+	Name string `json:"name"`
+	// EKS cluster to register
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// optional, one of "aws" (default) which uses argo-cd's built in aws auth,
+	// or "exec" which configures an exec provider running argocd-k8s-auth
+	AuthMethod string `json:"auth-method"`
+	// optional role that argo-cd assumes to authenticate to the cluster,
+	// defaults to the IRSA role of the argo-cd service accounts
+	RoleArn string `json:"role-arn"`
+
+	// optional, namespace of argo-cd, defaults to argo-cd
+	Namespace string `json:"namespace"`
+	// optional cluster labels and annotations, i.e. to select the cluster in
+	// application set cluster generators
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+
+	// optional outputs of the eks module, the cluster is looked up by name
+	// when these are not supplied
+	Endpoint                 pulumi.StringOutput
+	CertificateAuthorityData pulumi.StringOutput
+}
+
+// argo-cd cluster auth methods
+const (
+	ArgocdClusterAuthAws  = "aws"
+	ArgocdClusterAuthExec = "exec"
+)
+
+// RegisterArgocdCluster registers an external EKS cluster with argo-cd by creating a cluster secret in the argo-cd
+// namespace, so that a hub argo-cd can deploy to spoke clusters. argo-cd authenticates to the cluster with IAM, so its
+// role must be mapped in the cluster's aws-auth configmap.
+func RegisterArgocdCluster(ctx *pulumi.Context, pulumiResourceName string, input ArgocdClusterInput, opts ...pulumi.ResourceOption) (*corev1.Secret, error) {
+	if input.EKSClusterName == "" {
+		return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot register argo-cd cluster")
+	}
+	name := input.EKSClusterName
+	if input.Name != "" {
+		name = input.Name
+	}
+	namespace := "argo-cd"
+	if input.Namespace != "" {
+		namespace = input.Namespace
+	}
+
+	endpoint := input.Endpoint
+	certificateAuthorityData := input.CertificateAuthorityData
+	if endpoint.OutputState == nil || certificateAuthorityData.OutputState == nil {
+		cluster := eks.LookupClusterOutput(ctx, eks.LookupClusterOutputArgs{
+			Name: pulumi.String(input.EKSClusterName),
+		})
+		endpoint = cluster.Endpoint()
+		certificateAuthorityData = cluster.CertificateAuthority().Data()
+	}
+
+	clusterConfig := certificateAuthorityData.ApplyT(func(caData string) (string, error) {
+		return renderArgocdClusterConfig(input, caData)
+	}).(pulumi.StringOutput)
+
+	labels := pulumi.StringMap{}
+	for key, value := range input.Labels {
+		labels[key] = pulumi.String(value)
+	}
+	labels["argocd.argoproj.io/secret-type"] = pulumi.String("cluster")
+
+	return corev1.NewSecret(ctx, pulumiResourceName, &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:        pulumi.String(name + "-cluster"),
+			Namespace:   pulumi.String(namespace),
+			Labels:      labels,
+			Annotations: pulumi.ToStringMap(input.Annotations),
+		},
+		StringData: pulumi.StringMap{
+			"name":   pulumi.String(name),
+			"server": endpoint,
+			"config": clusterConfig,
+		},
+		Type: pulumi.String("Opaque"),
+	}, opts...)
+}
+
+// renders the argo-cd cluster config json with the configured auth method
+func renderArgocdClusterConfig(input ArgocdClusterInput, certificateAuthorityData string) (string, error) {
+	clusterConfig := map[string]interface{}{
+		"tlsClientConfig": map[string]interface{}{
+			"caData": certificateAuthorityData,
+		},
+	}
+
+	switch input.AuthMethod {
+	case "", ArgocdClusterAuthAws:
+		awsAuthConfig := map[string]interface{}{
+			"clusterName": input.EKSClusterName,
+		}
+		if input.RoleArn != "" {
+			awsAuthConfig["roleARN"] = input.RoleArn
+		}
+		clusterConfig["awsAuthConfig"] = awsAuthConfig
+	case ArgocdClusterAuthExec:
+		args := []string{"aws", "--cluster-name", input.EKSClusterName}
+		if input.RoleArn != "" {
+			args = append(args, "--role-arn", input.RoleArn)
+		}
+		clusterConfig["execProviderConfig"] = map[string]interface{}{
+			"apiVersion": "client.authentication.k8s.io/v1beta1",
+			"command":    "argocd-k8s-auth",
+			"args":       args,
+		}
+	default:
+		return "", errorx.IllegalArgument.New("unknown argo-cd cluster auth method: %s", input.AuthMethod)
+	}
+
+	bytes, err := json.Marshal(clusterConfig)
+	return string(bytes), err
+}
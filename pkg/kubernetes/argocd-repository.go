@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"github.com/joomcode/errorx"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"strconv"
+)
+
+// RepoCredConfig configures an argo-cd repository, or a credential template that applies to every repository whose
+// url starts with Url. Credentials are read from pulumi config secrets, one of username and password (i.e. an https
+// personal access token), an ssh private key, or a GitHub App.
+type RepoCredConfig struct {
+	// unique name of the repository
+	Name string `json:"name" validate:"required"`
+	// one of git or helm, defaults to git
+	Type string `json:"type" validate:"oneof=git|helm"`
+	Url  string `json:"url" validate:"required"`
+	// optional, creates a credential template (repo-creds) instead of a
+	// repository
+	Template bool `json:"template"`
+	// optional, enables oci for helm repositories
+	EnableOci bool `json:"enable-oci"`
+
+	// optional names of pulumi config secrets holding the repository
+	// credentials, either username and password or an ssh private key
+	UsernameSecretKey      string `json:"username-secret-key"`
+	PasswordSecretKey      string `json:"password-secret-key"`
+	SshPrivateKeySecretKey string `json:"ssh-private-key-secret-key"`
+
+	// optional GitHub App credentials, the private key is read from a pulumi
+	// config secret
+	GithubAppId                  int64  `json:"github-app-id"`
+	GithubAppInstallationId      int64  `json:"github-app-installation-id"`
+	GithubAppPrivateKeySecretKey string `json:"github-app-private-key-secret-key"`
+	GithubAppEnterpriseBaseUrl   string `json:"github-app-enterprise-base-url"`
+
+	// optional, namespace of argo-cd, defaults to argo-cd
+	Namespace string `json:"namespace"`
+
+	// optional pulumi resource name, defaults to argocd-repository-<name>
+	ResourceName string
+	// optional, config to read secrets from, defaults to the project config
+	PulumiConfig *config.Config
+}
+
+// argo-cd repository secret types
+const (
+	ArgocdSecretTypeRepository = "repository"
+	ArgocdSecretTypeRepoCreds  = "repo-creds"
+)
+
+// NewArgocdRepoSecret creates an argo-cd repository or credential template secret in the argo-cd namespace, labeled
+// so that argo-cd picks it up
+func NewArgocdRepoSecret(ctx *pulumi.Context, repository RepoCredConfig, opts ...pulumi.ResourceOption) (*corev1.Secret, error) {
+	if repository.Name == "" || repository.Url == "" {
+		return nil, errorx.IllegalArgument.New("argo-cd repository requires a name and url")
+	}
+	resourceName := "argocd-repository-" + repository.Name
+	if repository.ResourceName != "" {
+		resourceName = repository.ResourceName
+	}
+	namespace := "argo-cd"
+	if repository.Namespace != "" {
+		namespace = repository.Namespace
+	}
+	cfg := repository.PulumiConfig
+	if cfg == nil {
+		cfg = config.New(ctx, "")
+	}
+	secretType := ArgocdSecretTypeRepository
+	if repository.Template {
+		secretType = ArgocdSecretTypeRepoCreds
+	}
+	repositoryType := "git"
+	if repository.Type != "" {
+		repositoryType = repository.Type
+	}
+
+	data := pulumi.StringMap{
+		"url":  pulumi.String(repository.Url),
+		"type": pulumi.String(repositoryType),
+	}
+	// templates match repositories by url only
+	if !repository.Template {
+		data["name"] = pulumi.String(repository.Name)
+	}
+	if repository.EnableOci {
+		data["enableOCI"] = pulumi.String("true")
+	}
+	if repository.UsernameSecretKey != "" {
+		data["username"] = cfg.RequireSecret(repository.UsernameSecretKey)
+	}
+	if repository.PasswordSecretKey != "" {
+		data["password"] = cfg.RequireSecret(repository.PasswordSecretKey)
+	}
+	if repository.SshPrivateKeySecretKey != "" {
+		data["sshPrivateKey"] = cfg.RequireSecret(repository.SshPrivateKeySecretKey)
+	}
+	if repository.GithubAppPrivateKeySecretKey != "" {
+		if repository.GithubAppId == 0 || repository.GithubAppInstallationId == 0 {
+			return nil, errorx.IllegalArgument.New("argo-cd repository %s requires a GitHub App id and installation id", repository.Name)
+		}
+		data["githubAppID"] = pulumi.String(strconv.FormatInt(repository.GithubAppId, 10))
+		data["githubAppInstallationID"] = pulumi.String(strconv.FormatInt(repository.GithubAppInstallationId, 10))
+		data["githubAppPrivateKey"] = cfg.RequireSecret(repository.GithubAppPrivateKeySecretKey)
+		if repository.GithubAppEnterpriseBaseUrl != "" {
+			data["githubAppEnterpriseBaseUrl"] = pulumi.String(repository.GithubAppEnterpriseBaseUrl)
+		}
+	}
+
+	return corev1.NewSecret(ctx, resourceName, &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String("argocd-" + secretType + "-" + repository.Name),
+			Namespace: pulumi.String(namespace),
+			Labels: pulumi.StringMap{
+				"argocd.argoproj.io/secret-type": pulumi.String(secretType),
+			},
+		},
+		StringData: data,
+		Type:       pulumi.String("Opaque"),
+	}, opts...)
+}
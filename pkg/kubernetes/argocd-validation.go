@@ -0,0 +1,168 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/templates"
+	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+)
+
+// a subset of an openapi v3 schema, enough to find unknown and mistyped fields
+type openAPISchema struct {
+	Type                  string                   `yaml:"type"`
+	Properties            map[string]openAPISchema `yaml:"properties"`
+	Items                 *openAPISchema           `yaml:"items"`
+	AdditionalProperties  *openAPISchema           `yaml:"additionalProperties"`
+	PreserveUnknownFields bool                     `yaml:"x-kubernetes-preserve-unknown-fields"`
+	IntOrString           bool                     `yaml:"x-kubernetes-int-or-string"`
+	Required              []string                 `yaml:"required"`
+}
+
+// the part of a CustomResourceDefinition holding the schema of each version
+type customResourceDefinition struct {
+	Spec struct {
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Schema struct {
+				OpenAPIV3Schema openAPISchema `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// the Application CRD version whose schema applications are validated against
+const argocdApplicationCRDVersion = "v1alpha1"
+
+// ValidateArgocdApplication validates an application against the schema of the embedded Application CRD of the given
+// argo-cd version, returning an error listing every unknown, mistyped, or missing field
+func ValidateArgocdApplication(application ArgocdApplication, version string) error {
+	schema, err := argocdApplicationSchema(version)
+	if err != nil {
+		return err
+	}
+
+	// validate the marshalled form, which is what is applied to the cluster
+	bytes, err := yaml.Marshal(application)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	err = yaml.Unmarshal(bytes, &value)
+	if err != nil {
+		return err
+	}
+
+	problems := validateSchema(schema, value, "")
+	if len(problems) != 0 {
+		return errorx.IllegalArgument.New("argocd application %v is not valid for argo-cd %s:\n%s", application.Metadata["name"], version, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// returns the openapi schema of the embedded Application CRD of the argo-cd version
+func argocdApplicationSchema(version string) (openAPISchema, error) {
+	crdBytes, ok := templates.ApplicationCRDBytes[version]
+	if !ok {
+		var versions []string
+		for v := range templates.ApplicationCRDBytes {
+			versions = append(versions, v)
+		}
+		sort.Strings(versions)
+		return openAPISchema{}, errorx.IllegalArgument.New("unknown argo-cd schema version: %s . Please use one of %v", version, versions)
+	}
+	var crd customResourceDefinition
+	err := yaml.Unmarshal(crdBytes, &crd)
+	if err != nil {
+		return openAPISchema{}, errorx.Decorate(err, "parsing the Application CRD of argo-cd %s", version)
+	}
+	for _, crdVersion := range crd.Spec.Versions {
+		if crdVersion.Name == argocdApplicationCRDVersion {
+			return crdVersion.Schema.OpenAPIV3Schema, nil
+		}
+	}
+	return openAPISchema{}, errorx.IllegalState.New("the Application CRD of argo-cd %s has no %s version", version, argocdApplicationCRDVersion)
+}
+
+// returns the problems of the value at the given path
+func validateSchema(schema openAPISchema, value interface{}, path string) []string {
+	var problems []string
+	if schema.IntOrString {
+		switch value.(type) {
+		case int, string:
+		default:
+			problems = append(problems, fmt.Sprintf("%s: expected an integer or a string, got %T", displayPath(path), value))
+		}
+		return problems
+	}
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %T", displayPath(path), value)}
+		}
+		// objects without properties, i.e. metadata, are not checked further
+		if schema.PreserveUnknownFields || (schema.Properties == nil && schema.AdditionalProperties == nil) {
+			return nil
+		}
+		for _, required := range schema.Required {
+			if _, ok := object[required]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field", displayPath(path+"."+required)))
+			}
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := path + "." + key
+			if property, ok := schema.Properties[key]; ok {
+				problems = append(problems, validateSchema(property, object[key], fieldPath)...)
+			} else if schema.AdditionalProperties != nil {
+				problems = append(problems, validateSchema(*schema.AdditionalProperties, object[key], fieldPath)...)
+			} else {
+				problems = append(problems, unknownFieldProblem(schema, key, fieldPath))
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %T", displayPath(path), value)}
+		}
+		if schema.Items != nil {
+			for i, item := range array {
+				problems = append(problems, validateSchema(*schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a string, got %T", displayPath(path), value))
+		}
+	case "integer":
+		if _, ok := value.(int); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected an integer, got %T", displayPath(path), value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a boolean, got %T", displayPath(path), value))
+		}
+	}
+	return problems
+}
+
+// describes an unknown field, suggesting a known field that differs only in
+// case, i.e. a renamed field
+func unknownFieldProblem(schema openAPISchema, key string, path string) string {
+	for property := range schema.Properties {
+		if strings.EqualFold(property, key) {
+			return fmt.Sprintf("%s: unknown field, did you mean %s?", displayPath(path), property)
+		}
+	}
+	return fmt.Sprintf("%s: unknown field, the field may be unsupported by this argo-cd version", displayPath(path))
+}
+
+func displayPath(path string) string {
+	return strings.TrimPrefix(path, ".")
+}
@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ClusterBootstrapResource groups the resources of a cluster bootstrap, so that the resource graph shows them under the
+// cluster and options set on the bootstrap, like protect, propagate to them.
+type ClusterBootstrapResource struct {
+	pulumi.ResourceState
+}
+
+const clusterBootstrapResourceType = "catalystcommunity:kubernetes:ClusterBootstrap"
+
+// registers the component resource of a cluster bootstrap, and returns the
+// options that parent a resource to it
+func newClusterBootstrapResource(ctx *pulumi.Context, name string, opts ...pulumi.ResourceOption) (*ClusterBootstrapResource, []pulumi.ResourceOption, error) {
+	resource := &ClusterBootstrapResource{}
+	err := ctx.RegisterComponentResource(clusterBootstrapResourceType, name, resource, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	childOpts := []pulumi.ResourceOption{
+		pulumi.Parent(resource),
+		pulumi.Transformations([]pulumi.ResourceTransformation{rootAliasTransformation(ctx)}),
+	}
+	return resource, childOpts, nil
+}
+
+// BootstrapComponentResource groups the resources of a bootstrap component that deploys more than one resource, so that
+// components depending on it depend on all of its resources.
+type BootstrapComponentResource struct {
+	pulumi.ResourceState
+}
+
+const bootstrapComponentResourceType = "catalystcommunity:kubernetes:BootstrapComponent"
+
+// NewBootstrapComponentResource registers the component resource of a bootstrap component, and returns the options that
+// parent a resource to it. Deploy functions return it once its resources are created, see
+// BootstrapComponentResource.Done.
+func NewBootstrapComponentResource(ctx *pulumi.Context, name string, opts ...pulumi.ResourceOption) (*BootstrapComponentResource, []pulumi.ResourceOption, error) {
+	resource := &BootstrapComponentResource{}
+	err := ctx.RegisterComponentResource(bootstrapComponentResourceType, name, resource, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	childOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.Parent(resource))
+	return resource, childOpts, nil
+}
+
+// Done completes the component resource after its resources are created, and returns it as the resource of the
+// bootstrap component
+func (r *BootstrapComponentResource) Done(ctx *pulumi.Context) (pulumi.Resource, error) {
+	err := ctx.RegisterResourceOutputs(r, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// aliases every resource to the urn it had when it was created on the root
+// stack, so that existing bootstraps move into the component without being
+// replaced
+func rootAliasTransformation(ctx *pulumi.Context) pulumi.ResourceTransformation {
+	return func(args *pulumi.ResourceTransformationArgs) *pulumi.ResourceTransformationResult {
+		if args.Type == clusterBootstrapResourceType || args.Type == bootstrapComponentResourceType {
+			return nil
+		}
+		rootUrn := fmt.Sprintf("urn:pulumi:%s::%s::%s::%s", ctx.Stack(), ctx.Project(), args.Type, args.Name)
+		return &pulumi.ResourceTransformationResult{
+			Props: args.Props,
+			Opts:  append(args.Opts, pulumi.Aliases([]pulumi.Alias{{URN: pulumi.URN(rootUrn)}})),
+		}
+	}
+}
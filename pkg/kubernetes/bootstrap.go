@@ -1,14 +1,23 @@
 package kubernetes
 
 import (
-	"github.com/catalystcommunity/app-utils-go/errorutils"
+	"crypto/sha256"
+	"fmt"
+	moduleconfig "github.com/catalystcommunity/pulumi-modules-go/pkg/config"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/cost"
 	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/security"
 	"github.com/catalystcommunity/pulumi-modules-go/pkg/templates"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
-	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/helm/v3"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
 type PlatformApplicationConfig struct {
@@ -16,6 +25,14 @@ type PlatformApplicationConfig struct {
 	TargetRevision string
 	SyncPolicy     ArgocdApplicationSyncPolicy
 	Values         string
+
+	// optional, deletes the platform services when the application is deleted
+	ResourcesFinalizer   bool
+	RevisionHistoryLimit *int64
+
+	// optional argo-cd version whose Application CRD schema the application
+	// is validated against, i.e. "v2.6", validation is skipped when not set
+	SchemaVersion string
 }
 
 type K8sPlatformConfigInput struct {
@@ -23,206 +40,797 @@ type K8sPlatformConfigInput struct {
 	ArgocdHelm              HelmReleaseConfigInput `json:"argocd-helm-release"`
 	KubePrometheusStackHelm HelmReleaseConfigInput `json:"kube-prometheus-stack-helm-release"`
 
+	// optional, installs the prometheus-operator CRDs before
+	// kube-prometheus-stack and argo-cd, so that service monitors can be
+	// created when kube-prometheus-stack is disabled or managed elsewhere
+	PrometheusOperatorCrds PrometheusOperatorCrdsConfigInput `json:"prometheus-operator-crds"`
+
+	// optional, argo-cd HA, ingress, and admin password settings
+	Argocd ArgocdConfigInput `json:"argocd"`
+
+	// optional, private repositories and credential templates that argo-cd is
+	// configured to pull from, created as argo-cd repository secrets
+	ArgocdRepositories []RepoCredConfig `json:"argocd-repositories"`
+
 	// optional, enable management of eks auth config
 	ManageEksAuthConfigMap bool `json:"manage-eks-auth-configmap"`
 
+	// optional, enable management of eks addons
+	ManageEksAddons bool `json:"manage-eks-addons"`
+
+	// optional, enable management of karpenter AWS resources
+	ManageKarpenter bool `json:"manage-karpenter"`
+
+	// optional, enable guardduty and the export of the control plane audit
+	// logs, and falco if configured
+	ManageSecurityBaseline bool `json:"manage-security-baseline"`
+
+	// optional falco install of the security baseline. falco is only
+	// installed with the baseline, so enabling it requires
+	// manage-security-baseline to be true, and validation fails otherwise
+	Falco FalcoConfigInput `json:"falco"`
+
+	// optional, enable management of aws budgets of the cluster's costs
+	ManageCostBudgets bool `json:"manage-cost-budgets"`
+
+	// optional, installs opencost
+	Opencost OpencostConfigInput `json:"opencost"`
+
+	// optional, installs external-secrets with ClusterSecretStores for AWS
+	ExternalSecrets ExternalSecretsConfigInput `json:"external-secrets"`
+
+	// optional, installs cert-manager with ClusterIssuers, instead of relying
+	// on the platform application to install it
+	CertManager CertManagerConfigInput `json:"cert-manager"`
+	// optional, installs external-dns for route53 or cloudflare
+	ExternalDns ExternalDnsConfigInput `json:"external-dns"`
+
+	// optional, installs the aws-load-balancer-controller
+	LoadBalancerController LoadBalancerControllerConfigInput `json:"load-balancer-controller"`
+
+	// optional, installs the cluster-autoscaler for node groups, as an
+	// alternative to karpenter
+	ClusterAutoscaler ClusterAutoscalerConfigInput `json:"cluster-autoscaler"`
+
+	// optional, installs metrics-server for horizontal pod autoscaling and the
+	// vertical-pod-autoscaler
+	MetricsServer         MetricsServerConfigInput         `json:"metrics-server"`
+	VerticalPodAutoscaler VerticalPodAutoscalerConfigInput `json:"vertical-pod-autoscaler"`
+
+	// optional, manages storage classes and an EFS filesystem
+	Storage StorageConfigInput `json:"storage"`
+
+	// optional, installs tempo or jaeger and an opentelemetry collector
+	Tracing TracingConfigInput `json:"tracing"`
+
+	// optional namespaces with pod security labels, quotas, limit ranges, and
+	// default-deny network policies
+	Namespaces []NamespaceConfig `json:"namespaces"`
+
+	// optional cluster roles granted to kubernetes groups, i.e. the
+	// permission groups of the aws-auth configmap
+	Rbac []RbacGroupConfigInput `json:"rbac"`
+
+	// optional dockerconfigjson image pull secrets of private registries,
+	// created in the configured namespaces
+	ImagePullSecrets []ImagePullSecretConfigInput `json:"image-pull-secrets"`
+
+	// optional, installs kyverno or gatekeeper with policies of the module's
+	// library
+	PolicyEngine PolicyEngineConfigInput `json:"policy-engine"`
+
+	// optional, installs ingress-nginx or traefik behind an NLB, requires the
+	// aws-load-balancer-controller
+	IngressController IngressControllerConfigInput `json:"ingress-controller"`
+
+	// optional, installs cilium as CNI, chained to or replacing the vpc cni,
+	// and istio in sidecar or ambient mode
+	ServiceMesh ServiceMeshConfigInput `json:"service-mesh"`
+
+	// optional priority classes and pod disruption budgets of the platform
+	// services
+	Resilience PlatformResilienceConfigInput `json:"resilience"`
+
+	// optional, manages the cluster with flux instead of argo-cd
+	Gitops GitopsConfigInput `json:"gitops"`
+
+	// optional app-of-apps root applications, whose child applications are
+	// managed by argo-cd
+	AppOfApps []AppOfAppsConfig `json:"app-of-apps"`
+
+	// optional, waits for argo-cd and the platform application to become
+	// healthy
+	HealthGates HealthGatesConfigInput `json:"health-gates"`
+
+	// optional dns solver of the cert-manager installed by the platform
+	// application, defaults to cloudflare
+	CertManagerDnsSolver CertManagerDnsSolverConfigInput `json:"cert-manager-dns-solver"`
+
 	// optional, management of prometheus remote write basic auth secret
 	ManagePrometheusRemoteWriteBasicAuthSecret bool `json:"manage-prometheus-remote-write-basic-auth-secret"`
 	// defaults to stack name
 	PrometheusRemoteWriteBasicAuthUsername string `json:"prometheus-remote-write-basic-auth-username"`
 	// defaults to "prometheus-remote-write-basic-auth"
 	PrometheusRemoteWriteSecretName string `json:"prometheus-remote-write-basic-auth-secret-name"`
+	// optional remote write endpoints rendered into the kube-prometheus-stack
+	// values, i.e. grafana cloud with the basic auth secret or amazon managed
+	// prometheus with sigv4
+	PrometheusRemoteWrite []PrometheusRemoteWriteConfigInput `json:"prometheus-remote-write"`
+
+	// optional alertmanager receivers and routes rendered into the
+	// kube-prometheus-stack values
+	Alertmanager AlertmanagerConfigInput `json:"alertmanager"`
+
+	// optional name of the pulumi config secret holding the grafana admin
+	// password, the values files are respected when not set
+	GrafanaAdminPasswordSecretKey string `json:"grafana-admin-password-secret-key"`
+	// optional dashboards and datasources provisioned into grafana through
+	// the sidecars of kube-prometheus-stack
+	Grafana GrafanaConfigInput `json:"grafana"`
+
+	// optional tags added to every AWS resource the bootstrap creates, i.e.
+	// cost-center, environment, or owner
+	Tags map[string]string `json:"tags"`
+
+	// optional ignore-changes and replace-on-changes policies of the
+	// bootstrap's resources, keyed by resource type or by type and name, see
+	// utils.ResourcePoliciesTransformation
+	ResourcePolicies map[string]utils.ResourcePolicyInput `json:"resource-policies"`
 
-	// input from eks module
+	// optional permissions boundary, path, and name prefix and suffix of
+	// every IAM role and policy the bootstrap creates
+	Iam utils.IamConventionsInput `json:"iam"`
+
+	// optional, enable, disable, or reorder bootstrap components by name, i.e.
+	// disable kube-prometheus-stack when using external observability.
+	// dependencies on disabled components are dropped
+	Components map[string]BootstrapComponentConfigInput `json:"components"`
+
+	// optional, renders the kubeconfig of an eks cluster to deploy against
+	// when KubeConfig is not supplied
+	EksKubeconfig eks.KubeconfigInput `json:"eks-kubeconfig"`
+
+	// input from eks module, the ambient kubeconfig is used when neither this
+	// nor EksKubeconfig are supplied
 	KubeConfig pulumi.StringOutput
 }
 
+type ArgocdConfigInput struct {
+	// optional, runs argo-cd in high availability mode with redis-ha and
+	// replicated components
+	HA bool `json:"ha"`
+	// optional replica counts, default to 2 in HA mode and the values files
+	// otherwise
+	ServerReplicas         int `json:"server-replicas"`
+	RepoServerReplicas     int `json:"repo-server-replicas"`
+	ApplicationSetReplicas int `json:"application-set-replicas"`
+
+	// optional server ingress
+	Ingress ArgocdIngressConfigInput `json:"ingress"`
+
+	// optional name of the pulumi config secret holding the initial admin
+	// password. it is only set on the first deployment, later changes must be
+	// made with the argocd cli
+	AdminPasswordSecretKey string `json:"admin-password-secret-key"`
+
+	// optional, enables or disables the service monitors of the argo-cd
+	// components. by default the values files decide, unless neither the
+	// kube-prometheus-stack component nor the prometheus-operator CRDs are
+	// installed, in which case they are disabled because the ServiceMonitor
+	// CRD may not exist
+	ServiceMonitors *bool `json:"service-monitors"`
+}
+
+type ArgocdIngressConfigInput struct {
+	Enabled          bool              `json:"enabled"`
+	Hostname         string            `json:"hostname"`
+	IngressClassName string            `json:"ingress-class-name"`
+	TlsSecretName    string            `json:"tls-secret-name"`
+	Annotations      map[string]string `json:"annotations"`
+}
+
+// ArgocdRepositoryConfigInput configures an argo-cd repository.
+//
+// Deprecated: use RepoCredConfig
+type ArgocdRepositoryConfigInput = RepoCredConfig
+
 type HelmReleaseConfigInput struct {
-	Version     string   `json:"version"`
+	// optional chart version, overrides the module default. "latest" or a ~
+	// or ^ range, i.e. "~33.1", is resolved from the chart repository index
+	// and requires allow-floating-version
+	Version string `json:"version" validate:"semverrange"`
+	// optional, allows the version to be "latest" or a range. the range is
+	// resolved on every run, so a new chart release upgrades the release on
+	// the next update. the resolved version is exported as the
+	// <resource name>-chart-version stack output
+	AllowFloatingVersion bool `json:"allow-floating-version"`
+
 	ValuesFiles []string `json:"values-files"`
+
+	// optional, overrides the upstream chart repository, i.e. with a mirror.
+	// oci registries are supported with an oci:// url
+	Repo string `json:"repo"`
+	// optional, overrides the upstream chart name
+	Chart string `json:"chart"`
+	// optional names of pulumi config secrets holding the chart repository or
+	// registry credentials
+	RepoUsernameSecretKey string `json:"repo-username-secret-key"`
+	RepoPasswordSecretKey string `json:"repo-password-secret-key"`
+	// optional, authenticates to an ecr oci registry with a token of the
+	// ambient aws credentials instead of secret credentials
+	RepoEcrAuth bool `json:"repo-ecr-auth"`
+
+	// optional inline values, merged over the values files
+	Values map[string]interface{} `json:"values"`
+	// optional secret values, mapping a dotted values path to the name of the
+	// pulumi config secret holding the value
+	SecretValues map[string]string `json:"secret-values"`
+
+	// optional, seconds to wait for individual kubernetes operations, defaults
+	// to helm's 300
+	Timeout int `json:"timeout"`
+	// optional, rolls back a failed install or upgrade
+	Atomic bool `json:"atomic"`
+	// optional, deletes new resources created by a failed upgrade
+	CleanupOnFail bool `json:"cleanup-on-fail"`
+	// optional, does not wait for the release's resources to become ready
+	SkipAwait bool `json:"skip-await"`
+	// optional, warns when a newer chart version than the deployed one is in
+	// the chart repository
+	CheckUpdates bool `json:"check-updates"`
+}
+
+// ClusterBootstrapConfig configures the bootstrap of one cluster in a multi-cluster stack
+type ClusterBootstrapConfig struct {
+	// unique name of the cluster, prefixes all resource names. leave empty for
+	// a single cluster so that resource names are not prefixed
+	Name string
+
+	// kubeconfig of the cluster, used when Provider is not set
+	KubeConfig pulumi.StringOutput
+
+	// optional provider of the cluster
+	Provider *kubernetes.Provider
+
+	// optional aws provider of the cluster's account and region, used for
+	// the aws resources and lookups of the bootstrap. the default provider is
+	// used when not set
+	AwsProvider *aws.Provider
+
+	// optional overrides of the stack's k8s, eks-auth, eks-addons,
+	// karpenter, security-baseline, and cost-budgets config objects
+	K8sConfig        *K8sPlatformConfigInput
+	EksAuth          *eks.AuthConfigMapInput
+	EksAddons        *eks.AddonsInput
+	Karpenter        *eks.KarpenterInput
+	SecurityBaseline *security.SecurityBaselineInput
+	CostBudgets      *cost.BudgetsInput
+
+	// optional components to deploy in addition to the registered components,
+	// replacing registered components of the same name
+	Components []BootstrapComponent
+
+	// optional options of the cluster's component resource, i.e. protect,
+	// which its resources inherit
+	ResourceOptions []pulumi.ResourceOption
 }
 
 // BootstrapCluster installs argo-cd and kube-prometheus-stack as helm charts, bootstraps the aws-auth configmap, and
 // installs the catalyst squad platform-services chart as an argocd application. Configurations set on stacks are respected.
+// Each step is a BootstrapComponent, so steps can be disabled or reordered from config, and more can be registered
+// with RegisterBootstrapComponent.
 func BootstrapCluster(ctx *pulumi.Context) error {
-	var k8sConfig K8sPlatformConfigInput
-	// get config
+	return BootstrapClusterWithKubeconfig(ctx, pulumi.StringOutput{})
+}
+
+// BootstrapClusterWithKubeconfig bootstraps the cluster of the given kubeconfig, typically the kubeconfig output of the
+// eks module, the same way as BootstrapCluster. All kubernetes resources are deployed with an explicit provider.
+func BootstrapClusterWithKubeconfig(ctx *pulumi.Context, kubeconfig pulumi.StringOutput) error {
+	err := bootstrapCluster(ctx, config.New(ctx, ""), ClusterBootstrapConfig{KubeConfig: kubeconfig})
+	return logger(ctx, "").LogOnErr("error bootstrapping cluster", err)
+}
+
+// BootstrapClusters bootstraps several clusters in one stack, i.e. a management stack bootstrapping its spoke
+// clusters. Each cluster has its own provider and config overrides, and its resource names are prefixed with its name.
+func BootstrapClusters(ctx *pulumi.Context, clusters []ClusterBootstrapConfig) error {
 	cfg := config.New(ctx, "")
-	err := cfg.GetObject("k8s", &k8sConfig)
-	errorutils.LogOnErr(nil, "error marshalling config to struct", err)
-	if err != nil {
-		return err
+	names := map[string]bool{}
+	for _, cluster := range clusters {
+		if cluster.Name == "" || names[cluster.Name] {
+			return errorx.IllegalArgument.New("every cluster must have a unique name, got: '%s'", cluster.Name)
+		}
+		names[cluster.Name] = true
+
+		log := logger(ctx, cluster.Name)
+		log.Debugf("bootstrapping cluster")
+		err := bootstrapCluster(ctx, cfg, cluster)
+		err = log.LogOnErr("error bootstrapping cluster", err)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// manage aws auth configmap, require additional configuration object if enabled
-	if k8sConfig.ManageEksAuthConfigMap {
-		var eksAuthConfig eks.AuthConfigMapInput
-		err = cfg.GetObject("eks-auth", &eksAuthConfig)
+// the default bootstrap pipeline, in registration order
+var builtinBootstrapComponents = []BootstrapComponent{
+	NewBootstrapComponent("eks-auth-configmap", nil, deployEksAuthConfigMap),
+	NewBootstrapComponent("eks-addons", nil, deployEksAddons),
+	NewBootstrapComponent("karpenter", nil, deployKarpenter),
+	NewBootstrapComponent("security-baseline", nil, deploySecurityBaseline),
+	NewBootstrapComponent("cost-budgets", nil, deployCostBudgets),
+	NewBootstrapComponent("prometheus-remote-write-basic-auth-secret", nil, deployPrometheusRemoteWriteBasicAuthSecret),
+	// before the platform services whose pods use the priority classes
+	NewBootstrapComponent("priority-classes", nil, deployPriorityClasses),
+	// before kube-prometheus-stack, which would otherwise create the CRDs outside of the crds release
+	NewBootstrapComponent("prometheus-operator-crds", nil, deployPrometheusOperatorCrds),
+	// this should happen before argo-cd because the argo-cd helm chart installs service monitors. argo-cd's service
+	// monitors are disabled when neither kube-prometheus-stack nor the CRDs are installed, see argocdServiceMonitorValues
+	NewBootstrapComponent("kube-prometheus-stack", []string{"prometheus-remote-write-basic-auth-secret", "priority-classes", "prometheus-operator-crds"}, deployKubePrometheusStack),
+	NewBootstrapComponent("argo-cd", []string{"kube-prometheus-stack", "priority-classes", "prometheus-operator-crds"}, deployArgocd),
+	NewBootstrapComponent("argo-cd-repositories", []string{"argo-cd"}, deployArgocdRepositories),
+	// replaces argo-cd when the gitops provider is flux
+	NewBootstrapComponent("flux", nil, deployFlux),
+	NewBootstrapComponent("external-secrets", nil, deployExternalSecrets),
+	NewBootstrapComponent("cert-manager", nil, deployCertManager),
+	NewBootstrapComponent("external-dns", nil, deployExternalDns),
+	NewBootstrapComponent("load-balancer-controller", nil, deployLoadBalancerController),
+	NewBootstrapComponent("cluster-autoscaler", nil, deployClusterAutoscaler),
+	NewBootstrapComponent("metrics-server", nil, deployMetricsServer),
+	NewBootstrapComponent("vertical-pod-autoscaler", []string{"metrics-server"}, deployVerticalPodAutoscaler),
+	// after the addons that install the csi drivers
+	NewBootstrapComponent("storage", []string{"eks-addons"}, deployStorage),
+	NewBootstrapComponent("tracing", nil, deployTracing),
+	NewBootstrapComponent("namespaces", nil, deployNamespaces),
+	NewBootstrapComponent("rbac", []string{"namespaces"}, deployRbac),
+	NewBootstrapComponent("image-pull-secrets", []string{"namespaces"}, deployImagePullSecrets),
+	NewBootstrapComponent("policy-engine", nil, deployPolicyEngine),
+	NewBootstrapComponent("falco", []string{"security-baseline", "priority-classes"}, deployFalco),
+	NewBootstrapComponent("opencost", []string{"kube-prometheus-stack"}, deployOpencost),
+	NewBootstrapComponent("grafana-provisioning", []string{"kube-prometheus-stack"}, deployGrafanaProvisioning),
+	// the NLB is provisioned by the aws-load-balancer-controller
+	NewBootstrapComponent("ingress-controller", []string{"load-balancer-controller", "priority-classes"}, deployIngressController),
+	// after the addons that install the vpc cni that cilium is chained to
+	NewBootstrapComponent("service-mesh", []string{"eks-addons"}, deployServiceMesh),
+	NewBootstrapComponent("pod-disruption-budgets", []string{"argo-cd", "kube-prometheus-stack", "ingress-controller"}, deployPodDisruptionBudgets),
+	// depend on argocd or flux for application CRDs
+	NewBootstrapComponent("platform-application", []string{"argo-cd", "flux"}, deployPlatformApplicationManifest),
+	NewBootstrapComponent("cert-manager-dns-solver-secret", []string{"platform-application"}, deployCertManagerDnsSolverSecret),
+	NewBootstrapComponent("app-of-apps", []string{"argo-cd"}, deployAppOfApps),
+}
+
+func bootstrapCluster(ctx *pulumi.Context, cfg *config.Config, cluster ClusterBootstrapConfig) error {
+	var k8sConfig K8sPlatformConfigInput
+	var err error
+	// get config
+	if cluster.K8sConfig != nil {
+		k8sConfig = *cluster.K8sConfig
+	} else {
+		err = moduleconfig.GetObject(cfg, "k8s", &k8sConfig)
+		err = logger(ctx, cluster.Name).Wrap("error marshalling config to struct", err)
 		if err != nil {
 			return err
 		}
+	}
+	if cluster.KubeConfig.OutputState != nil {
+		k8sConfig.KubeConfig = cluster.KubeConfig
+	}
+	bootstrap := &BootstrapContext{
+		Config:    cfg,
+		K8sConfig: k8sConfig,
+		Cluster:   cluster,
+	}
 
-		err = eks.SyncAuthConfigMap(ctx, eksAuthConfig)
+	// configure the kubernetes provider, the providers option only applies to
+	// kubernetes resources so it is safe to pass to the eks module as well
+	var providerOpt pulumi.ResourceOption
+	if cluster.Provider != nil {
+		providerOpt = pulumi.Providers(cluster.Provider)
+		bootstrap.kubeconfig = k8sConfig.KubeConfig
+	} else {
+		providerOpt, bootstrap.kubeconfig, err = kubernetesProviderOpt(ctx, bootstrap.ResourceName("k8s-provider"), k8sConfig)
+		err = logger(ctx, cluster.Name).Wrap("error configuring kubernetes provider", err)
 		if err != nil {
 			return err
 		}
 	}
 
-	// deploy kube-prometheus-stack remote-write basic auth secret
-	prometheusRemoteWriteSecret, err := deployPrometheusRemoteWriteBasicAuthSecret(ctx, cfg, k8sConfig)
-	errorutils.LogOnErr(nil, "error deploying kube-prometheus-stack remote-write basic auth secret", err)
+	opts := []pulumi.ResourceOption{providerOpt}
+	if cluster.AwsProvider != nil {
+		opts = append(opts, pulumi.Providers(cluster.AwsProvider))
+	}
+	if len(k8sConfig.Tags) != 0 {
+		opts = append(opts, utils.TagsOpt(k8sConfig.Tags))
+	}
+	if len(k8sConfig.ResourcePolicies) != 0 {
+		opts = append(opts, utils.ResourcePoliciesOpt(k8sConfig.ResourcePolicies))
+	}
+	if !k8sConfig.Iam.IsZero() {
+		opts = append(opts, utils.IamConventionsOpt(k8sConfig.Iam))
+	}
+
+	// group the cluster's resources under a component resource
+	resourceName := "cluster-bootstrap"
+	if cluster.Name != "" {
+		resourceName = cluster.Name
+	}
+	resource, childOpts, err := newClusterBootstrapResource(ctx, resourceName, cluster.ResourceOptions...)
 	if err != nil {
 		return err
 	}
+	opts = append(opts, childOpts...)
 
-	// dynamic depends on for an optional resource
-	var prometheusDependsOn pulumi.ResourceOption
-	if prometheusRemoteWriteSecret != nil {
-		prometheusDependsOn = pulumi.DependsOn([]pulumi.Resource{prometheusRemoteWriteSecret})
+	components := mergeBootstrapComponents(bootstrapComponents, cluster.Components...)
+	err = deployBootstrapComponents(ctx, bootstrap, components, opts...)
+	if err != nil {
+		return err
+	}
+	return ctx.RegisterResourceOutputs(resource, pulumi.Map{})
+}
+
+// manage aws auth configmap, require additional configuration object if enabled
+func deployEksAuthConfigMap(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.ManageEksAuthConfigMap {
+		return nil, nil
 	}
 
-	// deploy kube-prometheus-stack, this should happen first because the argo-cd helm chart installs service monitors
-	prometheus, err := deployKubePrometheusStack(ctx, k8sConfig, prometheusDependsOn)
-	errorutils.LogOnErr(nil, "error deploying kube-prometheus-stack", err)
+	var eksAuthConfig eks.AuthConfigMapInput
+	if bootstrap.Cluster.EksAuth != nil {
+		eksAuthConfig = *bootstrap.Cluster.EksAuth
+	} else {
+		err := moduleconfig.GetObject(bootstrap.Config, "eks-auth", &eksAuthConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if eksAuthConfig.ResourcePrefix == "" {
+		eksAuthConfig.ResourcePrefix = bootstrap.Cluster.Name
+	}
+	if eksAuthConfig.AwsProvider == nil {
+		eksAuthConfig.AwsProvider = bootstrap.Cluster.AwsProvider
+	}
+
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("eks-auth-configmap"), opts...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = eks.SyncAuthConfigMap(ctx, eksAuthConfig, resourceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Done(ctx)
+}
+
+// manage eks addons, require additional configuration object if enabled
+func deployEksAddons(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.ManageEksAddons {
+		return nil, nil
 	}
 
-	// deploy argocd
-	argocd, err := deployArgocd(ctx, cfg, k8sConfig, pulumi.DependsOn([]pulumi.Resource{prometheus})) // this helm chart installs service monitors, so it depends on kube-prometheus-stack
-	errorutils.LogOnErr(nil, "error deploying argocd", err)
+	var eksAddonsConfig eks.AddonsInput
+	if bootstrap.Cluster.EksAddons != nil {
+		eksAddonsConfig = *bootstrap.Cluster.EksAddons
+	} else {
+		err := moduleconfig.GetObject(bootstrap.Config, "eks-addons", &eksAddonsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if eksAddonsConfig.ResourcePrefix == "" {
+		eksAddonsConfig.ResourcePrefix = bootstrap.Cluster.Name
+	}
+
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("eks-addons"), opts...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	_, err = eks.SyncAddons(ctx, eksAddonsConfig, resourceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Done(ctx)
+}
+
+// manage karpenter AWS resources, require additional configuration object if enabled
+func deployKarpenter(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.ManageKarpenter {
+		return nil, nil
+	}
+
+	var karpenterConfig eks.KarpenterInput
+	if bootstrap.Cluster.Karpenter != nil {
+		karpenterConfig = *bootstrap.Cluster.Karpenter
+	} else {
+		err := moduleconfig.GetObject(bootstrap.Config, "karpenter", &karpenterConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if karpenterConfig.ResourcePrefix == "" {
+		karpenterConfig.ResourcePrefix = bootstrap.Cluster.Name
 	}
 
-	// deploy cluster argocd application
-	platformApplication, err := deployPlatformApplicationManifest(ctx, pulumi.DependsOn([]pulumi.Resource{argocd})) // depend on argocd for application CRDs
-	errorutils.LogOnErr(nil, "error deploying cluster application manifest", err)
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("karpenter"), opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	_, err = eks.SyncKarpenter(ctx, karpenterConfig, resourceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Done(ctx)
+}
 
-	// create cert-manager dns secret
-	err = deployCertManagerDnsSolverSecret(ctx, pulumi.DependsOn([]pulumi.Resource{platformApplication}))
-	errorutils.LogOnErr(nil, "error deploying cert manager dns solver secret", err)
-	return err
+// returns a providers option for the configured kubeconfig, or nil to use the
+// ambient kubeconfig
+func kubernetesProviderOpt(ctx *pulumi.Context, pulumiResourceName string, k8sConfig K8sPlatformConfigInput) (pulumi.ResourceOption, pulumi.StringOutput, error) {
+	if k8sConfig.KubeConfig.OutputState != nil {
+		provider, err := kubernetes.NewProvider(ctx, pulumiResourceName, &kubernetes.ProviderArgs{
+			Kubeconfig: k8sConfig.KubeConfig,
+		})
+		if err != nil {
+			return nil, pulumi.StringOutput{}, err
+		}
+		return pulumi.Providers(provider), k8sConfig.KubeConfig, nil
+	}
+
+	if k8sConfig.EksKubeconfig.EKSClusterName != "" {
+		kubeconfig, provider, err := eks.GetKubeconfig(ctx, pulumiResourceName, k8sConfig.EksKubeconfig)
+		if err != nil {
+			return nil, pulumi.StringOutput{}, err
+		}
+		return pulumi.Providers(provider), kubeconfig, nil
+	}
+
+	return nil, pulumi.StringOutput{}, nil
 }
 
-func deployPrometheusRemoteWriteBasicAuthSecret(ctx *pulumi.Context, cfg *config.Config, k8sConfig K8sPlatformConfigInput) (pulumi.Resource, error) {
+func deployPrometheusRemoteWriteBasicAuthSecret(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	k8sConfig := bootstrap.K8sConfig
 	if k8sConfig.ManagePrometheusRemoteWriteBasicAuthSecret {
 		username := ctx.Stack()
 		if k8sConfig.PrometheusRemoteWriteBasicAuthUsername != "" {
 			username = k8sConfig.PrometheusRemoteWriteBasicAuthUsername
 		}
 
-		secretName := "prometheus-remote-write-basic-auth"
-		if k8sConfig.PrometheusRemoteWriteSecretName != "" {
-			secretName = k8sConfig.PrometheusRemoteWriteSecretName
-		}
+		secretName := prometheusRemoteWriteSecretName(k8sConfig)
 
-		secret, err := corev1.NewSecret(ctx, "prometheus-remote-write-basic-auth-secret", &corev1.SecretArgs{
+		secret, err := corev1.NewSecret(ctx, bootstrap.ResourceName("prometheus-remote-write-basic-auth-secret"), &corev1.SecretArgs{
 			Metadata: &metav1.ObjectMetaArgs{
 				Name:      pulumi.String(secretName),
-				Namespace: pulumi.String("kube-prometheus-stack"),
+				Namespace: pulumi.String(kubePrometheusStackNamespace),
 			},
 			StringData: pulumi.StringMap{
 				"username": pulumi.String(username),
-				"password": cfg.RequireSecret("prometheusRemoteWriteBasicAuthPassword"),
+				"password": bootstrap.Config.RequireSecret("prometheusRemoteWriteBasicAuthPassword"),
 			},
-		})
-		return secret, err
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Export(bootstrap.ResourceName("prometheus-remote-write-basic-auth-password"), bootstrap.Config.RequireSecret("prometheusRemoteWriteBasicAuthPassword"))
+		return secret, nil
 	}
 
 	return nil, nil
 }
 
-func deployArgocd(ctx *pulumi.Context, cfg *config.Config, k8sConfig K8sPlatformConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
-	// set default helm chart versions if not defined
-	argocdVersion := "3.33.8"
-	if k8sConfig.ArgocdHelm.Version != "" {
-		argocdVersion = k8sConfig.ArgocdHelm.Version
-	}
-
-	// set default helm values files if not defined
-	argocdValues := []string{
-		"./helm-values/argo-cd-values.yaml",
-	}
-	if len(k8sConfig.ArgocdHelm.ValuesFiles) != 0 {
-		argocdValues = k8sConfig.ArgocdHelm.ValuesFiles
-	}
-
-	// deploy argo using helm
-	argocd, err := helm.NewRelease(ctx, "argo-cd", &helm.ReleaseArgs{
-		Chart:           pulumi.String("argo-cd"),
-		Name:            pulumi.String("argo-cd"),
-		Namespace:       pulumi.String("argo-cd"),
-		CreateNamespace: pulumi.Bool(true),
-		Version:         pulumi.String(argocdVersion),
-		RepositoryOpts: helm.RepositoryOptsArgs{
-			Repo: pulumi.String("https://argoproj.github.io/argo-helm"),
-		},
-		ValueYamlFiles: stringArrayToAssetOrArchiveArrayOutput(argocdValues),
-		Values: pulumi.Map{
-			"configs": pulumi.Map{
-				"repositories": pulumi.Map{
-					"matthews-helm": pulumi.Map{
-						"name":     pulumi.String("MatthewsREIS Github Helm Repository"),
-						"type":     pulumi.String("helm"),
-						"url":      pulumi.String("https://raw.githubusercontent.com/MatthewsREIS/charts/main"),
-						"username": cfg.RequireSecret("helmRepoPat"),
-						"password": cfg.RequireSecret("helmRepoPat"),
-					},
+func deployArgocd(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	k8sConfig := bootstrap.K8sConfig
+	if fluxEnabled(k8sConfig) {
+		return nil, nil
+	}
+	// export the configured admin password, a generated one is kept in the
+	// argocd-initial-admin-secret and shown by `argocd admin initial-password`
+	if k8sConfig.Argocd.AdminPasswordSecretKey != "" {
+		ctx.Export(bootstrap.ResourceName("argocd-admin-password"), bootstrap.Config.RequireSecret(k8sConfig.Argocd.AdminPasswordSecretKey))
+	}
+
+	values := argocdValues(bootstrap.Config, k8sConfig.Argocd)
+	values = mergeHelmValues(values, argocdServiceMonitorValues(k8sConfig))
+	values = mergeHelmValues(values, priorityClassValues(k8sConfig, PriorityClassPlatform, "controller", "server", "repoServer", "applicationSet", "redis", "dex"))
+
+	// deploy argo using helm, ignoring changes to the admin password hash
+	// because bcrypt is salted and renders a new hash on every run
+	release, err := DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:       bootstrap.ResourceName("argo-cd"),
+		Name:               "argo-cd",
+		Repo:               "https://argoproj.github.io/argo-helm",
+		Version:            "3.33.8",
+		DefaultValuesFiles: []string{"./helm-values/argo-cd-values.yaml"},
+		Values:             values,
+		Config:             k8sConfig.ArgocdHelm,
+		PulumiConfig:       bootstrap.Config,
+	}, append(opts, pulumi.IgnoreChanges([]string{"values.configs.secret.argocdServerAdminPassword"}))...)
+	if err != nil || !k8sConfig.HealthGates.Enabled {
+		return release, err
+	}
+
+	// wait again whenever the release is upgraded
+	revision := release.Status.Revision().ApplyT(func(revision *int) string {
+		if revision == nil {
+			return ""
+		}
+		return fmt.Sprint(*revision)
+	}).(pulumi.StringOutput)
+	return deployArgocdHealthGate(ctx, bootstrap, release, revision, opts...)
+}
+
+// renders argo-cd HA, ingress, and admin password settings into helm values
+func argocdValues(cfg *config.Config, argocdConfig ArgocdConfigInput) pulumi.Map {
+	values := pulumi.Map{}
+
+	// replica counts are only rendered in HA mode or when configured, so that
+	// the values files are respected otherwise
+	defaultReplicas := 0
+	if argocdConfig.HA {
+		defaultReplicas = 2
+		values["redis-ha"] = pulumi.Map{
+			"enabled": pulumi.Bool(true),
+		}
+	}
+	replicas := func(component pulumi.Map, key string, configured int) {
+		if configured != 0 {
+			component[key] = pulumi.Int(configured)
+		} else if defaultReplicas != 0 {
+			component[key] = pulumi.Int(defaultReplicas)
+		}
+	}
+	server := pulumi.Map{}
+	repoServer := pulumi.Map{}
+	applicationSet := pulumi.Map{}
+	replicas(server, "replicas", argocdConfig.ServerReplicas)
+	replicas(repoServer, "replicas", argocdConfig.RepoServerReplicas)
+	replicas(applicationSet, "replicaCount", argocdConfig.ApplicationSetReplicas)
+
+	if argocdConfig.Ingress.Enabled {
+		ingress := pulumi.Map{
+			"enabled":     pulumi.Bool(true),
+			"hosts":       pulumi.ToStringArray([]string{argocdConfig.Ingress.Hostname}),
+			"annotations": pulumi.ToStringMap(argocdConfig.Ingress.Annotations),
+		}
+		if argocdConfig.Ingress.IngressClassName != "" {
+			ingress["ingressClassName"] = pulumi.String(argocdConfig.Ingress.IngressClassName)
+		}
+		if argocdConfig.Ingress.TlsSecretName != "" {
+			ingress["tls"] = pulumi.Array{
+				pulumi.Map{
+					"secretName": pulumi.String(argocdConfig.Ingress.TlsSecretName),
+					"hosts":      pulumi.ToStringArray([]string{argocdConfig.Ingress.Hostname}),
 				},
-			}},
-	}, opts...)
-	return argocd, err
+			}
+		}
+		server["ingress"] = ingress
+		server["config"] = pulumi.Map{
+			"url": pulumi.String(fmt.Sprintf("https://%s", argocdConfig.Ingress.Hostname)),
+		}
+	}
+
+	for key, component := range map[string]pulumi.Map{"server": server, "repoServer": repoServer, "applicationSet": applicationSet} {
+		if len(component) != 0 {
+			values[key] = component
+		}
+	}
+
+	// argo-cd expects a bcrypt hash of the admin password
+	if argocdConfig.AdminPasswordSecretKey != "" {
+		values["configs"] = pulumi.Map{
+			"secret": pulumi.Map{
+				"argocdServerAdminPassword": cfg.RequireSecret(argocdConfig.AdminPasswordSecretKey).ApplyT(func(password string) (string, error) {
+					hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+					return string(hash), err
+				}).(pulumi.StringOutput),
+			},
+		}
+	}
+
+	return values
 }
 
-func deployKubePrometheusStack(ctx *pulumi.Context, cfg K8sPlatformConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
-	kubePrometheusStackVersion := "33.1.0"
-	if cfg.KubePrometheusStackHelm.Version != "" {
-		kubePrometheusStackVersion = cfg.KubePrometheusStackHelm.Version
+// renders whether the argo-cd components create service monitors. nil is
+// returned when the values files decide
+func argocdServiceMonitorValues(k8sConfig K8sPlatformConfigInput) pulumi.Map {
+	serviceMonitors := k8sConfig.Argocd.ServiceMonitors
+	if serviceMonitors == nil {
+		crds := k8sConfig.PrometheusOperatorCrds.Enabled && bootstrapComponentEnabled(k8sConfig, "prometheus-operator-crds")
+		if crds || bootstrapComponentEnabled(k8sConfig, "kube-prometheus-stack") {
+			return nil
+		}
+		disabled := false
+		serviceMonitors = &disabled
 	}
 
-	prometheusValues := []string{
-		"./helm-values/prometheus-values.yaml",
+	values := pulumi.Map{}
+	for _, component := range []string{"controller", "server", "repoServer", "applicationSet", "dex", "redis"} {
+		metrics := pulumi.Map{
+			"serviceMonitor": pulumi.Map{
+				"enabled": pulumi.Bool(*serviceMonitors),
+			},
+		}
+		// service monitors need the metrics services
+		if *serviceMonitors {
+			metrics["enabled"] = pulumi.Bool(true)
+		}
+		values[component] = pulumi.Map{
+			"metrics": metrics,
+		}
 	}
-	if len(cfg.KubePrometheusStackHelm.ValuesFiles) != 0 {
-		prometheusValues = cfg.KubePrometheusStackHelm.ValuesFiles
+	return values
+}
+
+// creates the configured argo-cd repository secrets. argo-cd retries syncs
+// that fail on missing credentials, so nothing depends on the secrets
+func deployArgocdRepositories(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if len(bootstrap.K8sConfig.ArgocdRepositories) != 0 && fluxEnabled(bootstrap.K8sConfig) {
+		return nil, errorx.IllegalArgument.New("argo-cd repositories require the argocd gitops provider")
+	}
+	for _, repository := range bootstrap.K8sConfig.ArgocdRepositories {
+		if repository.ResourceName == "" {
+			repository.ResourceName = bootstrap.ResourceName("argocd-repository-" + repository.Name)
+		}
+		if repository.PulumiConfig == nil {
+			repository.PulumiConfig = bootstrap.Config
+		}
+		_, err := NewArgocdRepoSecret(ctx, repository, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func deployKubePrometheusStack(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	values, err := kubePrometheusStackValues(ctx, bootstrap, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if bootstrap.K8sConfig.GrafanaAdminPasswordSecretKey != "" {
+		ctx.Export(bootstrap.ResourceName("grafana-admin-password"), bootstrap.Config.RequireSecret(bootstrap.K8sConfig.GrafanaAdminPasswordSecretKey))
 	}
 
 	// deploy prometheus using helm
-	return helm.NewRelease(ctx, "kube-prometheus-stack", &helm.ReleaseArgs{
-		Chart:           pulumi.String("kube-prometheus-stack"),
-		Name:            pulumi.String("kube-prometheus-stack"),
-		Namespace:       pulumi.String("kube-prometheus-stack"),
-		CreateNamespace: pulumi.Bool(true),
-		Version:         pulumi.String(kubePrometheusStackVersion),
-		RepositoryOpts: helm.RepositoryOptsArgs{
-			Repo: pulumi.String("https://prometheus-community.github.io/helm-charts"),
-		},
-		ValueYamlFiles: stringArrayToAssetOrArchiveArrayOutput(prometheusValues),
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:       bootstrap.ResourceName("kube-prometheus-stack"),
+		Name:               "kube-prometheus-stack",
+		Repo:               "https://prometheus-community.github.io/helm-charts",
+		Version:            "33.1.0",
+		DefaultValuesFiles: []string{"./helm-values/prometheus-values.yaml"},
+		Values:             values,
+		Config:             bootstrap.K8sConfig.KubePrometheusStackHelm,
+		PulumiConfig:       bootstrap.Config,
 	}, opts...)
 }
 
-func deployCertManagerDnsSolverSecret(ctx *pulumi.Context, opts ...pulumi.ResourceOption) error {
-	cfg := config.New(ctx, "")
-	_, err := corev1.NewSecret(ctx, "cert-manager-cloudflare-api-token-secret", &corev1.SecretArgs{
-		Metadata: &metav1.ObjectMetaArgs{
-			Name:      pulumi.String("cloudflare-api-token-secret"),
-			Namespace: pulumi.String("cert-manager"),
-		},
-		StringData: pulumi.StringMap{
-			"api-token": cfg.RequireSecret("cloudflareApiToken"),
-		},
-		Type: pulumi.String("Opaque"),
-	}, opts...)
-	return err
+// creates the credentials of the dns solver used by the cert-manager that the
+// platform application installs, defaults to cloudflare
+func deployCertManagerDnsSolverSecret(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	solver := bootstrap.K8sConfig.CertManagerDnsSolver
+	switch solver.Type {
+	case "", CertManagerSolverCloudflare:
+		secretKey := "cloudflareApiToken"
+		if solver.CloudflareApiTokenSecretKey != "" {
+			secretKey = solver.CloudflareApiTokenSecretKey
+		}
+		return newCertManagerSecret(ctx, bootstrap, "cert-manager-cloudflare-api-token-secret", "cloudflare-api-token-secret", "api-token", secretKey, opts...)
+	case CertManagerSolverGoogleCloudDns:
+		return newCertManagerSecret(ctx, bootstrap, "cert-manager-clouddns-service-account-secret", "clouddns-service-account", "key.json", solver.GoogleServiceAccountKeySecretKey, opts...)
+	case CertManagerSolverRoute53:
+		// route53 is authenticated with IRSA, the role arn is exported so that
+		// it can be set on the cert-manager service account
+		roleArn, err := newCertManagerRoute53Role(ctx, bootstrap, solver.EKSClusterName, solver.Route53HostedZoneIds, opts...)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Export(bootstrap.ResourceName("cert-manager-role-arn"), roleArn)
+		return nil, nil
+	default:
+		return nil, errorx.IllegalArgument.New("unknown cert-manager dns solver type: '%s'", solver.Type)
+	}
 }
 
-func deployPlatformApplicationManifest(ctx *pulumi.Context, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+func deployPlatformApplicationManifest(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
 	var platformApplicationConfig PlatformApplicationConfig
-	cfg := config.New(ctx, "")
+	cfg := bootstrap.Config
 	cfg.RequireObject("platform-application", &platformApplicationConfig)
 	if platformApplicationConfig.Enabled {
 		// get application from template
@@ -234,18 +842,30 @@ func deployPlatformApplicationManifest(ctx *pulumi.Context, opts ...pulumi.Resou
 		application.Spec.SyncPolicy = platformApplicationConfig.SyncPolicy
 		application.Spec.Source.TargetRevision = platformApplicationConfig.TargetRevision
 		application.Spec.Source.Helm.Values = platformApplicationConfig.Values
+		application.Spec.RevisionHistoryLimit = platformApplicationConfig.RevisionHistoryLimit
+		application.SchemaVersion = platformApplicationConfig.SchemaVersion
+		if platformApplicationConfig.ResourcesFinalizer {
+			application.AddResourcesFinalizer(false)
+		}
+		if fluxEnabled(bootstrap.K8sConfig) {
+			return deployFluxPlatformApplication(ctx, bootstrap, application, opts...)
+		}
 		// sync
-		resource, err := SyncArgocdApplication(ctx, "cluster-services", application, opts...)
-		errorutils.LogOnErr(nil, "error syncing cluster application", err)
-		return resource, err
-	}
-	return nil, nil
-}
+		resource, err := SyncArgocdApplication(ctx, bootstrap.ResourceName("cluster-services"), application, opts...)
+		err = logger(ctx, bootstrap.ResourceName("cluster-services")).Wrap("error syncing cluster application", err)
+		if err != nil || !bootstrap.K8sConfig.HealthGates.Enabled {
+			return resource, err
+		}
 
-func stringArrayToAssetOrArchiveArrayOutput(in []string) pulumi.AssetOrArchiveArrayOutput {
-	var o pulumi.AssetOrArchiveArray
-	for _, i := range in {
-		o = append(o, pulumi.NewFileAsset(i))
+		// wait again whenever the application changes
+		manifest, err := yaml.Marshal(application)
+		if err != nil {
+			return nil, err
+		}
+		name, _ := application.Metadata["name"].(string)
+		namespace, _ := application.Metadata["namespace"].(string)
+		trigger := pulumi.String(fmt.Sprintf("%x", sha256.Sum256(manifest)))
+		return deployApplicationHealthGate(ctx, bootstrap, name, namespace, resource, trigger, opts...)
 	}
-	return o.ToAssetOrArchiveArrayOutput()
+	return nil, nil
 }
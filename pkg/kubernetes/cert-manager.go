@@ -0,0 +1,294 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/joomcode/errorx"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"gopkg.in/yaml.v3"
+)
+
+type CertManagerConfigInput struct {
+	// optional, installs cert-manager
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+
+	// optional, cluster whose OIDC provider is trusted by the cert-manager
+	// IRSA role, required by route53 solvers
+	EKSClusterName string `json:"eks-cluster-name"`
+
+	// optional cluster issuers to create once cert-manager is installed
+	ClusterIssuers []ClusterIssuerConfigInput `json:"cluster-issuers"`
+}
+
+type ClusterIssuerConfigInput struct {
+	Name string `json:"name"`
+	// acme account email
+	Email string `json:"email"`
+	// optional acme server, defaults to letsencrypt production
+	Server string `json:"server"`
+
+	Solver CertManagerSolverConfigInput `json:"solver"`
+}
+
+type CertManagerSolverConfigInput struct {
+	// one of "cloudflare", "route53", "google-clouddns", or "http01"
+	Type string `json:"type" validate:"oneof=cloudflare|route53|google-clouddns|http01"`
+	// optional dns zones the solver applies to, defaults to all
+	DnsZones []string `json:"dns-zones"`
+
+	// cloudflare, optional name of the pulumi config secret holding the api
+	// token, defaults to cloudflareApiToken
+	CloudflareApiTokenSecretKey string `json:"cloudflare-api-token-secret-key"`
+
+	// route53, hosted zones that the IRSA role may change
+	Route53HostedZoneIds []string `json:"route53-hosted-zone-ids"`
+	// optional, defaults to the aws:region config
+	Route53Region string `json:"route53-region"`
+
+	// google-clouddns, project of the managed zones and the name of the
+	// pulumi config secret holding the service account key json
+	GoogleProject                    string `json:"google-project"`
+	GoogleServiceAccountKeySecretKey string `json:"google-service-account-key-secret-key"`
+
+	// http01, optional ingress class of the solver ingress
+	IngressClass string `json:"ingress-class"`
+}
+
+type CertManagerDnsSolverConfigInput struct {
+	CertManagerSolverConfigInput
+
+	// route53, cluster whose OIDC provider is trusted by the cert-manager
+	// IRSA role
+	EKSClusterName string `json:"eks-cluster-name"`
+}
+
+// cert-manager solver types
+const (
+	CertManagerSolverCloudflare     = "cloudflare"
+	CertManagerSolverRoute53        = "route53"
+	CertManagerSolverGoogleCloudDns = "google-clouddns"
+	CertManagerSolverHttp01         = "http01"
+)
+
+// installs cert-manager and its IRSA role, then creates the configured
+// cluster issuers and their solver secrets
+func deployCertManager(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	certManagerConfig := bootstrap.K8sConfig.CertManager
+	if !certManagerConfig.Enabled {
+		return nil, nil
+	}
+
+	values := pulumi.Map{
+		"installCRDs": pulumi.Bool(true),
+	}
+	var hostedZoneIds []string
+	for _, issuer := range certManagerConfig.ClusterIssuers {
+		if issuer.Solver.Type == CertManagerSolverRoute53 {
+			hostedZoneIds = append(hostedZoneIds, issuer.Solver.Route53HostedZoneIds...)
+		}
+	}
+	if len(hostedZoneIds) != 0 {
+		role, err := newCertManagerRoute53Role(ctx, bootstrap, certManagerConfig.EKSClusterName, hostedZoneIds, opts...)
+		if err != nil {
+			return nil, err
+		}
+		values["serviceAccount"] = pulumi.Map{
+			"annotations": pulumi.Map{
+				"eks.amazonaws.com/role-arn": role,
+			},
+		}
+		// allows cert-manager to read the projected IRSA token
+		values["securityContext"] = pulumi.Map{
+			"fsGroup": pulumi.Int(1001),
+		}
+	}
+
+	release, err := DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("cert-manager"),
+		Name:         "cert-manager",
+		Repo:         "https://charts.jetstack.io",
+		Version:      "v1.13.3",
+		Values:       values,
+		Config:       certManagerConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the helm release waits for cert-manager to be ready, so its CRDs and
+	// webhook are available to the issuers
+	issuerOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{release}))
+	for _, issuer := range certManagerConfig.ClusterIssuers {
+		err = deployClusterIssuer(ctx, bootstrap, issuer, issuerOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// creates a cluster issuer and the secret of its solver
+func deployClusterIssuer(ctx *pulumi.Context, bootstrap *BootstrapContext, issuer ClusterIssuerConfigInput, opts ...pulumi.ResourceOption) error {
+	if issuer.Name == "" || issuer.Email == "" {
+		return errorx.IllegalArgument.New("cluster issuer requires a name and email")
+	}
+	server := "https://acme-v02.api.letsencrypt.org/directory"
+	if issuer.Server != "" {
+		server = issuer.Server
+	}
+
+	solver, err := certManagerSolver(ctx, bootstrap, issuer, opts...)
+	if err != nil {
+		return err
+	}
+	if len(issuer.Solver.DnsZones) != 0 {
+		solver["selector"] = map[string]interface{}{
+			"dnsZones": issuer.Solver.DnsZones,
+		}
+	}
+
+	manifest, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "ClusterIssuer",
+		"metadata": map[string]interface{}{
+			"name": issuer.Name,
+		},
+		"spec": map[string]interface{}{
+			"acme": map[string]interface{}{
+				"email":  issuer.Email,
+				"server": server,
+				"privateKeySecretRef": map[string]interface{}{
+					"name": issuer.Name + "-account-key",
+				},
+				"solvers": []interface{}{solver},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = SyncKubernetesManifest(ctx, bootstrap.ResourceName(issuer.Name+"-cluster-issuer"), manifest, opts...)
+	return err
+}
+
+// renders the acme solver of an issuer, creating its credentials secret
+func certManagerSolver(ctx *pulumi.Context, bootstrap *BootstrapContext, issuer ClusterIssuerConfigInput, opts ...pulumi.ResourceOption) (map[string]interface{}, error) {
+	switch issuer.Solver.Type {
+	case CertManagerSolverCloudflare:
+		secretKey := "cloudflareApiToken"
+		if issuer.Solver.CloudflareApiTokenSecretKey != "" {
+			secretKey = issuer.Solver.CloudflareApiTokenSecretKey
+		}
+		secretName := issuer.Name + "-cloudflare-api-token"
+		_, err := newCertManagerSecret(ctx, bootstrap, secretName, secretName, "api-token", secretKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"dns01": map[string]interface{}{
+				"cloudflare": map[string]interface{}{
+					"apiTokenSecretRef": map[string]interface{}{
+						"name": secretName,
+						"key":  "api-token",
+					},
+				},
+			},
+		}, nil
+	case CertManagerSolverRoute53:
+		region := issuer.Solver.Route53Region
+		if region == "" {
+			region = config.New(ctx, "aws").Require("region")
+		}
+		route53 := map[string]interface{}{
+			"region": region,
+		}
+		if len(issuer.Solver.Route53HostedZoneIds) == 1 {
+			route53["hostedZoneID"] = issuer.Solver.Route53HostedZoneIds[0]
+		}
+		return map[string]interface{}{
+			"dns01": map[string]interface{}{
+				"route53": route53,
+			},
+		}, nil
+	case CertManagerSolverGoogleCloudDns:
+		if issuer.Solver.GoogleProject == "" {
+			return nil, errorx.IllegalArgument.New("google-clouddns solver of issuer %s requires a google project", issuer.Name)
+		}
+		secretName := issuer.Name + "-clouddns-service-account"
+		_, err := newCertManagerSecret(ctx, bootstrap, secretName, secretName, "key.json", issuer.Solver.GoogleServiceAccountKeySecretKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"dns01": map[string]interface{}{
+				"cloudDNS": map[string]interface{}{
+					"project": issuer.Solver.GoogleProject,
+					"serviceAccountSecretRef": map[string]interface{}{
+						"name": secretName,
+						"key":  "key.json",
+					},
+				},
+			},
+		}, nil
+	case CertManagerSolverHttp01:
+		ingress := map[string]interface{}{}
+		if issuer.Solver.IngressClass != "" {
+			ingress["ingressClassName"] = issuer.Solver.IngressClass
+		}
+		return map[string]interface{}{
+			"http01": map[string]interface{}{
+				"ingress": ingress,
+			},
+		}, nil
+	default:
+		return nil, errorx.IllegalArgument.New("unknown cert-manager solver type for issuer %s: '%s'", issuer.Name, issuer.Solver.Type)
+	}
+}
+
+// creates a solver credentials secret in the cert-manager namespace from a
+// pulumi config secret
+func newCertManagerSecret(ctx *pulumi.Context, bootstrap *BootstrapContext, resourceName string, secretName string, key string, secretKey string, opts ...pulumi.ResourceOption) (*corev1.Secret, error) {
+	if secretKey == "" {
+		return nil, errorx.IllegalArgument.New("cert-manager secret %s requires the name of a pulumi config secret", secretName)
+	}
+	return corev1.NewSecret(ctx, bootstrap.ResourceName(resourceName), &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(secretName),
+			Namespace: pulumi.String("cert-manager"),
+		},
+		StringData: pulumi.StringMap{
+			key: bootstrap.Config.RequireSecret(secretKey),
+		},
+		Type: pulumi.String("Opaque"),
+	}, opts...)
+}
+
+// creates the cert-manager IRSA role, allowed to change the given hosted zones
+func newCertManagerRoute53Role(ctx *pulumi.Context, bootstrap *BootstrapContext, clusterName string, hostedZoneIds []string, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	if clusterName == "" {
+		return pulumi.StringOutput{}, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create cert-manager IRSA role")
+	}
+	policy, err := iampolicy.CertManagerRoute53Policy(hostedZoneIds).Json()
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	role, err := eks.NewIrsaRole(ctx, bootstrap.ResourceName("cert-manager-role"), eks.IrsaRoleInput{
+		Name:           fmt.Sprintf("CertManagerRole-%s", clusterName),
+		EKSClusterName: clusterName,
+		Namespace:      "cert-manager",
+		ServiceAccount: "cert-manager",
+		InlinePolicy:   policy,
+		AwsProvider:    bootstrap.Cluster.AwsProvider,
+	}, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return role.Arn, nil
+}
@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+type ClusterAutoscalerConfigInput struct {
+	// optional, installs the cluster-autoscaler
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+
+	// the autoscaler IRSA role
+	eks.ClusterAutoscalerInput
+
+	// optional, defaults to the aws:region config
+	Region string `json:"region"`
+	// optional autoscaler image tag, defaults to the patch release .0 of the
+	// cluster's kubernetes version, i.e. v1.24.0
+	ImageTag string `json:"image-tag"`
+}
+
+// creates the cluster-autoscaler IRSA role, then installs the autoscaler,
+// discovering the auto scaling groups tagged for the cluster
+func deployClusterAutoscaler(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	autoscalerConfig := bootstrap.K8sConfig.ClusterAutoscaler
+	if !autoscalerConfig.Enabled {
+		return nil, nil
+	}
+	region := autoscalerConfig.Region
+	if region == "" {
+		region = config.New(ctx, "aws").Require("region")
+	}
+	namespace := "kube-system"
+	if autoscalerConfig.Namespace != "" {
+		namespace = autoscalerConfig.Namespace
+	}
+	serviceAccount := "cluster-autoscaler"
+	if autoscalerConfig.ServiceAccount != "" {
+		serviceAccount = autoscalerConfig.ServiceAccount
+	}
+
+	eksConfig := autoscalerConfig.ClusterAutoscalerInput
+	if eksConfig.ResourcePrefix == "" {
+		eksConfig.ResourcePrefix = bootstrap.Cluster.Name
+	}
+	output, err := eks.SyncClusterAutoscaler(ctx, eksConfig, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// the autoscaler must match the kubernetes minor version of the cluster
+	imageTag := fmt.Sprintf("v%s.0", output.KubernetesVersion)
+	if autoscalerConfig.ImageTag != "" {
+		imageTag = autoscalerConfig.ImageTag
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("cluster-autoscaler"),
+		Name:                "cluster-autoscaler",
+		Namespace:           namespace,
+		SkipCreateNamespace: namespace == "kube-system",
+		Repo:                "https://kubernetes.github.io/autoscaler",
+		Version:             "9.29.3",
+		Values: pulumi.Map{
+			"cloudProvider": pulumi.String("aws"),
+			"awsRegion":     pulumi.String(region),
+			"autoDiscovery": pulumi.Map{
+				"clusterName": pulumi.String(autoscalerConfig.EKSClusterName),
+			},
+			"image": pulumi.Map{
+				"tag": pulumi.String(imageTag),
+			},
+			"rbac": pulumi.Map{
+				"serviceAccount": pulumi.Map{
+					"name": pulumi.String(serviceAccount),
+					"annotations": pulumi.Map{
+						"eks.amazonaws.com/role-arn": output.RoleArn,
+					},
+				},
+			},
+			"extraArgs": pulumi.Map{
+				"balance-similar-node-groups":   pulumi.Bool(true),
+				"skip-nodes-with-system-pods":   pulumi.Bool(false),
+				"skip-nodes-with-local-storage": pulumi.Bool(false),
+			},
+		},
+		Config:       autoscalerConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
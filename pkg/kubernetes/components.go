@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"strings"
+)
+
+// BootstrapComponent is a unit of the cluster bootstrap, i.e. a helm release or a secret. Components are deployed in
+// dependency order by BootstrapCluster.
+type BootstrapComponent interface {
+	// Name uniquely identifies the component, and is used to enable, disable, and order it from config
+	Name() string
+	// DependsOn lists the names of the components that must be deployed first
+	DependsOn() []string
+	// Deploy deploys the component. opts carry the kubernetes provider and the component's dependencies. A nil
+	// resource may be returned when the component has nothing to deploy, and components that deploy more than one
+	// resource group them under a BootstrapComponentResource, so that their dependents wait for all of them.
+	Deploy(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error)
+}
+
+// BootstrapContext carries the configuration shared by the components of one cluster's bootstrap
+type BootstrapContext struct {
+	Config    *config.Config
+	K8sConfig K8sPlatformConfigInput
+	Cluster   ClusterBootstrapConfig
+
+	// kubeconfig of the cluster if known, used by commands run against it
+	kubeconfig pulumi.StringOutput
+}
+
+// ResourceName prefixes a pulumi resource name with the cluster name, see utils.PrefixedName
+func (b *BootstrapContext) ResourceName(name string) string {
+	return utils.PrefixedName(b.Cluster.Name, name)
+}
+
+type BootstrapComponentConfigInput struct {
+	// optional, defaults to true
+	Enabled *bool `json:"enabled"`
+
+	// optional, replaces the component's default dependencies
+	DependsOn []string `json:"depends-on"`
+}
+
+// BootstrapComponentFunc deploys a bootstrap component, see BootstrapComponent.Deploy
+type BootstrapComponentFunc func(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error)
+
+type bootstrapComponent struct {
+	name      string
+	dependsOn []string
+	deploy    BootstrapComponentFunc
+}
+
+func (c bootstrapComponent) Name() string {
+	return c.name
+}
+
+func (c bootstrapComponent) DependsOn() []string {
+	return c.dependsOn
+}
+
+func (c bootstrapComponent) Deploy(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	return c.deploy(ctx, bootstrap, opts...)
+}
+
+// NewBootstrapComponent creates a bootstrap component from a deploy function
+func NewBootstrapComponent(name string, dependsOn []string, deploy BootstrapComponentFunc) BootstrapComponent {
+	return bootstrapComponent{
+		name:      name,
+		dependsOn: dependsOn,
+		deploy:    deploy,
+	}
+}
+
+// registered components, in registration order
+var bootstrapComponents = append([]BootstrapComponent{}, builtinBootstrapComponents...)
+
+// RegisterBootstrapComponent adds a component to every cluster bootstrap, replacing any registered component with the
+// same name. Components should be registered before BootstrapCluster is called.
+func RegisterBootstrapComponent(component BootstrapComponent) {
+	bootstrapComponents = mergeBootstrapComponents(bootstrapComponents, component)
+}
+
+// returns components with the additional components appended, additional
+// components replace existing components of the same name in place
+func mergeBootstrapComponents(components []BootstrapComponent, additional ...BootstrapComponent) []BootstrapComponent {
+	merged := append([]BootstrapComponent{}, components...)
+	for _, component := range additional {
+		replaced := false
+		for i, existing := range merged {
+			if existing.Name() == component.Name() {
+				merged[i] = component
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, component)
+		}
+	}
+	return merged
+}
+
+// deploys the enabled components in dependency order
+func deployBootstrapComponents(ctx *pulumi.Context, bootstrap *BootstrapContext, components []BootstrapComponent, opts ...pulumi.ResourceOption) error {
+	ordered, dependencies, err := orderBootstrapComponents(components, bootstrap.K8sConfig.Components)
+	if err != nil {
+		return err
+	}
+
+	// resources that dependents of each component depend on. components that
+	// deploy nothing pass their own dependencies through, so ordering is kept
+	resources := map[string][]pulumi.Resource{}
+	for _, component := range ordered {
+		var dependsOn []pulumi.Resource
+		for _, dependency := range dependencies[component.Name()] {
+			dependsOn = append(dependsOn, resources[dependency]...)
+		}
+
+		componentOpts := append([]pulumi.ResourceOption{}, opts...)
+		if len(dependsOn) != 0 {
+			componentOpts = append(componentOpts, pulumi.DependsOn(dependsOn))
+		}
+		log := logger(ctx, bootstrap.ResourceName(component.Name()))
+		log.Debugf("deploying bootstrap component after %v", dependencies[component.Name()])
+		resource, err := component.Deploy(ctx, bootstrap, componentOpts...)
+		err = log.Wrap("error deploying bootstrap component", err)
+		if err != nil {
+			return err
+		}
+
+		if resource != nil {
+			resources[component.Name()] = []pulumi.Resource{resource}
+		} else {
+			log.Debugf("bootstrap component deployed no resources")
+			resources[component.Name()] = dependsOn
+		}
+	}
+	return nil
+}
+
+// returns whether the named component is not disabled in the components
+// config
+func bootstrapComponentEnabled(k8sConfig K8sPlatformConfigInput, name string) bool {
+	componentConfig, ok := k8sConfig.Components[name]
+	return !ok || componentConfig.Enabled == nil || *componentConfig.Enabled
+}
+
+// filters out disabled components and sorts the rest so that every component
+// comes after its dependencies, keeping registration order otherwise.
+// dependencies on disabled components are dropped
+func orderBootstrapComponents(components []BootstrapComponent, componentConfigs map[string]BootstrapComponentConfigInput) ([]BootstrapComponent, map[string][]string, error) {
+	registered := map[string]bool{}
+	for _, component := range components {
+		registered[component.Name()] = true
+	}
+	for name := range componentConfigs {
+		if !registered[name] {
+			return nil, nil, errorx.IllegalArgument.New("unknown bootstrap component in config: %s", name)
+		}
+	}
+
+	var enabled []BootstrapComponent
+	enabledNames := map[string]bool{}
+	for _, component := range components {
+		componentConfig := componentConfigs[component.Name()]
+		if componentConfig.Enabled == nil || *componentConfig.Enabled {
+			enabled = append(enabled, component)
+			enabledNames[component.Name()] = true
+		}
+	}
+
+	dependencies := map[string][]string{}
+	for _, component := range enabled {
+		dependsOn := component.DependsOn()
+		if componentConfig, ok := componentConfigs[component.Name()]; ok && componentConfig.DependsOn != nil {
+			dependsOn = componentConfig.DependsOn
+		}
+		for _, dependency := range dependsOn {
+			if !registered[dependency] {
+				return nil, nil, errorx.IllegalArgument.New("bootstrap component %s depends on unknown component %s", component.Name(), dependency)
+			}
+			if enabledNames[dependency] {
+				dependencies[component.Name()] = append(dependencies[component.Name()], dependency)
+			}
+		}
+	}
+
+	var ordered []BootstrapComponent
+	deployed := map[string]bool{}
+	for len(ordered) < len(enabled) {
+		progressed := false
+		for _, component := range enabled {
+			if deployed[component.Name()] {
+				continue
+			}
+			ready := true
+			for _, dependency := range dependencies[component.Name()] {
+				if !deployed[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, component)
+				deployed[component.Name()] = true
+				progressed = true
+				break
+			}
+		}
+		if !progressed {
+			var remaining []string
+			for _, component := range enabled {
+				if !deployed[component.Name()] {
+					remaining = append(remaining, component.Name())
+				}
+			}
+			return nil, nil, errorx.IllegalArgument.New("bootstrap components have circular dependencies: %s", strings.Join(remaining, ", "))
+		}
+	}
+	return ordered, dependencies, nil
+}
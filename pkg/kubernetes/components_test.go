@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// returns components without deploy functions, as ordering does not deploy
+func testBootstrapComponents(dependencies map[string][]string, names ...string) []BootstrapComponent {
+	var components []BootstrapComponent
+	for _, name := range names {
+		components = append(components, NewBootstrapComponent(name, dependencies[name], nil))
+	}
+	return components
+}
+
+func componentNames(components []BootstrapComponent) []string {
+	var names []string
+	for _, component := range components {
+		names = append(names, component.Name())
+	}
+	return names
+}
+
+func TestOrderBootstrapComponents(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name         string
+		components   []BootstrapComponent
+		configs      map[string]BootstrapComponentConfigInput
+		ordered      []string
+		dependencies map[string][]string
+		err          string
+	}{
+		{
+			name:         "registration order",
+			components:   testBootstrapComponents(nil, "a", "b", "c"),
+			ordered:      []string{"a", "b", "c"},
+			dependencies: map[string][]string{},
+		},
+		{
+			name:         "dependencies first",
+			components:   testBootstrapComponents(map[string][]string{"a": {"c"}, "b": {"a"}}, "a", "b", "c"),
+			ordered:      []string{"c", "a", "b"},
+			dependencies: map[string][]string{"a": {"c"}, "b": {"a"}},
+		},
+		{
+			name:         "disabled dependency is dropped",
+			components:   testBootstrapComponents(map[string][]string{"b": {"a"}}, "a", "b"),
+			configs:      map[string]BootstrapComponentConfigInput{"a": {Enabled: &disabled}},
+			ordered:      []string{"b"},
+			dependencies: map[string][]string{},
+		},
+		{
+			name:         "config replaces dependencies",
+			components:   testBootstrapComponents(map[string][]string{"b": {"a"}}, "a", "b", "c"),
+			configs:      map[string]BootstrapComponentConfigInput{"a": {DependsOn: []string{"c"}}, "b": {DependsOn: []string{}}},
+			ordered:      []string{"b", "c", "a"},
+			dependencies: map[string][]string{"a": {"c"}},
+		},
+		{
+			name:       "cycle",
+			components: testBootstrapComponents(map[string][]string{"a": {"c"}, "b": {"a"}, "c": {"b"}}, "a", "b", "c", "d"),
+			err:        "bootstrap components have circular dependencies: a, b, c",
+		},
+		{
+			name:       "cycle from config",
+			components: testBootstrapComponents(map[string][]string{"b": {"a"}}, "a", "b"),
+			configs:    map[string]BootstrapComponentConfigInput{"a": {DependsOn: []string{"b"}}},
+			err:        "bootstrap components have circular dependencies: a, b",
+		},
+		{
+			name:       "self dependency",
+			components: testBootstrapComponents(map[string][]string{"a": {"a"}}, "a"),
+			err:        "bootstrap components have circular dependencies: a",
+		},
+		{
+			name:       "unknown dependency",
+			components: testBootstrapComponents(map[string][]string{"a": {"x"}}, "a"),
+			err:        "bootstrap component a depends on unknown component x",
+		},
+		{
+			name:       "unknown component in config",
+			components: testBootstrapComponents(nil, "a"),
+			configs:    map[string]BootstrapComponentConfigInput{"x": {Enabled: &disabled}},
+			err:        "unknown bootstrap component in config: x",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ordered, dependencies, err := orderBootstrapComponents(test.components, test.configs)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := componentNames(ordered); !reflect.DeepEqual(names, test.ordered) {
+				t.Errorf("expected order %v, got %v", test.ordered, names)
+			}
+			if !reflect.DeepEqual(dependencies, test.dependencies) {
+				t.Errorf("expected dependencies %v, got %v", test.dependencies, dependencies)
+			}
+		})
+	}
+}
+
+func TestBuiltinBootstrapComponentsAreOrdered(t *testing.T) {
+	_, _, err := orderBootstrapComponents(builtinBootstrapComponents, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeBootstrapComponents(t *testing.T) {
+	components := testBootstrapComponents(nil, "a", "b")
+	replacement := NewBootstrapComponent("a", []string{"b"}, nil)
+	merged := mergeBootstrapComponents(components, replacement, NewBootstrapComponent("c", nil, nil))
+
+	if names := componentNames(merged); !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("expected replaced components to keep their position, got %v", names)
+	}
+	if !reflect.DeepEqual(merged[0].DependsOn(), []string{"b"}) {
+		t.Errorf("expected component a to be replaced, got %v", merged[0].DependsOn())
+	}
+	if len(components[0].DependsOn()) != 0 {
+		t.Error("merging modified the merged components")
+	}
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	moduleconfig "github.com/catalystcommunity/pulumi-modules-go/pkg/config"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/cost"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type OpencostConfigInput struct {
+	// optional, installs opencost, which allocates the cluster's costs to
+	// namespaces and workloads from prometheus metrics
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+	// optional url of the prometheus that opencost queries, defaults to the
+	// prometheus of kube-prometheus-stack
+	PrometheusUrl string `json:"prometheus-url"`
+}
+
+// creates aws budgets of the cluster, requires an additional configuration
+// object if enabled
+func deployCostBudgets(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.ManageCostBudgets {
+		return nil, nil
+	}
+
+	var budgetsConfig cost.BudgetsInput
+	if bootstrap.Cluster.CostBudgets != nil {
+		budgetsConfig = *bootstrap.Cluster.CostBudgets
+	} else {
+		err := moduleconfig.GetObject(bootstrap.Config, "cost-budgets", &budgetsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if budgetsConfig.ResourcePrefix == "" {
+		budgetsConfig.ResourcePrefix = bootstrap.Cluster.Name
+	}
+
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("cost-budgets"), opts...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cost.SyncBudgets(ctx, budgetsConfig, resourceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Done(ctx)
+}
+
+// installs opencost, querying the prometheus of kube-prometheus-stack unless
+// another is configured
+func deployOpencost(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	opencostConfig := bootstrap.K8sConfig.Opencost
+	if !opencostConfig.Enabled {
+		return nil, nil
+	}
+
+	prometheus := pulumi.Map{
+		"internal": pulumi.Map{
+			"enabled":       pulumi.Bool(true),
+			"serviceName":   pulumi.String("kube-prometheus-stack-prometheus"),
+			"namespaceName": pulumi.String(kubePrometheusStackNamespace),
+			"port":          pulumi.Int(9090),
+		},
+	}
+	if opencostConfig.PrometheusUrl != "" {
+		prometheus = pulumi.Map{
+			"internal": pulumi.Map{
+				"enabled": pulumi.Bool(false),
+			},
+			"external": pulumi.Map{
+				"enabled": pulumi.Bool(true),
+				"url":     pulumi.String(opencostConfig.PrometheusUrl),
+			},
+		}
+	} else if !bootstrapComponentEnabled(bootstrap.K8sConfig, "kube-prometheus-stack") {
+		return nil, errorx.IllegalArgument.New("opencost requires kube-prometheus-stack or a prometheus url")
+	}
+
+	values := pulumi.Map{
+		"opencost": pulumi.Map{
+			"prometheus": prometheus,
+		},
+	}
+	if bootstrap.Cluster.Name != "" {
+		setHelmValue(values, []string{"opencost", "exporter", "defaultClusterId"}, pulumi.String(bootstrap.Cluster.Name))
+	}
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("opencost"),
+		Name:         "opencost",
+		Repo:         "https://opencost.github.io/opencost-helm-chart",
+		Version:      "1.18.1",
+		Values:       values,
+		Config:       opencostConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
@@ -0,0 +1,162 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ExternalDnsConfigInput struct {
+	// optional, installs external-dns
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+
+	// one of "route53" (default) or "cloudflare"
+	Provider string `json:"provider" validate:"oneof=route53|cloudflare"`
+	// optional domains that external-dns manages records of
+	DomainFilters []string `json:"domain-filters"`
+	// optional, identifies the records owned by this cluster, defaults to the
+	// stack name
+	TxtOwnerId string `json:"txt-owner-id"`
+
+	// route53, cluster whose OIDC provider is trusted by the IRSA role, and
+	// the hosted zones that the role may change
+	EKSClusterName string   `json:"eks-cluster-name"`
+	HostedZoneIds  []string `json:"hosted-zone-ids"`
+
+	// cloudflare, optional name of the pulumi config secret holding the api
+	// token, defaults to cloudflareApiToken
+	CloudflareApiTokenSecretKey string `json:"cloudflare-api-token-secret-key"`
+}
+
+// external-dns providers
+const (
+	ExternalDnsProviderRoute53    = "route53"
+	ExternalDnsProviderCloudflare = "cloudflare"
+)
+
+// installs external-dns, authenticated to route53 with an IRSA role scoped to
+// the configured hosted zones, or to cloudflare with an api token
+func deployExternalDns(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	externalDnsConfig := bootstrap.K8sConfig.ExternalDns
+	if !externalDnsConfig.Enabled {
+		return nil, nil
+	}
+	txtOwnerId := ctx.Stack()
+	if externalDnsConfig.TxtOwnerId != "" {
+		txtOwnerId = externalDnsConfig.TxtOwnerId
+	}
+
+	values := pulumi.Map{
+		"txtOwnerId":    pulumi.String(txtOwnerId),
+		"domainFilters": pulumi.ToStringArray(externalDnsConfig.DomainFilters),
+		"policy":        pulumi.String("sync"),
+	}
+	spec := HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("external-dns"),
+		Name:         "external-dns",
+		Repo:         "https://kubernetes-sigs.github.io/external-dns",
+		Version:      "1.14.3",
+		Config:       externalDnsConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}
+	releaseOpts := append([]pulumi.ResourceOption{}, opts...)
+
+	switch externalDnsConfig.Provider {
+	case "", ExternalDnsProviderRoute53:
+		if len(externalDnsConfig.HostedZoneIds) == 0 {
+			return nil, errorx.IllegalArgument.New("external-dns with route53 requires hosted zone ids")
+		}
+		role, err := newExternalDnsRoute53Role(ctx, bootstrap, externalDnsConfig, opts...)
+		if err != nil {
+			return nil, err
+		}
+		var zoneIdFilters []string
+		for _, hostedZoneId := range externalDnsConfig.HostedZoneIds {
+			zoneIdFilters = append(zoneIdFilters, "--zone-id-filter="+hostedZoneId)
+		}
+		values["provider"] = pulumi.Map{
+			"name": pulumi.String("aws"),
+		}
+		values["extraArgs"] = pulumi.ToStringArray(zoneIdFilters)
+		values["serviceAccount"] = pulumi.Map{
+			"annotations": pulumi.Map{
+				"eks.amazonaws.com/role-arn": role.Arn,
+			},
+		}
+	case ExternalDnsProviderCloudflare:
+		secretKey := "cloudflareApiToken"
+		if externalDnsConfig.CloudflareApiTokenSecretKey != "" {
+			secretKey = externalDnsConfig.CloudflareApiTokenSecretKey
+		}
+		// the token secret must exist before the release, so the namespace
+		// is created here instead of by helm
+		namespace, err := corev1.NewNamespace(ctx, bootstrap.ResourceName("external-dns-namespace"), &corev1.NamespaceArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Name: pulumi.String("external-dns"),
+			},
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := corev1.NewSecret(ctx, bootstrap.ResourceName("external-dns-cloudflare-api-token-secret"), &corev1.SecretArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Name:      pulumi.String("cloudflare-api-token"),
+				Namespace: namespace.Metadata.Name().Elem(),
+			},
+			StringData: pulumi.StringMap{
+				"api-token": bootstrap.Config.RequireSecret(secretKey),
+			},
+			Type: pulumi.String("Opaque"),
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		spec.SkipCreateNamespace = true
+		releaseOpts = append(releaseOpts, pulumi.DependsOn([]pulumi.Resource{secret}))
+		values["provider"] = pulumi.Map{
+			"name": pulumi.String("cloudflare"),
+		}
+		values["env"] = pulumi.Array{
+			pulumi.Map{
+				"name": pulumi.String("CF_API_TOKEN"),
+				"valueFrom": pulumi.Map{
+					"secretKeyRef": pulumi.Map{
+						"name": pulumi.String("cloudflare-api-token"),
+						"key":  pulumi.String("api-token"),
+					},
+				},
+			},
+		}
+	default:
+		return nil, errorx.IllegalArgument.New("unknown external-dns provider: '%s'", externalDnsConfig.Provider)
+	}
+
+	spec.Values = values
+	return DeployHelmRelease(ctx, spec, releaseOpts...)
+}
+
+// creates the external-dns IRSA role, allowed to change the given hosted zones
+func newExternalDnsRoute53Role(ctx *pulumi.Context, bootstrap *BootstrapContext, externalDnsConfig ExternalDnsConfigInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if externalDnsConfig.EKSClusterName == "" {
+		return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create external-dns IRSA role")
+	}
+	policy, err := iampolicy.ExternalDnsRoute53Policy(externalDnsConfig.HostedZoneIds).Json()
+	if err != nil {
+		return nil, err
+	}
+
+	return eks.NewIrsaRole(ctx, bootstrap.ResourceName("external-dns-role"), eks.IrsaRoleInput{
+		Name:           fmt.Sprintf("ExternalDnsRole-%s", externalDnsConfig.EKSClusterName),
+		EKSClusterName: externalDnsConfig.EKSClusterName,
+		Namespace:      "external-dns",
+		ServiceAccount: "external-dns",
+		InlinePolicy:   policy,
+		AwsProvider:    bootstrap.Cluster.AwsProvider,
+	}, opts...)
+}
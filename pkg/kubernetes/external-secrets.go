@@ -0,0 +1,160 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+type ExternalSecretsConfigInput struct {
+	// optional, installs external-secrets
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+
+	// cluster whose OIDC provider is trusted by the controller IRSA role
+	EKSClusterName string `json:"eks-cluster-name"`
+	// optional, region of the secrets, defaults to the aws:region config
+	Region string `json:"region"`
+
+	// optional name prefixes of the Secrets Manager secrets and SSM parameters
+	// that the controller may read. a ClusterSecretStore is created for each
+	// service with prefixes
+	SecretsManagerPrefixes []string `json:"secrets-manager-prefixes"`
+	ParameterStorePrefixes []string `json:"parameter-store-prefixes"`
+}
+
+// ClusterSecretStore names, referenced by ExternalSecrets
+const (
+	ExternalSecretsSecretsManagerStore = "aws-secrets-manager"
+	ExternalSecretsParameterStoreStore = "aws-parameter-store"
+)
+
+// installs external-secrets with an IRSA role scoped to the configured
+// prefixes, and creates a ClusterSecretStore per configured service
+func deployExternalSecrets(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	externalSecretsConfig := bootstrap.K8sConfig.ExternalSecrets
+	if !externalSecretsConfig.Enabled {
+		return nil, nil
+	}
+	if externalSecretsConfig.EKSClusterName == "" {
+		return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create external-secrets IRSA role")
+	}
+	region := externalSecretsConfig.Region
+	if region == "" {
+		region = config.New(ctx, "aws").Require("region")
+	}
+
+	policy, err := externalSecretsPolicy(externalSecretsConfig)
+	if err != nil {
+		return nil, err
+	}
+	role, err := eks.NewIrsaRole(ctx, bootstrap.ResourceName("external-secrets-role"), eks.IrsaRoleInput{
+		Name:           fmt.Sprintf("ExternalSecretsRole-%s", externalSecretsConfig.EKSClusterName),
+		EKSClusterName: externalSecretsConfig.EKSClusterName,
+		Namespace:      "external-secrets",
+		ServiceAccount: "external-secrets",
+		InlinePolicy:   policy,
+		AwsProvider:    bootstrap.Cluster.AwsProvider,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("external-secrets"),
+		Name:         "external-secrets",
+		Repo:         "https://charts.external-secrets.io",
+		Version:      "0.9.11",
+		Values: pulumi.Map{
+			"installCRDs": pulumi.Bool(true),
+			"serviceAccount": pulumi.Map{
+				"name": pulumi.String("external-secrets"),
+				"annotations": pulumi.Map{
+					"eks.amazonaws.com/role-arn": role.Arn,
+				},
+			},
+		},
+		Config:       externalSecretsConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stores need the external-secrets CRDs
+	storeOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{release}))
+	stores := map[string][]string{
+		ExternalSecretsSecretsManagerStore: externalSecretsConfig.SecretsManagerPrefixes,
+		ExternalSecretsParameterStoreStore: externalSecretsConfig.ParameterStorePrefixes,
+	}
+	services := map[string]string{
+		ExternalSecretsSecretsManagerStore: "SecretsManager",
+		ExternalSecretsParameterStoreStore: "ParameterStore",
+	}
+	for _, name := range []string{ExternalSecretsSecretsManagerStore, ExternalSecretsParameterStoreStore} {
+		if len(stores[name]) == 0 {
+			continue
+		}
+		manifest, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "external-secrets.io/v1beta1",
+			"kind":       "ClusterSecretStore",
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"provider": map[string]interface{}{
+					"aws": map[string]interface{}{
+						"service": services[name],
+						"region":  region,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = SyncKubernetesManifest(ctx, bootstrap.ResourceName(name+"-cluster-secret-store"), manifest, storeOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// read only access to the secrets and parameters under the configured prefixes
+func externalSecretsPolicy(externalSecretsConfig ExternalSecretsConfigInput) (string, error) {
+	var statements []iampolicy.Statement
+	if len(externalSecretsConfig.SecretsManagerPrefixes) != 0 {
+		var resources []string
+		for _, prefix := range externalSecretsConfig.SecretsManagerPrefixes {
+			resources = append(resources, fmt.Sprintf("arn:*:secretsmanager:*:*:secret:%s*", prefix))
+		}
+		statements = append(statements, iampolicy.Allow(
+			"secretsmanager:GetResourcePolicy",
+			"secretsmanager:GetSecretValue",
+			"secretsmanager:DescribeSecret",
+			"secretsmanager:ListSecretVersionIds",
+		).On(resources...))
+	}
+	if len(externalSecretsConfig.ParameterStorePrefixes) != 0 {
+		var resources []string
+		for _, prefix := range externalSecretsConfig.ParameterStorePrefixes {
+			resources = append(resources, fmt.Sprintf("arn:*:ssm:*:*:parameter/%s*", strings.TrimPrefix(prefix, "/")))
+		}
+		statements = append(statements, iampolicy.Allow(
+			"ssm:GetParameter",
+			"ssm:GetParameters",
+			"ssm:GetParametersByPath",
+		).On(resources...))
+	}
+	if len(statements) == 0 {
+		return "", errorx.IllegalArgument.New("external-secrets requires secrets manager or parameter store prefixes")
+	}
+
+	return iampolicy.NewDocument(statements...).Json()
+}
@@ -0,0 +1,249 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/joomcode/errorx"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+type GitopsConfigInput struct {
+	// optional, one of "argocd" (default) or "flux". with flux, argo-cd is
+	// not installed and the platform application is rendered as a flux
+	// HelmRelease
+	Provider string          `json:"provider" validate:"oneof=argocd|flux"`
+	Flux     FluxConfigInput `json:"flux"`
+}
+
+type FluxConfigInput struct {
+	Helm HelmReleaseConfigInput `json:"helm-release"`
+	// optional git repositories whose manifests flux applies with a
+	// kustomization
+	GitRepositories []FluxGitRepositoryConfigInput `json:"git-repositories"`
+}
+
+type FluxGitRepositoryConfigInput struct {
+	Name string `json:"name" validate:"required"`
+	Url  string `json:"url" validate:"required"`
+	// optional, defaults to main
+	Branch string `json:"branch"`
+	// optional path of the manifests in the repository, defaults to the root
+	Path string `json:"path"`
+	// optional reconcile interval, defaults to 5m
+	Interval string `json:"interval"`
+	// optional, deletes resources removed from the repository
+	Prune bool `json:"prune"`
+	// optional name of an existing secret in flux-system holding the
+	// repository credentials
+	SecretName string `json:"secret-name"`
+}
+
+// gitops providers
+const (
+	GitopsProviderArgocd = "argocd"
+	GitopsProviderFlux   = "flux"
+)
+
+// the namespace that flux and its custom resources are installed in
+const fluxNamespace = "flux-system"
+
+// returns whether the cluster is managed by flux instead of argo-cd
+func fluxEnabled(k8sConfig K8sPlatformConfigInput) bool {
+	return k8sConfig.Gitops.Provider == GitopsProviderFlux
+}
+
+// installs flux and syncs the configured git repositories
+func deployFlux(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !fluxEnabled(bootstrap.K8sConfig) {
+		return nil, nil
+	}
+	fluxConfig := bootstrap.K8sConfig.Gitops.Flux
+
+	release, err := DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("flux2"),
+		Name:         "flux2",
+		Namespace:    fluxNamespace,
+		Repo:         "https://fluxcd-community.github.io/helm-charts",
+		Version:      "2.7.0",
+		Config:       fluxConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the custom resources need the flux CRDs
+	crdOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{release}))
+	for _, repository := range fluxConfig.GitRepositories {
+		manifest, err := fluxGitRepositoryManifest(repository)
+		if err != nil {
+			return nil, err
+		}
+		_, err = SyncKubernetesManifests(ctx, bootstrap.ResourceName("flux-git-repository-"+repository.Name), manifest, crdOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// renders a GitRepository and the Kustomization that applies its manifests
+func fluxGitRepositoryManifest(repository FluxGitRepositoryConfigInput) ([]byte, error) {
+	if repository.Name == "" || repository.Url == "" {
+		return nil, errorx.IllegalArgument.New("flux git repositories require a name and url")
+	}
+	interval := stringOrDefault(repository.Interval, "5m")
+
+	gitRepositorySpec := map[string]interface{}{
+		"interval": interval,
+		"url":      repository.Url,
+		"ref": map[string]interface{}{
+			"branch": stringOrDefault(repository.Branch, "main"),
+		},
+	}
+	if repository.SecretName != "" {
+		gitRepositorySpec["secretRef"] = map[string]interface{}{
+			"name": repository.SecretName,
+		}
+	}
+	return marshalManifests(
+		map[string]interface{}{
+			"apiVersion": "source.toolkit.fluxcd.io/v1beta2",
+			"kind":       "GitRepository",
+			"metadata": map[string]interface{}{
+				"name":      repository.Name,
+				"namespace": fluxNamespace,
+			},
+			"spec": gitRepositorySpec,
+		},
+		map[string]interface{}{
+			"apiVersion": "kustomize.toolkit.fluxcd.io/v1beta2",
+			"kind":       "Kustomization",
+			"metadata": map[string]interface{}{
+				"name":      repository.Name,
+				"namespace": fluxNamespace,
+			},
+			"spec": map[string]interface{}{
+				"interval": interval,
+				"path":     stringOrDefault(repository.Path, "./"),
+				"prune":    repository.Prune,
+				"sourceRef": map[string]interface{}{
+					"kind": "GitRepository",
+					"name": repository.Name,
+				},
+			},
+		},
+	)
+}
+
+// renders the platform application as a flux HelmRepository and HelmRelease
+// of the same chart, version, and values. the values are passed in a secret
+// because they may contain secret placeholders
+func deployFluxPlatformApplication(ctx *pulumi.Context, bootstrap *BootstrapContext, application ArgocdApplication, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	source := application.Spec.Source
+	if source.Chart == "" {
+		return nil, errorx.IllegalArgument.New("the platform application must be a helm chart to be deployed with flux")
+	}
+	name := stringOrDefault(source.Helm.ReleaseName, source.Chart)
+
+	// the values stay secret without placeholders too
+	values := pulumi.ToSecret(pulumi.String(source.Helm.Values)).(pulumi.StringOutput)
+	resolver, err := secretResolverFor(ctx, source.Helm.Values)
+	if err != nil {
+		return nil, err
+	}
+	if resolver != nil {
+		values = resolver.ReplaceOutput(source.Helm.Values)
+	}
+
+	valuesSecret, err := corev1.NewSecret(ctx, bootstrap.ResourceName("flux-platform-application-values"), &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(fmt.Sprintf("%s-values", name)),
+			Namespace: pulumi.String(fluxNamespace),
+		},
+		StringData: pulumi.StringMap{
+			"values.yaml": values,
+		},
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := marshalManifests(
+		map[string]interface{}{
+			"apiVersion": "source.toolkit.fluxcd.io/v1beta2",
+			"kind":       "HelmRepository",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": fluxNamespace,
+			},
+			"spec": map[string]interface{}{
+				"interval": "10m",
+				"url":      source.RepoUrl,
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "helm.toolkit.fluxcd.io/v2beta1",
+			"kind":       "HelmRelease",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": fluxNamespace,
+			},
+			"spec": map[string]interface{}{
+				"interval":        "10m",
+				"releaseName":     name,
+				"targetNamespace": application.Spec.Destination.Namespace,
+				"install": map[string]interface{}{
+					"createNamespace": true,
+				},
+				"chart": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"chart":   source.Chart,
+						"version": source.TargetRevision,
+						"sourceRef": map[string]interface{}{
+							"kind": "HelmRepository",
+							"name": name,
+						},
+					},
+				},
+				"valuesFrom": []interface{}{
+					map[string]interface{}{
+						"kind":      "Secret",
+						"name":      fmt.Sprintf("%s-values", name),
+						"valuesKey": "values.yaml",
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := SyncKubernetesManifest(ctx, bootstrap.ResourceName("flux-platform-application"), manifest, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{valuesSecret}))...)
+	if err != nil || !bootstrap.K8sConfig.HealthGates.Enabled {
+		return resource, err
+	}
+
+	// wait again whenever the release or its values change
+	trigger := pulumi.String(fmt.Sprintf("%x", sha256.Sum256(append(manifest, source.Helm.Values...))))
+	return deployHealthGate(ctx, bootstrap, fmt.Sprintf("%s-health-gate", name), trigger, []healthGateWait{
+		{fmt.Sprintf("helmrelease/%s", name), fluxNamespace, "condition=Ready"},
+	}, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{resource}))...)
+}
+
+// marshals objects into a multi-document yaml manifest
+func marshalManifests(objects ...map[string]interface{}) ([]byte, error) {
+	var manifest []byte
+	for _, object := range objects {
+		document, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, []byte("---\n")...)
+		manifest = append(manifest, document...)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,296 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/joomcode/errorx"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+)
+
+type GrafanaConfigInput struct {
+	// optional dashboards provisioned into the grafana of
+	// kube-prometheus-stack
+	Dashboards []GrafanaDashboardConfigInput `json:"dashboards"`
+	// optional datasources added next to the prometheus datasource of
+	// kube-prometheus-stack
+	Datasources []GrafanaDatasourceConfigInput `json:"datasources"`
+}
+
+type GrafanaDashboardConfigInput struct {
+	// name of the dashboard, unique across dashboards, which names its
+	// configmap and must be a valid kubernetes name
+	Name string `json:"name" validate:"required"`
+	// optional grafana folder of the dashboard, defaults to the general folder
+	Folder string `json:"folder"`
+	// one of the embedded dashboard json, the path of a dashboard json file,
+	// or a url that grafana downloads the dashboard from on startup
+	Json string `json:"json"`
+	File string `json:"file"`
+	Url  string `json:"url"`
+}
+
+type GrafanaDatasourceConfigInput struct {
+	Name string `json:"name" validate:"required"`
+	Type string `json:"type" validate:"required,oneof=prometheus|loki|tempo|cloudwatch"`
+	// optional uid, so that dashboards can reference the datasource
+	Uid string `json:"uid"`
+	// required unless the type is cloudwatch, or tempo when the bootstrap
+	// installs tempo
+	Url string `json:"url"`
+	// optional additional jsonData of the datasource, i.e. the
+	// tracesToLogs settings of tempo
+	JsonData map[string]interface{} `json:"json-data"`
+
+	// default region of cloudwatch datasources
+	Region string `json:"region"`
+	// optional, creates an IRSA role for the grafana service account that
+	// may read cloudwatch metrics and logs
+	ManageIrsaRole bool   `json:"manage-irsa-role"`
+	EKSClusterName string `json:"eks-cluster-name"`
+}
+
+// the service account of the grafana of the kube-prometheus-stack release,
+// and the labels and annotation its sidecars watch
+const (
+	kubePrometheusStackGrafanaAccount = "kube-prometheus-stack-grafana"
+	grafanaDashboardLabel             = "grafana_dashboard"
+	grafanaDatasourceLabel            = "grafana_datasource"
+	grafanaFolderAnnotation           = "grafana_folder"
+	grafanaUrlDashboardProvider       = "bootstrap-url-dashboards"
+)
+
+// renders the sidecar, url dashboard, and cloudwatch IRSA settings of the
+// grafana of kube-prometheus-stack, creating the IRSA role if configured
+func grafanaValues(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Map, error) {
+	grafanaConfig := bootstrap.K8sConfig.Grafana
+	grafana := pulumi.Map{}
+
+	if len(grafanaConfig.Dashboards) != 0 {
+		// configmap dashboards are placed in the folder named by their
+		// annotation
+		setHelmValue(grafana, []string{"sidecar", "dashboards", "folderAnnotation"}, pulumi.String(grafanaFolderAnnotation))
+		setHelmValue(grafana, []string{"sidecar", "dashboards", "provider", "foldersFromFilesStructure"}, pulumi.Bool(true))
+	}
+
+	// grafana downloads url dashboards on startup, one provider per folder
+	urlDashboards := map[string]pulumi.Map{}
+	for _, dashboard := range grafanaConfig.Dashboards {
+		if dashboard.Url == "" {
+			continue
+		}
+		if urlDashboards[dashboard.Folder] == nil {
+			urlDashboards[dashboard.Folder] = pulumi.Map{}
+		}
+		urlDashboards[dashboard.Folder][dashboard.Name] = pulumi.Map{
+			"url": pulumi.String(dashboard.Url),
+		}
+	}
+	if len(urlDashboards) != 0 {
+		var folders []string
+		for folder := range urlDashboards {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+		var providers pulumi.Array
+		dashboards := pulumi.Map{}
+		for i, folder := range folders {
+			provider := fmt.Sprintf("%s-%d", grafanaUrlDashboardProvider, i)
+			providers = append(providers, pulumi.Map{
+				"name":            pulumi.String(provider),
+				"folder":          pulumi.String(folder),
+				"type":            pulumi.String("file"),
+				"disableDeletion": pulumi.Bool(false),
+				"options": pulumi.Map{
+					"path": pulumi.String(fmt.Sprintf("/var/lib/grafana/dashboards/%s", provider)),
+				},
+			})
+			dashboards[provider] = urlDashboards[folder]
+		}
+		grafana["dashboardProviders"] = pulumi.Map{
+			"dashboardproviders.yaml": pulumi.Map{
+				"apiVersion": pulumi.Int(1),
+				"providers":  providers,
+			},
+		}
+		grafana["dashboards"] = dashboards
+	}
+
+	if len(grafanaConfig.Datasources) != 0 {
+		datasources, err := grafanaDatasources(bootstrap)
+		if err != nil {
+			return nil, err
+		}
+		// the datasources sidecar only runs when grafana starts, roll the pods
+		// when the provisioned datasources change
+		setHelmValue(grafana, []string{"podAnnotations", "checksum/bootstrap-datasources"}, pulumi.String(fmt.Sprintf("%x", sha256.Sum256([]byte(datasources)))))
+	}
+
+	var manageIrsaRole bool
+	var eksClusterName string
+	for _, datasource := range grafanaConfig.Datasources {
+		if datasource.Type == "cloudwatch" && datasource.ManageIrsaRole {
+			manageIrsaRole = true
+			eksClusterName = datasource.EKSClusterName
+		}
+	}
+	if manageIrsaRole {
+		if eksClusterName == "" {
+			return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create grafana cloudwatch IRSA role")
+		}
+		role, err := eks.NewIrsaRole(ctx, bootstrap.ResourceName("grafana-cloudwatch-role"), eks.IrsaRoleInput{
+			Name:           fmt.Sprintf("GrafanaCloudWatchRole-%s", eksClusterName),
+			EKSClusterName: eksClusterName,
+			Namespace:      kubePrometheusStackNamespace,
+			ServiceAccount: kubePrometheusStackGrafanaAccount,
+			PolicyArns:     []string{"arn:aws:iam::aws:policy/CloudWatchReadOnlyAccess"},
+			AwsProvider:    bootstrap.Cluster.AwsProvider,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		setHelmValue(grafana, []string{"serviceAccount", "annotations", "eks.amazonaws.com/role-arn"}, role.Arn)
+	}
+
+	if len(grafana) == 0 {
+		return nil, nil
+	}
+	return grafana, nil
+}
+
+// renders the provisioning file of the configured datasources
+func grafanaDatasources(bootstrap *BootstrapContext) (string, error) {
+	var datasources []map[string]interface{}
+	for _, datasourceConfig := range bootstrap.K8sConfig.Grafana.Datasources {
+		datasource := map[string]interface{}{
+			"name":   datasourceConfig.Name,
+			"type":   datasourceConfig.Type,
+			"access": "proxy",
+		}
+		if datasourceConfig.Uid != "" {
+			datasource["uid"] = datasourceConfig.Uid
+		}
+		jsonData := map[string]interface{}{}
+		for key, value := range datasourceConfig.JsonData {
+			jsonData[key] = value
+		}
+
+		url := datasourceConfig.Url
+		switch datasourceConfig.Type {
+		case "cloudwatch":
+			// authenticates with the IRSA role of the grafana service account
+			jsonData["authType"] = "default"
+			if datasourceConfig.Region != "" {
+				jsonData["defaultRegion"] = datasourceConfig.Region
+			}
+		case "tempo":
+			tracingConfig := bootstrap.K8sConfig.Tracing
+			if url == "" && tracingConfig.Enabled && tracingConfig.Backend != TracingBackendJaeger {
+				url = "http://tempo.tempo.svc.cluster.local:3100"
+			}
+		}
+		if url == "" && datasourceConfig.Type != "cloudwatch" {
+			return "", errorx.IllegalArgument.New("url of grafana datasource %s not supplied", datasourceConfig.Name)
+		}
+		if url != "" {
+			datasource["url"] = url
+		}
+		if len(jsonData) != 0 {
+			datasource["jsonData"] = jsonData
+		}
+		datasources = append(datasources, datasource)
+	}
+
+	bytes, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":  1,
+		"datasources": datasources,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// creates the dashboard configmaps and the datasources secret that the
+// sidecars of the kube-prometheus-stack grafana load
+func deployGrafanaProvisioning(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	grafanaConfig := bootstrap.K8sConfig.Grafana
+	if len(grafanaConfig.Dashboards) == 0 && len(grafanaConfig.Datasources) == 0 {
+		return nil, nil
+	}
+	if !bootstrapComponentEnabled(bootstrap.K8sConfig, "kube-prometheus-stack") {
+		return nil, errorx.IllegalArgument.New("grafana dashboards and datasources require kube-prometheus-stack")
+	}
+
+	for _, dashboard := range grafanaConfig.Dashboards {
+		dashboardJson, err := grafanaDashboardJson(dashboard)
+		if err != nil {
+			return nil, err
+		}
+		if dashboardJson == "" {
+			// url dashboards are downloaded by grafana, see grafanaValues
+			continue
+		}
+		annotations := pulumi.StringMap{}
+		if dashboard.Folder != "" {
+			annotations[grafanaFolderAnnotation] = pulumi.String(dashboard.Folder)
+		}
+		_, err = corev1.NewConfigMap(ctx, bootstrap.ResourceName(fmt.Sprintf("grafana-dashboard-%s", dashboard.Name)), &corev1.ConfigMapArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Namespace:   pulumi.String(kubePrometheusStackNamespace),
+				Labels:      pulumi.StringMap{grafanaDashboardLabel: pulumi.String("1")},
+				Annotations: annotations,
+			},
+			Data: pulumi.StringMap{
+				fmt.Sprintf("%s.json", dashboard.Name): pulumi.String(dashboardJson),
+			},
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(grafanaConfig.Datasources) == 0 {
+		return nil, nil
+	}
+	datasources, err := grafanaDatasources(bootstrap)
+	if err != nil {
+		return nil, err
+	}
+	return corev1.NewSecret(ctx, bootstrap.ResourceName("grafana-datasources"), &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String("bootstrap-grafana-datasources"),
+			Namespace: pulumi.String(kubePrometheusStackNamespace),
+			Labels:    pulumi.StringMap{grafanaDatasourceLabel: pulumi.String("1")},
+		},
+		StringData: pulumi.StringMap{
+			"bootstrap-datasources.yaml": pulumi.String(datasources),
+		},
+	}, opts...)
+}
+
+// returns the embedded or file json of a dashboard, empty for url dashboards
+func grafanaDashboardJson(dashboard GrafanaDashboardConfigInput) (string, error) {
+	sources := 0
+	for _, source := range []string{dashboard.Json, dashboard.File, dashboard.Url} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return "", errorx.IllegalArgument.New("grafana dashboard %s must set exactly one of json, file, or url", dashboard.Name)
+	}
+	if dashboard.File != "" {
+		bytes, err := os.ReadFile(dashboard.File)
+		if err != nil {
+			return "", errorx.Decorate(err, "reading grafana dashboard %s", dashboard.Name)
+		}
+		return string(bytes), nil
+	}
+	return dashboard.Json, nil
+}
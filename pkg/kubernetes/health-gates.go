@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type HealthGatesConfigInput struct {
+	// optional, waits for the argo-cd deployments to become available and
+	// for the platform application to become healthy and synced, or its
+	// flux HelmRelease ready, so that the update fails while the platform is
+	// broken. kubectl 1.23 or newer must be on the path
+	Enabled bool `json:"enabled"`
+	// optional timeout of each gate in seconds, defaults to 600
+	Timeout int `json:"timeout"`
+}
+
+// a kubectl wait of a health gate
+type healthGateWait struct {
+	resource  string
+	namespace string
+	condition string
+}
+
+// creates a command that waits for the given conditions with kubectl wait.
+// the command is replaced, and so run again, whenever the trigger changes,
+// i.e. with the revision of the release it gates. nil is returned when the
+// health gates are disabled
+func deployHealthGate(ctx *pulumi.Context, bootstrap *BootstrapContext, name string, trigger pulumi.StringInput, waits []healthGateWait, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	gatesConfig := bootstrap.K8sConfig.HealthGates
+	if !gatesConfig.Enabled {
+		return nil, nil
+	}
+	timeout := 600
+	if gatesConfig.Timeout != 0 {
+		timeout = gatesConfig.Timeout
+	}
+
+	var commands []string
+	for _, wait := range waits {
+		commands = append(commands, fmt.Sprintf("kubectl wait %s --namespace %s --for=%s --timeout=%ds", wait.resource, wait.namespace, wait.condition, timeout))
+	}
+	log := logger(ctx, bootstrap.ResourceName(name))
+	log.Debugf("waiting for %d conditions with a timeout of %ds", len(waits), timeout)
+	command, err := newKubectlCommand(ctx, bootstrap, name, commands, trigger, opts...)
+	err = log.Wrap("error creating health gate", err)
+	return command, err
+}
+
+// waits for the deployments of the argo-cd release to become available
+func deployArgocdHealthGate(ctx *pulumi.Context, bootstrap *BootstrapContext, release pulumi.Resource, revision pulumi.StringInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	return deployHealthGate(ctx, bootstrap, "argo-cd-health-gate", revision, []healthGateWait{
+		{"deployment --all", "argo-cd", "condition=Available"},
+	}, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{release}))...)
+}
+
+// waits for the argo-cd application of the given name and namespace to
+// become healthy and synced
+func deployApplicationHealthGate(ctx *pulumi.Context, bootstrap *BootstrapContext, name string, namespace string, resource pulumi.Resource, trigger pulumi.StringInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	application := fmt.Sprintf("application/%s", name)
+	return deployHealthGate(ctx, bootstrap, fmt.Sprintf("%s-health-gate", name), trigger, []healthGateWait{
+		{application, namespace, "jsonpath={.status.health.status}=Healthy"},
+		{application, namespace, "jsonpath={.status.sync.status}=Synced"},
+	}, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{resource}))...)
+}
@@ -0,0 +1,185 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// latestHelmChartVersion resolves to the newest stable version of a chart
+const latestHelmChartVersion = "latest"
+
+// resolves a version range of a release from its chart repository, if the
+// release allows floating versions, and exports the resolved version as the
+// <resource name>-chart-version stack output so that the deployed version
+// stays visible. warns when a newer
+// version than the deployed one is available if update checks are enabled.
+// private repositories are fetched without credentials
+func helmReleaseVersion(ctx *pulumi.Context, resourceName string, spec HelmReleaseSpec, defaultChart string, version string) (string, error) {
+	if !isHelmChartVersionRange(version) && (!spec.Config.CheckUpdates || version == "") {
+		return version, nil
+	}
+	repo := spec.Repo
+	if spec.Config.Repo != "" {
+		repo = spec.Config.Repo
+	}
+	chart := defaultChart
+	if spec.Config.Chart != "" {
+		chart = spec.Config.Chart
+	}
+	log := logger(ctx, resourceName)
+
+	if isHelmChartVersionRange(version) {
+		if !spec.Config.AllowFloatingVersion {
+			return "", floatingHelmChartVersionError(chart, version)
+		}
+		resolved, err := resolveHelmChartVersion(repo, chart, version)
+		if err != nil {
+			return "", err
+		}
+		log.Infof("resolved chart %s version %s to %s", chart, version, resolved)
+		ctx.Export(resourceName+"-chart-version", pulumi.String(resolved))
+		version = resolved
+	}
+
+	if spec.Config.CheckUpdates {
+		newer, err := newerHelmChartVersion(repo, chart, version)
+		if err != nil {
+			log.Warnf("error checking chart %s for updates: %v", chart, err)
+		} else if newer != "" {
+			log.Warnf("chart %s version %s is behind the latest version %s", chart, version, newer)
+		}
+	}
+	return version, nil
+}
+
+// returns whether the version must be resolved from the chart repository,
+// that is "latest" or a ~ or ^ range
+func isHelmChartVersionRange(version string) bool {
+	return version == latestHelmChartVersion || strings.HasPrefix(version, "~") || strings.HasPrefix(version, "^")
+}
+
+// the error of a version range of a release that does not allow floating
+// versions
+func floatingHelmChartVersionError(chart string, version string) error {
+	return fmt.Errorf("chart %s version %s is resolved again on every run and upgrades the release when the chart is released, pin an exact version or set allow-floating-version", chart, version)
+}
+
+// returns the newest stable version of the chart that satisfies the version
+// range: "latest" is any version, "~1.2" is 1.2.x, and "^1.2" is 1.x.x from
+// 1.2.0. exact versions are returned as is
+func resolveHelmChartVersion(repo string, chart string, versionRange string) (string, error) {
+	if !isHelmChartVersionRange(versionRange) {
+		return versionRange, nil
+	}
+	inRange, err := helmChartVersionRange(versionRange)
+	if err != nil {
+		return "", err
+	}
+	versions, err := fetchHelmChartVersions(repo, chart)
+	if err != nil {
+		return "", err
+	}
+
+	var resolved *semver.Version
+	var resolvedName string
+	for _, name := range versions {
+		version, err := semver.ParseTolerant(name)
+		if err != nil || len(version.Pre) != 0 || !inRange(version) {
+			continue
+		}
+		if resolved == nil || version.GT(*resolved) {
+			resolved = &version
+			resolvedName = name
+		}
+	}
+	if resolved == nil {
+		return "", fmt.Errorf("no version of chart %s in %s matches %s", chart, repo, versionRange)
+	}
+	return resolvedName, nil
+}
+
+// returns the newest stable version of the chart that is newer than the
+// given version, or an empty string when the given version is the newest
+func newerHelmChartVersion(repo string, chart string, current string) (string, error) {
+	currentVersion, err := semver.ParseTolerant(current)
+	if err != nil {
+		return "", fmt.Errorf("chart %s version %s is not a semantic version", chart, current)
+	}
+	latest, err := resolveHelmChartVersion(repo, chart, latestHelmChartVersion)
+	if err != nil {
+		return "", err
+	}
+	latestVersion, err := semver.ParseTolerant(latest)
+	if err != nil || !latestVersion.GT(currentVersion) {
+		return "", nil
+	}
+	return latest, nil
+}
+
+// parses a version range into a function that matches the versions in it
+func helmChartVersionRange(versionRange string) (func(semver.Version) bool, error) {
+	if versionRange == latestHelmChartVersion {
+		return func(semver.Version) bool { return true }, nil
+	}
+	operator := versionRange[:1]
+	parts := strings.Split(strings.TrimPrefix(versionRange[1:], "v"), ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid chart version range %s", versionRange)
+	}
+	// a partial version is completed with zeros, i.e. ~1.2 is ~1.2.0
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	lower, err := semver.Parse(strings.Join(parts, "."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid chart version range %s: %v", versionRange, err)
+	}
+	return func(version semver.Version) bool {
+		if version.LT(lower) || version.Major != lower.Major {
+			return false
+		}
+		// ~ keeps the minor version, ^ only the major version
+		return operator == "^" || version.Minor == lower.Minor
+	}, nil
+}
+
+// returns the versions of a chart in the index of its helm repository
+func fetchHelmChartVersions(repo string, chart string) ([]string, error) {
+	if strings.HasPrefix(repo, "oci://") {
+		return nil, fmt.Errorf("chart versions of oci registry %s cannot be listed, pin an exact version", repo)
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	response, err := client.Get(strings.TrimSuffix(repo, "/") + "/index.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the chart index of %s: %v", repo, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching the chart index of %s: %s", repo, response.Status)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the chart index of %s: %v", repo, err)
+	}
+
+	var index struct {
+		Entries map[string][]struct {
+			Version string `yaml:"version"`
+		} `yaml:"entries"`
+	}
+	err = yaml.Unmarshal(body, &index)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the chart index of %s: %v", repo, err)
+	}
+	var versions []string
+	for _, entry := range index.Entries[chart] {
+		versions = append(versions, entry.Version)
+	}
+	return versions, nil
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"github.com/blang/semver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHelmChartVersionRange(t *testing.T) {
+	tests := []struct {
+		versionRange string
+		matches      []string
+		excludes     []string
+	}{
+		{"latest", []string{"0.1.0", "1.0.0", "33.1.2"}, nil},
+		{"~33.1", []string{"33.1.0", "33.1.9"}, []string{"33.0.9", "33.2.0", "34.1.0"}},
+		{"~33.1.2", []string{"33.1.2", "33.1.9"}, []string{"33.1.1", "33.2.0"}},
+		{"~v1", []string{"1.0.0", "1.0.5"}, []string{"1.1.0", "0.9.0"}},
+		{"^33.1", []string{"33.1.0", "33.9.0"}, []string{"33.0.9", "34.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.3.0"}, []string{"1.2.2", "2.0.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.versionRange, func(t *testing.T) {
+			inRange, err := helmChartVersionRange(test.versionRange)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range test.matches {
+				if !inRange(semver.MustParse(version)) {
+					t.Errorf("expected %s to match %s", version, test.versionRange)
+				}
+			}
+			for _, version := range test.excludes {
+				if inRange(semver.MustParse(version)) {
+					t.Errorf("expected %s not to match %s", version, test.versionRange)
+				}
+			}
+		})
+	}
+}
+
+func TestHelmChartVersionRangeInvalid(t *testing.T) {
+	for _, versionRange := range []string{"~1.2.3.4", "^a.b"} {
+		if _, err := helmChartVersionRange(versionRange); err == nil {
+			t.Errorf("expected %s to be invalid", versionRange)
+		}
+	}
+}
+
+const testChartIndex = `apiVersion: v1
+entries:
+  argo-cd:
+  - version: 5.20.0
+  - version: 5.19.3
+  - version: 5.19.12
+  - version: 5.21.0-rc.1
+  - version: 4.10.9
+  other:
+  - version: 9.0.0
+`
+
+func TestResolveHelmChartVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testChartIndex))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		versionRange string
+		resolved     string
+		err          string
+	}{
+		// prereleases are never resolved
+		{versionRange: "latest", resolved: "5.20.0"},
+		// versions compare semantically, not as strings
+		{versionRange: "~5.19", resolved: "5.19.12"},
+		{versionRange: "^4.1", resolved: "4.10.9"},
+		// exact versions are returned without fetching the index
+		{versionRange: "5.19.3", resolved: "5.19.3"},
+		{versionRange: "~6", err: "no version of chart argo-cd"},
+	}
+	for _, test := range tests {
+		t.Run(test.versionRange, func(t *testing.T) {
+			resolved, err := resolveHelmChartVersion(server.URL+"/", "argo-cd", test.versionRange)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolved != test.resolved {
+				t.Errorf("expected %s to resolve to %s, got %s", test.versionRange, test.resolved, resolved)
+			}
+		})
+	}
+}
+
+func TestResolveHelmChartVersionOci(t *testing.T) {
+	_, err := resolveHelmChartVersion("oci://123456789012.dkr.ecr.us-east-1.amazonaws.com/charts", "argo-cd", "latest")
+	if err == nil {
+		t.Fatal("expected version ranges of oci registries to fail")
+	}
+}
+
+func TestNewerHelmChartVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testChartIndex))
+	}))
+	defer server.Close()
+
+	newer, err := newerHelmChartVersion(server.URL, "argo-cd", "5.19.12")
+	if err != nil || newer != "5.20.0" {
+		t.Errorf("expected newer version 5.20.0, got %q, %v", newer, err)
+	}
+	newer, err = newerHelmChartVersion(server.URL, "argo-cd", "5.20.0")
+	if err != nil || newer != "" {
+		t.Errorf("expected no newer version, got %q, %v", newer, err)
+	}
+}
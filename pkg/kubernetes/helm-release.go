@@ -0,0 +1,187 @@
+package kubernetes
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ecr"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/helm/v3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"regexp"
+	"strings"
+)
+
+// HelmReleaseSpec describes a helm release deployed by DeployHelmRelease. The module defaults set here are overridden
+// by the user's HelmReleaseConfigInput.
+type HelmReleaseSpec struct {
+	// optional pulumi resource name, defaults to Name
+	ResourceName string
+	// helm release name
+	Name string
+	// optional, defaults to Name
+	Namespace string
+	// optional, does not create the namespace, i.e. when it is managed elsewhere
+	SkipCreateNamespace bool
+
+	// default chart repository, chart name, and chart version. Chart defaults
+	// to Name
+	Repo    string
+	Chart   string
+	Version string
+	// optional, used when the release config has no values files
+	DefaultValuesFiles []string
+	// optional module generated values, the release config's inline and
+	// secret values are merged over these
+	Values pulumi.Map
+
+	// user config of the release
+	Config HelmReleaseConfigInput
+	// optional, config to read secrets from, defaults to the project config
+	PulumiConfig *config.Config
+}
+
+// DeployHelmRelease deploys a helm release with the conventions of the bootstrap releases: module defaults overridden
+// by user config, values files as assets, inline and secret values, authenticated and oci chart repositories, and
+// helm's timeout, atomic, cleanup-on-fail, and skip-await flags.
+func DeployHelmRelease(ctx *pulumi.Context, spec HelmReleaseSpec, opts ...pulumi.ResourceOption) (*helm.Release, error) {
+	if spec.Name == "" {
+		return nil, errorx.IllegalArgument.New("helm release name not supplied")
+	}
+	resourceName := spec.Name
+	if spec.ResourceName != "" {
+		resourceName = spec.ResourceName
+	}
+	namespace := spec.Name
+	if spec.Namespace != "" {
+		namespace = spec.Namespace
+	}
+	defaultChart := spec.Name
+	if spec.Chart != "" {
+		defaultChart = spec.Chart
+	}
+	cfg := spec.PulumiConfig
+	if cfg == nil {
+		cfg = config.New(ctx, "")
+	}
+	values := spec.Values
+	if values == nil {
+		values = pulumi.Map{}
+	}
+
+	// set default helm chart version and values files if not defined
+	version := spec.Version
+	if spec.Config.Version != "" {
+		version = spec.Config.Version
+	}
+	valuesFiles := spec.DefaultValuesFiles
+	if len(spec.Config.ValuesFiles) != 0 {
+		valuesFiles = spec.Config.ValuesFiles
+	}
+
+	chart, repositoryOpts, err := helmChart(ctx, cfg, spec.Config, spec.Repo, defaultChart)
+	if err != nil {
+		return nil, err
+	}
+	version, err = helmReleaseVersion(ctx, resourceName, spec, defaultChart, version)
+	if err != nil {
+		return nil, err
+	}
+	helmValues, err := HelmValues(ctx, cfg, spec.Config, values)
+	if err != nil {
+		return nil, err
+	}
+
+	args := &helm.ReleaseArgs{
+		Chart:           chart,
+		Name:            pulumi.String(spec.Name),
+		Namespace:       pulumi.String(namespace),
+		CreateNamespace: pulumi.Bool(!spec.SkipCreateNamespace),
+		RepositoryOpts:  repositoryOpts,
+		ValueYamlFiles:  stringArrayToAssetOrArchiveArrayOutput(valuesFiles),
+		Values:          helmValues,
+		Atomic:          pulumi.Bool(spec.Config.Atomic),
+		CleanupOnFail:   pulumi.Bool(spec.Config.CleanupOnFail),
+		SkipAwait:       pulumi.Bool(spec.Config.SkipAwait),
+	}
+	if version != "" {
+		args.Version = pulumi.String(version)
+	}
+	if spec.Config.Timeout != 0 {
+		args.Timeout = pulumi.Int(spec.Config.Timeout)
+	}
+	// the ecr token changes on every run, which would otherwise make every
+	// run a diff of the release. note that pulumi applies the changes of
+	// other inputs with the password of the state, the token of the last
+	// create, which expires after 12 hours
+	if spec.Config.RepoEcrAuth {
+		opts = append(append([]pulumi.ResourceOption{}, opts...), pulumi.IgnoreChanges([]string{"repositoryOpts.password"}))
+	}
+	log := logger(ctx, resourceName)
+	log.Debugf("deploying helm release %s of chart %s version %s to namespace %s", spec.Name, defaultChart, version, namespace)
+	release, err := helm.NewRelease(ctx, resourceName, args, opts...)
+	err = log.Wrap("error deploying helm release", err)
+	return release, err
+}
+
+// matches the account id of an ecr registry host, i.e. 123456789012.dkr.ecr.us-east-1.amazonaws.com
+var ecrRegistryPattern = regexp.MustCompile(`^(?:oci://)?(\d{12})\.dkr\.ecr\.[a-z0-9-]+\.amazonaws\.com`)
+
+// returns the chart and repository options of a release, applying the
+// configured repo and chart overrides and credentials. oci charts are
+// referenced by their full url, with the credentials used to log in to the
+// registry
+func helmChart(ctx *pulumi.Context, cfg *config.Config, releaseConfig HelmReleaseConfigInput, defaultRepo string, defaultChart string) (pulumi.String, helm.RepositoryOptsArgs, error) {
+	repo := defaultRepo
+	if releaseConfig.Repo != "" {
+		repo = releaseConfig.Repo
+	}
+	chart := defaultChart
+	if releaseConfig.Chart != "" {
+		chart = releaseConfig.Chart
+	}
+
+	repositoryOpts, err := helmRepositoryAuth(ctx, cfg, releaseConfig, repo)
+	if err != nil {
+		return "", repositoryOpts, err
+	}
+
+	if strings.HasPrefix(repo, "oci://") {
+		return pulumi.String(strings.TrimSuffix(repo, "/") + "/" + chart), repositoryOpts, nil
+	}
+	repositoryOpts.Repo = pulumi.String(repo)
+	return pulumi.String(chart), repositoryOpts, nil
+}
+
+// returns repository options holding the configured credentials, either read
+// from pulumi config secrets or exchanged for an ecr authorization token
+func helmRepositoryAuth(ctx *pulumi.Context, cfg *config.Config, releaseConfig HelmReleaseConfigInput, repo string) (helm.RepositoryOptsArgs, error) {
+	repositoryOpts := helm.RepositoryOptsArgs{}
+	if releaseConfig.RepoEcrAuth {
+		match := ecrRegistryPattern.FindStringSubmatch(repo)
+		if match == nil {
+			return repositoryOpts, errorx.IllegalArgument.New("ecr auth requires an ecr registry repo, got: '%s'", repo)
+		}
+		token := ecr.GetAuthorizationTokenOutput(ctx, ecr.GetAuthorizationTokenOutputArgs{
+			RegistryId: pulumi.String(match[1]),
+		})
+		repositoryOpts.Username = token.UserName()
+		repositoryOpts.Password = pulumi.ToSecret(token.Password()).(pulumi.StringOutput)
+		return repositoryOpts, nil
+	}
+
+	if (releaseConfig.RepoUsernameSecretKey == "") != (releaseConfig.RepoPasswordSecretKey == "") {
+		return repositoryOpts, errorx.IllegalArgument.New("repo username and password must be configured together for repo '%s'", repo)
+	}
+	if releaseConfig.RepoUsernameSecretKey != "" {
+		repositoryOpts.Username = cfg.RequireSecret(releaseConfig.RepoUsernameSecretKey)
+		repositoryOpts.Password = cfg.RequireSecret(releaseConfig.RepoPasswordSecretKey)
+	}
+	return repositoryOpts, nil
+}
+
+func stringArrayToAssetOrArchiveArrayOutput(in []string) pulumi.AssetOrArchiveArrayOutput {
+	var o pulumi.AssetOrArchiveArray
+	for _, i := range in {
+		o = append(o, pulumi.NewFileAsset(i))
+	}
+	return o.ToAssetOrArchiveArrayOutput()
+}
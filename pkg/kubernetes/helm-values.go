@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"strings"
+)
+
+// HelmValues merges a release's inline values and secret values over the given module generated values. Secret values
+// are keyed by a dotted values path, i.e. `server.config.token`, and reference pulumi config secrets by name so that
+// they stay secret. Inline string values may also contain <<mySecretValue>> placeholders, which are replaced from the
+// configured secret provider as secret outputs. Helm applies these values over the release's values files.
+func HelmValues(ctx *pulumi.Context, cfg *config.Config, releaseConfig HelmReleaseConfigInput, values pulumi.Map) (pulumi.Map, error) {
+	resolver, err := secretResolverFor(ctx, releaseConfig.Values)
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeHelmValues(values, toHelmValues(resolver, releaseConfig.Values))
+	for path, secretKey := range releaseConfig.SecretValues {
+		setHelmValue(merged, strings.Split(path, "."), cfg.RequireSecret(secretKey))
+	}
+	return merged, nil
+}
+
+// converts plain values to pulumi values, keeping nested maps as pulumi maps
+// so that they can be merged, and replacing secret placeholders in strings
+// when a resolver is given
+func toHelmValues(resolver *secrets.SecretResolver, in map[string]interface{}) pulumi.Map {
+	values := pulumi.Map{}
+	for key, value := range in {
+		if nested, ok := value.(map[string]interface{}); ok {
+			values[key] = toHelmValues(resolver, nested)
+		} else if str, ok := value.(string); ok && resolver != nil && secretPlaceholderPattern.MatchString(str) {
+			values[key] = resolver.ReplaceOutput(str)
+		} else {
+			values[key] = pulumi.Any(value)
+		}
+	}
+	return values
+}
+
+// deep merges override into base, returning a new map
+func mergeHelmValues(base pulumi.Map, override pulumi.Map) pulumi.Map {
+	merged := pulumi.Map{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseMap, baseIsMap := merged[key].(pulumi.Map)
+		overrideMap, overrideIsMap := value.(pulumi.Map)
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeHelmValues(baseMap, overrideMap)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// sets a value at the given path, creating or replacing intermediate maps
+func setHelmValue(values pulumi.Map, path []string, value pulumi.Input) {
+	if len(path) == 1 {
+		values[path[0]] = value
+		return
+	}
+	nested, ok := values[path[0]].(pulumi.Map)
+	if !ok {
+		nested = pulumi.Map{}
+	} else {
+		nested = mergeHelmValues(nested, pulumi.Map{})
+	}
+	setHelmValue(nested, path[1:], value)
+	values[path[0]] = nested
+}
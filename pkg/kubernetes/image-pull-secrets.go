@@ -0,0 +1,146 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/ecr"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"sort"
+	"strings"
+)
+
+type ImagePullSecretConfigInput struct {
+	// name of the secret in each namespace
+	Name string `json:"name" validate:"required"`
+	// registry host, i.e. harbor.example.com or
+	// 123456789012.dkr.ecr.us-east-1.amazonaws.com
+	Registry string `json:"registry" validate:"required"`
+	// optional keys of the pulumi config secrets holding the registry
+	// credentials
+	UsernameSecretKey string `json:"username-secret-key"`
+	PasswordSecretKey string `json:"password-secret-key"`
+	// optional, exchanges the aws credentials of the stack for an ecr
+	// authorization token. the token expires after 12 hours and is refreshed
+	// on each update, so this suits short lived clusters or scheduled updates
+	EcrAuth bool `json:"ecr-auth"`
+	// namespaces to create the secret in, they must exist, i.e. created with
+	// the namespaces config
+	Namespaces []string `json:"namespaces" validate:"required"`
+	// optional, sets the secret on the default service account of each
+	// namespace with kubectl, which must be on the path. this replaces the
+	// image pull secrets of the default service accounts with the ones
+	// configured for their namespace
+	PatchDefaultServiceAccount bool `json:"patch-default-service-account"`
+}
+
+// creates the image pull secrets in their namespaces and patches the default
+// service accounts
+func deployImagePullSecrets(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	cfg := config.New(ctx, "")
+	// the secrets set on the default service account of each namespace
+	serviceAccountSecrets := map[string][]string{}
+	secretsByNamespace := map[string][]pulumi.Resource{}
+	for _, secretConfig := range bootstrap.K8sConfig.ImagePullSecrets {
+		dockerConfig, err := imagePullSecretDockerConfig(ctx, cfg, secretConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, namespace := range secretConfig.Namespaces {
+			secret, err := corev1.NewSecret(ctx, bootstrap.ResourceName(fmt.Sprintf("image-pull-secret-%s-%s", namespace, secretConfig.Name)), &corev1.SecretArgs{
+				Metadata: &metav1.ObjectMetaArgs{
+					Name:      pulumi.String(secretConfig.Name),
+					Namespace: pulumi.String(namespace),
+				},
+				Type: pulumi.String("kubernetes.io/dockerconfigjson"),
+				StringData: pulumi.StringMap{
+					".dockerconfigjson": dockerConfig,
+				},
+			}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			if secretConfig.PatchDefaultServiceAccount {
+				serviceAccountSecrets[namespace] = append(serviceAccountSecrets[namespace], secretConfig.Name)
+				secretsByNamespace[namespace] = append(secretsByNamespace[namespace], secret)
+			}
+		}
+	}
+	if len(serviceAccountSecrets) == 0 {
+		return nil, nil
+	}
+
+	// one patch per namespace, so that the secrets of a namespace do not
+	// overwrite each other
+	var namespaces []string
+	for namespace := range serviceAccountSecrets {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	var commands []string
+	var dependencies []pulumi.Resource
+	for _, namespace := range namespaces {
+		var references []map[string]string
+		for _, name := range serviceAccountSecrets[namespace] {
+			references = append(references, map[string]string{"name": name})
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"imagePullSecrets": references,
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, fmt.Sprintf("kubectl patch serviceaccount default --namespace %s --patch '%s'", namespace, patch))
+		dependencies = append(dependencies, secretsByNamespace[namespace]...)
+	}
+
+	// patch again whenever the secrets of the service accounts change
+	command, err := newKubectlCommand(ctx, bootstrap, "image-pull-secrets-default-service-accounts", commands, pulumi.String(strings.Join(commands, "\n")),
+		append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn(dependencies))...)
+	err = logger(ctx, bootstrap.ResourceName("image-pull-secrets")).Wrap("error patching default service accounts", err)
+	return command, err
+}
+
+// returns the .dockerconfigjson of an image pull secret, holding the
+// credentials read from pulumi config secrets or exchanged for an ecr
+// authorization token
+func imagePullSecretDockerConfig(ctx *pulumi.Context, cfg *config.Config, secretConfig ImagePullSecretConfigInput) (pulumi.StringOutput, error) {
+	var username, password pulumi.StringOutput
+	if secretConfig.EcrAuth {
+		match := ecrRegistryPattern.FindStringSubmatch(secretConfig.Registry)
+		if match == nil {
+			return pulumi.StringOutput{}, errorx.IllegalArgument.New("ecr auth requires an ecr registry, got: '%s'", secretConfig.Registry)
+		}
+		token := ecr.GetAuthorizationTokenOutput(ctx, ecr.GetAuthorizationTokenOutputArgs{
+			RegistryId: pulumi.String(match[1]),
+		})
+		username = token.UserName()
+		password = token.Password()
+	} else {
+		if secretConfig.UsernameSecretKey == "" || secretConfig.PasswordSecretKey == "" {
+			return pulumi.StringOutput{}, errorx.IllegalArgument.New("image pull secret %s requires a username and password or ecr auth", secretConfig.Name)
+		}
+		username = cfg.RequireSecret(secretConfig.UsernameSecretKey)
+		password = cfg.RequireSecret(secretConfig.PasswordSecretKey)
+	}
+
+	dockerConfig := pulumi.All(username, password).ApplyT(func(args []interface{}) (string, error) {
+		username := args[0].(string)
+		password := args[1].(string)
+		dockerConfig, err := json.Marshal(map[string]interface{}{
+			"auths": map[string]interface{}{
+				secretConfig.Registry: map[string]string{
+					"username": username,
+					"password": password,
+					"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+				},
+			},
+		})
+		return string(dockerConfig), err
+	}).(pulumi.StringOutput)
+	return pulumi.ToSecret(dockerConfig).(pulumi.StringOutput), nil
+}
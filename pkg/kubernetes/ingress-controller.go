@@ -0,0 +1,195 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/lb"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type IngressControllerConfigInput struct {
+	// optional, installs an ingress controller behind an NLB provisioned by
+	// the aws-load-balancer-controller
+	Enabled bool `json:"enabled"`
+	// one of "ingress-nginx" (default) or "traefik"
+	Controller string                 `json:"controller" validate:"oneof=ingress-nginx|traefik"`
+	Helm       HelmReleaseConfigInput `json:"helm-release"`
+
+	// optional, name of the ingress-nginx ingress class, defaults to nginx.
+	// the traefik ingress class is named traefik
+	IngressClass string `json:"ingress-class"`
+	// optional, makes the ingress class the cluster default
+	DefaultIngressClass bool `json:"default-ingress-class"`
+
+	// optional, provisions an internal NLB instead of an internet facing one
+	Internal bool `json:"internal"`
+	// optional, disables cross-zone load balancing of the NLB
+	DisableCrossZone bool `json:"disable-cross-zone"`
+	// optional, sends the client address to the controller with the proxy
+	// protocol. traefik only trusts the header from the given cidrs, i.e. the
+	// vpc cidr
+	ProxyProtocol             bool     `json:"proxy-protocol"`
+	ProxyProtocolTrustedCidrs []string `json:"proxy-protocol-trusted-cidrs" validate:"cidr"`
+	// optional ACM certificate that the NLB terminates TLS with, https is
+	// forwarded to the controller's http port
+	AcmCertificateArn string `json:"acm-certificate-arn"`
+}
+
+// ingress controllers
+const (
+	IngressControllerNginx   = "ingress-nginx"
+	IngressControllerTraefik = "traefik"
+)
+
+// installs ingress-nginx or traefik with its service exposed through an NLB,
+// and exports the NLB hostname for dns records
+func deployIngressController(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	ingressConfig := bootstrap.K8sConfig.IngressController
+	if !ingressConfig.Enabled {
+		return nil, nil
+	}
+
+	annotations := nlbServiceAnnotations(ingressConfig)
+	var spec HelmReleaseSpec
+	var serviceName, priorityClassPath string
+	switch ingressConfig.Controller {
+	case "", IngressControllerNginx:
+		spec = HelmReleaseSpec{
+			ResourceName: bootstrap.ResourceName("ingress-nginx"),
+			Name:         "ingress-nginx",
+			Repo:         "https://kubernetes.github.io/ingress-nginx",
+			Version:      "4.4.2",
+			Values:       ingressNginxValues(ingressConfig, annotations),
+		}
+		serviceName, priorityClassPath = "ingress-nginx-controller", "controller"
+	case IngressControllerTraefik:
+		if ingressConfig.ProxyProtocol && len(ingressConfig.ProxyProtocolTrustedCidrs) == 0 {
+			return nil, errorx.IllegalArgument.New("traefik requires proxy protocol trusted cidrs")
+		}
+		spec = HelmReleaseSpec{
+			ResourceName: bootstrap.ResourceName("traefik"),
+			Name:         "traefik",
+			Repo:         "https://traefik.github.io/charts",
+			Version:      "20.8.0",
+			Values:       traefikValues(ingressConfig, annotations),
+		}
+		serviceName, priorityClassPath = "traefik", ""
+	default:
+		return nil, errorx.IllegalArgument.New("unknown ingress controller: '%s'", ingressConfig.Controller)
+	}
+	spec.Values = mergeHelmValues(spec.Values, priorityClassValues(bootstrap.K8sConfig, PriorityClassPlatformCritical, priorityClassPath))
+	spec.Config = ingressConfig.Helm
+	spec.PulumiConfig = bootstrap.Config
+	release, err := DeployHelmRelease(ctx, spec, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the aws-load-balancer-controller tags the NLB with the service it
+	// serves. the lookup waits for the release, whose service is ready once
+	// the NLB is provisioned
+	stack := release.Status.Namespace().ApplyT(func(namespace *string) string {
+		if namespace == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s/%s", *namespace, serviceName)
+	}).(pulumi.StringOutput)
+	loadBalancer := lb.LookupLoadBalancerOutput(ctx, lb.LookupLoadBalancerOutputArgs{
+		Tags: pulumi.StringMap{
+			"service.k8s.aws/stack": stack,
+		},
+	})
+	ctx.Export(bootstrap.ResourceName("ingress-load-balancer-hostname"), loadBalancer.DnsName())
+	return release, nil
+}
+
+// annotations that make the aws-load-balancer-controller provision an NLB
+// targeting the controller pods
+func nlbServiceAnnotations(ingressConfig IngressControllerConfigInput) pulumi.Map {
+	scheme := "internet-facing"
+	if ingressConfig.Internal {
+		scheme = "internal"
+	}
+	annotations := pulumi.Map{
+		"service.beta.kubernetes.io/aws-load-balancer-type":            pulumi.String("external"),
+		"service.beta.kubernetes.io/aws-load-balancer-nlb-target-type": pulumi.String("ip"),
+		"service.beta.kubernetes.io/aws-load-balancer-scheme":          pulumi.String(scheme),
+		"service.beta.kubernetes.io/aws-load-balancer-attributes":      pulumi.String(fmt.Sprintf("load_balancing.cross_zone.enabled=%t", !ingressConfig.DisableCrossZone)),
+	}
+	if ingressConfig.ProxyProtocol {
+		annotations["service.beta.kubernetes.io/aws-load-balancer-proxy-protocol"] = pulumi.String("*")
+	}
+	if ingressConfig.AcmCertificateArn != "" {
+		annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-cert"] = pulumi.String(ingressConfig.AcmCertificateArn)
+	}
+	return annotations
+}
+
+func ingressNginxValues(ingressConfig IngressControllerConfigInput, annotations pulumi.Map) pulumi.Map {
+	ingressClass := stringOrDefault(ingressConfig.IngressClass, "nginx")
+	service := pulumi.Map{
+		"annotations": annotations,
+	}
+	if ingressConfig.AcmCertificateArn != "" {
+		annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-ports"] = pulumi.String("https")
+		service["targetPorts"] = pulumi.Map{
+			"https": pulumi.String("http"),
+		}
+	}
+	controller := pulumi.Map{
+		"ingressClass": pulumi.String(ingressClass),
+		"ingressClassResource": pulumi.Map{
+			"name":            pulumi.String(ingressClass),
+			"default":         pulumi.Bool(ingressConfig.DefaultIngressClass),
+			"controllerValue": pulumi.String("k8s.io/" + ingressClass),
+		},
+		"service": service,
+	}
+	if ingressConfig.ProxyProtocol {
+		controller["config"] = pulumi.Map{
+			"use-proxy-protocol": pulumi.String("true"),
+		}
+	}
+	return pulumi.Map{
+		"controller": controller,
+	}
+}
+
+func traefikValues(ingressConfig IngressControllerConfigInput, annotations pulumi.Map) pulumi.Map {
+	web := pulumi.Map{}
+	websecure := pulumi.Map{}
+	if ingressConfig.ProxyProtocol {
+		proxyProtocol := pulumi.Map{
+			"trustedIPs": pulumi.ToStringArray(ingressConfig.ProxyProtocolTrustedCidrs),
+		}
+		web["proxyProtocol"] = proxyProtocol
+		websecure["proxyProtocol"] = proxyProtocol
+	}
+	if ingressConfig.AcmCertificateArn != "" {
+		// the NLB terminates TLS, so the websecure entrypoint receives http
+		annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-ports"] = pulumi.String("websecure")
+		websecure["tls"] = pulumi.Map{
+			"enabled": pulumi.Bool(false),
+		}
+	}
+
+	values := pulumi.Map{
+		"ingressClass": pulumi.Map{
+			"enabled":        pulumi.Bool(true),
+			"isDefaultClass": pulumi.Bool(ingressConfig.DefaultIngressClass),
+		},
+		"service": pulumi.Map{
+			"annotations": annotations,
+		},
+	}
+	ports := pulumi.Map{}
+	for name, port := range map[string]pulumi.Map{"web": web, "websecure": websecure} {
+		if len(port) != 0 {
+			ports[name] = port
+		}
+	}
+	if len(ports) != 0 {
+		values["ports"] = ports
+	}
+	return values
+}
@@ -0,0 +1,288 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type PrometheusRemoteWriteConfigInput struct {
+	// remote write endpoint, i.e. of grafana cloud, mimir, or amazon managed
+	// prometheus
+	Url string `json:"url"`
+	// optional name of the remote write queue
+	Name string `json:"name"`
+
+	// optional, authenticates with the basic auth secret managed by the
+	// bootstrap, see ManagePrometheusRemoteWriteBasicAuthSecret
+	BasicAuth bool `json:"basic-auth"`
+	// optional, signs requests for amazon managed prometheus
+	Sigv4 PrometheusSigv4ConfigInput `json:"sigv4"`
+
+	// optional relabeling of the written series, i.e. to drop high
+	// cardinality metrics
+	WriteRelabelConfigs []PrometheusRelabelConfigInput `json:"write-relabel-configs"`
+	// optional queue tuning
+	QueueConfig PrometheusQueueConfigInput `json:"queue-config"`
+}
+
+type PrometheusSigv4ConfigInput struct {
+	Enabled bool `json:"enabled"`
+	// optional, defaults to the region of the prometheus pod
+	Region string `json:"region"`
+	// optional role to assume before signing
+	RoleArn string `json:"role-arn"`
+
+	// optional, creates an IRSA role for the prometheus service account that
+	// may remote write to amazon managed prometheus
+	ManageIrsaRole bool   `json:"manage-irsa-role"`
+	EKSClusterName string `json:"eks-cluster-name"`
+}
+
+type PrometheusRelabelConfigInput struct {
+	SourceLabels []string `json:"source-labels"`
+	Separator    string   `json:"separator"`
+	TargetLabel  string   `json:"target-label"`
+	Regex        string   `json:"regex"`
+	Replacement  string   `json:"replacement"`
+	// optional, defaults to replace
+	Action string `json:"action"`
+}
+
+type PrometheusQueueConfigInput struct {
+	Capacity          int    `json:"capacity"`
+	MinShards         int    `json:"min-shards"`
+	MaxShards         int    `json:"max-shards"`
+	MaxSamplesPerSend int    `json:"max-samples-per-send"`
+	BatchSendDeadline string `json:"batch-send-deadline"`
+	MinBackoff        string `json:"min-backoff"`
+	MaxBackoff        string `json:"max-backoff"`
+}
+
+type PrometheusOperatorCrdsConfigInput struct {
+	// optional, installs the prometheus-operator CRDs, i.e. ServiceMonitor
+	// and PrometheusRule, on their own
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+}
+
+// the service account and namespace of the prometheus of the
+// kube-prometheus-stack release
+const (
+	kubePrometheusStackNamespace         = "kube-prometheus-stack"
+	kubePrometheusStackPrometheusAccount = "kube-prometheus-stack-prometheus"
+)
+
+// renders the remote write, alertmanager, and grafana configuration of the
+// bootstrap into kube-prometheus-stack values
+func kubePrometheusStackValues(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Map, error) {
+	values := pulumi.Map{}
+
+	prometheus, err := prometheusValues(ctx, bootstrap, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if prometheus != nil {
+		values["prometheus"] = prometheus
+	}
+
+	alertmanagerConfig := bootstrap.K8sConfig.Alertmanager
+	if alertmanagerConfig.Enabled {
+		alertmanager, err := alertmanagerValues(bootstrap.Config, alertmanagerConfig)
+		if err != nil {
+			return nil, err
+		}
+		values["alertmanager"] = pulumi.Map{
+			"config": alertmanager,
+		}
+	}
+
+	if bootstrap.K8sConfig.GrafanaAdminPasswordSecretKey != "" {
+		values["grafana"] = pulumi.Map{
+			"adminPassword": bootstrap.Config.RequireSecret(bootstrap.K8sConfig.GrafanaAdminPasswordSecretKey),
+		}
+	}
+	grafana, err := grafanaValues(ctx, bootstrap, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if grafana != nil {
+		values = mergeHelmValues(values, pulumi.Map{"grafana": grafana})
+	}
+
+	priorityClasses := priorityClassValues(bootstrap.K8sConfig, PriorityClassPlatform, "prometheus.prometheusSpec", "alertmanager.alertmanagerSpec", "prometheusOperator")
+	return mergeHelmValues(values, priorityClasses), nil
+}
+
+// renders the remote write configuration, creating the IRSA role of sigv4
+// remote writes if configured
+func prometheusValues(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Map, error) {
+	k8sConfig := bootstrap.K8sConfig
+	if len(k8sConfig.PrometheusRemoteWrite) == 0 {
+		return nil, nil
+	}
+
+	var remoteWrites pulumi.Array
+	var manageIrsaRole bool
+	var eksClusterName string
+	for _, remoteWriteConfig := range k8sConfig.PrometheusRemoteWrite {
+		remoteWrite, err := prometheusRemoteWriteValues(k8sConfig, remoteWriteConfig)
+		if err != nil {
+			return nil, err
+		}
+		remoteWrites = append(remoteWrites, remoteWrite)
+		if remoteWriteConfig.Sigv4.ManageIrsaRole {
+			manageIrsaRole = true
+			eksClusterName = remoteWriteConfig.Sigv4.EKSClusterName
+		}
+	}
+	prometheus := pulumi.Map{
+		"prometheusSpec": pulumi.Map{
+			"remoteWrite": remoteWrites,
+		},
+	}
+
+	if manageIrsaRole {
+		if eksClusterName == "" {
+			return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create prometheus remote write IRSA role")
+		}
+		role, err := eks.NewIrsaRole(ctx, bootstrap.ResourceName("prometheus-remote-write-role"), eks.IrsaRoleInput{
+			Name:           fmt.Sprintf("PrometheusRemoteWriteRole-%s", eksClusterName),
+			EKSClusterName: eksClusterName,
+			Namespace:      kubePrometheusStackNamespace,
+			ServiceAccount: kubePrometheusStackPrometheusAccount,
+			PolicyArns:     []string{"arn:aws:iam::aws:policy/AmazonPrometheusRemoteWriteAccess"},
+			AwsProvider:    bootstrap.Cluster.AwsProvider,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		prometheus["serviceAccount"] = pulumi.Map{
+			"annotations": pulumi.Map{
+				"eks.amazonaws.com/role-arn": role.Arn,
+			},
+		}
+	}
+
+	return prometheus, nil
+}
+
+// renders a single prometheus remoteWrite spec
+func prometheusRemoteWriteValues(k8sConfig K8sPlatformConfigInput, remoteWriteConfig PrometheusRemoteWriteConfigInput) (pulumi.Map, error) {
+	if remoteWriteConfig.Url == "" {
+		return nil, errorx.IllegalArgument.New("prometheus remote write url not supplied")
+	}
+	if remoteWriteConfig.BasicAuth && remoteWriteConfig.Sigv4.Enabled {
+		return nil, errorx.IllegalArgument.New("prometheus remote write to %s cannot use both basic auth and sigv4", remoteWriteConfig.Url)
+	}
+
+	remoteWrite := pulumi.Map{
+		"url": pulumi.String(remoteWriteConfig.Url),
+	}
+	if remoteWriteConfig.Name != "" {
+		remoteWrite["name"] = pulumi.String(remoteWriteConfig.Name)
+	}
+
+	if remoteWriteConfig.BasicAuth {
+		secretName := prometheusRemoteWriteSecretName(k8sConfig)
+		remoteWrite["basicAuth"] = pulumi.Map{
+			"username": pulumi.Map{
+				"name": pulumi.String(secretName),
+				"key":  pulumi.String("username"),
+			},
+			"password": pulumi.Map{
+				"name": pulumi.String(secretName),
+				"key":  pulumi.String("password"),
+			},
+		}
+	}
+	if remoteWriteConfig.Sigv4.Enabled {
+		sigv4 := pulumi.Map{}
+		if remoteWriteConfig.Sigv4.Region != "" {
+			sigv4["region"] = pulumi.String(remoteWriteConfig.Sigv4.Region)
+		}
+		if remoteWriteConfig.Sigv4.RoleArn != "" {
+			sigv4["roleArn"] = pulumi.String(remoteWriteConfig.Sigv4.RoleArn)
+		}
+		remoteWrite["sigv4"] = sigv4
+	}
+
+	var relabelConfigs pulumi.Array
+	for _, relabelConfig := range remoteWriteConfig.WriteRelabelConfigs {
+		relabel := pulumi.Map{}
+		if len(relabelConfig.SourceLabels) != 0 {
+			relabel["sourceLabels"] = pulumi.ToStringArray(relabelConfig.SourceLabels)
+		}
+		for key, value := range map[string]string{
+			"separator":   relabelConfig.Separator,
+			"targetLabel": relabelConfig.TargetLabel,
+			"regex":       relabelConfig.Regex,
+			"replacement": relabelConfig.Replacement,
+			"action":      relabelConfig.Action,
+		} {
+			if value != "" {
+				relabel[key] = pulumi.String(value)
+			}
+		}
+		relabelConfigs = append(relabelConfigs, relabel)
+	}
+	if len(relabelConfigs) != 0 {
+		remoteWrite["writeRelabelConfigs"] = relabelConfigs
+	}
+
+	queueConfig := pulumi.Map{}
+	queue := remoteWriteConfig.QueueConfig
+	for key, value := range map[string]int{
+		"capacity":          queue.Capacity,
+		"minShards":         queue.MinShards,
+		"maxShards":         queue.MaxShards,
+		"maxSamplesPerSend": queue.MaxSamplesPerSend,
+	} {
+		if value != 0 {
+			queueConfig[key] = pulumi.Int(value)
+		}
+	}
+	for key, value := range map[string]string{
+		"batchSendDeadline": queue.BatchSendDeadline,
+		"minBackoff":        queue.MinBackoff,
+		"maxBackoff":        queue.MaxBackoff,
+	} {
+		if value != "" {
+			queueConfig[key] = pulumi.String(value)
+		}
+	}
+	if len(queueConfig) != 0 {
+		remoteWrite["queueConfig"] = queueConfig
+	}
+
+	return remoteWrite, nil
+}
+
+// returns the name of the remote write basic auth secret, defaults to
+// "prometheus-remote-write-basic-auth"
+func prometheusRemoteWriteSecretName(k8sConfig K8sPlatformConfigInput) string {
+	if k8sConfig.PrometheusRemoteWriteSecretName != "" {
+		return k8sConfig.PrometheusRemoteWriteSecretName
+	}
+	return "prometheus-remote-write-basic-auth"
+}
+
+// installs the prometheus-operator CRDs from a pinned chart, so that charts
+// creating service monitors deploy when kube-prometheus-stack is disabled or
+// managed elsewhere. kube-prometheus-stack skips CRDs that already exist
+func deployPrometheusOperatorCrds(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	crdsConfig := bootstrap.K8sConfig.PrometheusOperatorCrds
+	if !crdsConfig.Enabled {
+		return nil, nil
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("prometheus-operator-crds"),
+		Name:         "prometheus-operator-crds",
+		Repo:         "https://prometheus-community.github.io/helm-charts",
+		Version:      "5.1.0",
+		Config:       crdsConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
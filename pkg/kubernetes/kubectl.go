@@ -0,0 +1,34 @@
+package kubernetes
+
+import (
+	"github.com/pulumi/pulumi-command/sdk/go/command/local"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+// creates a local command that runs the given kubectl commands against the
+// cluster of the bootstrap, stopping at the first failure. the command is
+// replaced, and so run again, whenever the trigger changes
+func newKubectlCommand(ctx *pulumi.Context, bootstrap *BootstrapContext, name string, commands []string, trigger pulumi.StringInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	// the kubeconfig of the bootstrap is written to a temporary file, the
+	// ambient kubeconfig is used when there is none
+	script := `if [ -n "$BOOTSTRAP_KUBECONFIG" ]; then
+  export KUBECONFIG="$(mktemp)"
+  trap 'rm -f "$KUBECONFIG"' EXIT
+  printf '%s' "$BOOTSTRAP_KUBECONFIG" > "$KUBECONFIG"
+fi
+` + strings.Join(commands, " && \\\n")
+
+	environment := pulumi.StringMap{
+		"COMMAND_TRIGGER": trigger,
+	}
+	if bootstrap.kubeconfig.OutputState != nil {
+		environment["BOOTSTRAP_KUBECONFIG"] = pulumi.ToSecret(bootstrap.kubeconfig).(pulumi.StringOutput)
+	}
+
+	return local.NewCommand(ctx, bootstrap.ResourceName(name), &local.CommandArgs{
+		Create:      pulumi.String(script),
+		Interpreter: pulumi.ToStringArray([]string{"/bin/sh", "-c"}),
+		Environment: environment,
+	}, append(append([]pulumi.ResourceOption{}, opts...), pulumi.ReplaceOnChanges([]string{"*"}))...)
+}
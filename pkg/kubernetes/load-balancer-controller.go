@@ -0,0 +1,104 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+type LoadBalancerControllerConfigInput struct {
+	// optional, installs the aws-load-balancer-controller
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+
+	// the controller IRSA role and subnet tags
+	eks.LoadBalancerControllerInput
+
+	// vpc of the cluster, and optional region, defaults to the aws:region
+	// config
+	VpcId  string `json:"vpc-id"`
+	Region string `json:"region"`
+
+	// optional, name of the ingress class, defaults to alb
+	IngressClass string `json:"ingress-class"`
+	// optional, makes the ingress class the cluster default
+	DefaultIngressClass bool `json:"default-ingress-class"`
+	// optional ingress class params applied to every ingress of the class,
+	// i.e. scheme or group
+	IngressClassParams map[string]interface{} `json:"ingress-class-params"`
+}
+
+// creates the aws-load-balancer-controller IRSA role and subnet tags, then
+// installs the controller
+func deployLoadBalancerController(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	controllerConfig := bootstrap.K8sConfig.LoadBalancerController
+	if !controllerConfig.Enabled {
+		return nil, nil
+	}
+	if controllerConfig.VpcId == "" {
+		return nil, errorx.IllegalArgument.New("vpc id not supplied, cannot install the aws-load-balancer-controller")
+	}
+	region := controllerConfig.Region
+	if region == "" {
+		region = config.New(ctx, "aws").Require("region")
+	}
+	ingressClass := "alb"
+	if controllerConfig.IngressClass != "" {
+		ingressClass = controllerConfig.IngressClass
+	}
+	namespace := "kube-system"
+	if controllerConfig.Namespace != "" {
+		namespace = controllerConfig.Namespace
+	}
+	serviceAccount := "aws-load-balancer-controller"
+	if controllerConfig.ServiceAccount != "" {
+		serviceAccount = controllerConfig.ServiceAccount
+	}
+
+	eksConfig := controllerConfig.LoadBalancerControllerInput
+	if eksConfig.ResourcePrefix == "" {
+		eksConfig.ResourcePrefix = bootstrap.Cluster.Name
+	}
+	output, err := eks.SyncLoadBalancerController(ctx, eksConfig, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := pulumi.Map{
+		"clusterName": pulumi.String(controllerConfig.EKSClusterName),
+		"region":      pulumi.String(region),
+		"vpcId":       pulumi.String(controllerConfig.VpcId),
+		"serviceAccount": pulumi.Map{
+			"name": pulumi.String(serviceAccount),
+			"annotations": pulumi.Map{
+				"eks.amazonaws.com/role-arn": output.ControllerRoleArn,
+			},
+		},
+		"ingressClass": pulumi.String(ingressClass),
+		"ingressClassConfig": pulumi.Map{
+			"default": pulumi.Bool(controllerConfig.DefaultIngressClass),
+		},
+	}
+	if len(controllerConfig.IngressClassParams) != 0 {
+		resolver, err := secretResolverFor(ctx, controllerConfig.IngressClassParams)
+		if err != nil {
+			return nil, err
+		}
+		values["ingressClassParams"] = pulumi.Map{
+			"spec": toHelmValues(resolver, controllerConfig.IngressClassParams),
+		}
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("aws-load-balancer-controller"),
+		Name:                "aws-load-balancer-controller",
+		Namespace:           namespace,
+		SkipCreateNamespace: namespace == "kube-system",
+		Repo:                "https://aws.github.io/eks-charts",
+		Version:             "1.6.2",
+		Values:              values,
+		Config:              controllerConfig.Helm,
+		PulumiConfig:        bootstrap.Config,
+	}, opts...)
+}
@@ -0,0 +1,11 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/logging"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// returns the module logger for messages about a pulumi resource
+func logger(ctx *pulumi.Context, resourceName string) *logging.Logger {
+	return logging.New(ctx, "kubernetes").WithResource(resourceName)
+}
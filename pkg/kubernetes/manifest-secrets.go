@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"bytes"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/secrets"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"os/exec"
+)
+
+type SealedSecretsConfig struct {
+	// optional name and namespace of the sealed-secrets controller, used to
+	// fetch its certificate, default to sealed-secrets-controller and
+	// kube-system like kubeseal
+	ControllerName      string `json:"controller-name"`
+	ControllerNamespace string `json:"controller-namespace"`
+	// optional path or url of the controller's public certificate, allows
+	// sealing without access to the cluster
+	Cert string `json:"cert"`
+}
+
+// SyncSopsManifest takes in a pulumi resource name, and a SOPS encrypted yaml kubernetes manifest as byte array. The
+// manifest is decrypted with the `sops` cli, which must be installed and have access to the age key or KMS key the
+// manifest is encrypted with, i.e. through SOPS_AGE_KEY_FILE or aws credentials. The decrypted manifest is synced
+// like SyncKubernetesManifest, so encrypted manifests can be kept in git next to the argo-cd applications.
+func SyncSopsManifest(ctx *pulumi.Context, pulumiResourceName string, encryptedManifest []byte, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	manifest, err := DecryptSopsManifest(encryptedManifest)
+	err = logger(ctx, pulumiResourceName).Wrap("error decrypting sops manifest", err)
+	if err != nil {
+		return nil, err
+	}
+	return SyncKubernetesManifest(ctx, pulumiResourceName, manifest, opts...)
+}
+
+// DecryptSopsManifest decrypts a SOPS encrypted yaml manifest with the `sops` cli
+func DecryptSopsManifest(encryptedManifest []byte) ([]byte, error) {
+	return runManifestCommand(encryptedManifest, "sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", "/dev/stdin")
+}
+
+// SyncSealedSecret takes in a pulumi resource name, and a plain kubernetes secret manifest as byte array. Secret
+// placeholders in the manifest, i.e. <<mySecretValue>>, are replaced from the configured secret provider, then the
+// secret is sealed with the `kubeseal` cli and the resulting SealedSecret is synced, so that only the encrypted secret
+// leaves the machine. Sealing is not deterministic, so changes to the encrypted data are ignored after the first sync;
+// replace the resource to rotate the secret.
+func SyncSealedSecret(ctx *pulumi.Context, pulumiResourceName string, secretManifest []byte, sealedSecretsConfig SealedSecretsConfig, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	manifest, err := secrets.ReplaceSecrets(ctx, string(secretManifest))
+	err = logger(ctx, pulumiResourceName).Wrap("error replacing secrets in secret manifest", err)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--format", "yaml"}
+	if sealedSecretsConfig.Cert != "" {
+		args = append(args, "--cert", sealedSecretsConfig.Cert)
+	}
+	if sealedSecretsConfig.ControllerName != "" {
+		args = append(args, "--controller-name", sealedSecretsConfig.ControllerName)
+	}
+	if sealedSecretsConfig.ControllerNamespace != "" {
+		args = append(args, "--controller-namespace", sealedSecretsConfig.ControllerNamespace)
+	}
+	sealedSecret, err := runManifestCommand([]byte(manifest), "kubeseal", args...)
+	err = logger(ctx, pulumiResourceName).Wrap("error sealing secret", err)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreEncryptedData := func(args *pulumi.ResourceTransformationArgs) *pulumi.ResourceTransformationResult {
+		return &pulumi.ResourceTransformationResult{
+			Props: args.Props,
+			Opts:  append(args.Opts, pulumi.IgnoreChanges([]string{"spec.encryptedData"})),
+		}
+	}
+	opts = append(opts, pulumi.Transformations([]pulumi.ResourceTransformation{ignoreEncryptedData}))
+	return SyncKubernetesManifest(ctx, pulumiResourceName, sealedSecret, opts...)
+}
+
+// runs a cli with the manifest on stdin, returning its stdout
+func runManifestCommand(manifest []byte, command string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = bytes.NewReader(manifest)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, errorx.ExternalError.Wrap(err, "%s failed: %s", command, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"bytes"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/secrets"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/yaml"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"regexp"
+	"text/template"
+)
+
+// matches catalyst squad secret placeholders, i.e. <<mySecretValue>>
+var secretPlaceholderPattern = regexp.MustCompile(`<<([^<>]+)>>`)
+
+// SyncTemplatedManifest takes in a pulumi resource name, a yaml kubernetes manifest template, and template variables.
+// Variables are substituted with go templating, i.e. {{ .namespace }}, and every <<mySecretValue>> placeholder is
+// replaced with the secret `mySecretValue` of the configured secret provider, see secrets.ReplaceSecrets. Secrets are
+// substituted into the resources as pulumi secret outputs, so they never appear in plain text in the state or diffs.
+func SyncTemplatedManifest(ctx *pulumi.Context, pulumiResourceName string, manifestTemplate []byte, vars map[string]interface{}, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	parsed, err := template.New(pulumiResourceName).Option("missingkey=error").Parse(string(manifestTemplate))
+	err = logger(ctx, pulumiResourceName).Wrap("error parsing manifest template", err)
+	if err != nil {
+		return nil, err
+	}
+	var manifest bytes.Buffer
+	err = parsed.Execute(&manifest, vars)
+	err = logger(ctx, pulumiResourceName).Wrap("error templating manifest", err)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := secretResolverFor(ctx, manifest.String())
+	err = logger(ctx, pulumiResourceName).Wrap("error replacing secrets in manifest", err)
+	if err != nil {
+		return nil, err
+	}
+	replaceSecrets := func(state map[string]interface{}, opts ...pulumi.ResourceOption) {
+		replaceSecretsInObject(resolver, state)
+	}
+	return syncKubernetesManifest(ctx, pulumiResourceName, manifest.Bytes(), []yaml.Transformation{replaceSecrets}, opts...)
+}
+
+// returns a resolver of the configured secret provider when the value
+// contains secret placeholders, so that the secret provider is only required
+// when secrets are used
+func secretResolverFor(ctx *pulumi.Context, value interface{}) (*secrets.SecretResolver, error) {
+	if !containsSecretPlaceholder(value) {
+		return nil, nil
+	}
+	return secrets.NewSecretResolver(ctx)
+}
+
+func containsSecretPlaceholder(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return secretPlaceholderPattern.MatchString(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			if containsSecretPlaceholder(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if containsSecretPlaceholder(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaces secret placeholders in every string of the object in place
+func replaceSecretsInObject(resolver *secrets.SecretResolver, object map[string]interface{}) {
+	for key, value := range object {
+		object[key] = replaceSecretsInValue(resolver, value)
+	}
+}
+
+func replaceSecretsInValue(resolver *secrets.SecretResolver, value interface{}) interface{} {
+	if resolver == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		if secretPlaceholderPattern.MatchString(v) {
+			return resolver.ReplaceOutput(v)
+		}
+	case map[string]interface{}:
+		replaceSecretsInObject(resolver, v)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceSecretsInValue(resolver, item)
+		}
+	}
+	return value
+}
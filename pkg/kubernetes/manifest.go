@@ -1,35 +1,126 @@
 package kubernetes
 
 import (
-	"fmt"
-	"github.com/catalystcommunity/app-utils-go/errorutils"
+	"bytes"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/kustomize"
 	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/yaml"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-	"os"
+	goyaml "gopkg.in/yaml.v3"
+	"io"
+	"strings"
 )
 
 // SyncKubernetesManifest takes in a pulumi resource name, and a yaml kubernetes manifest as byte array.
-// It writes the manifest to a file, defers deletion of said file, and creates a pulumi config file from it.
-// Pulumi creates the k8s resources from the config file. Recommended use is to store your manifests in yaml file,
-// embed them, template them with pulumi secrets, or variables, and then pass them to this method to sync
-// the kubernetes resource, whatever it may be.
+// Pulumi creates the k8s resources from the manifest in memory, so nothing is written to disk and concurrent stacks
+// don't collide. Recommended use is to store your manifests in yaml file, embed them, template them with pulumi
+// secrets, or variables, and then pass them to this method to sync the kubernetes resource, whatever it may be.
 func SyncKubernetesManifest(ctx *pulumi.Context, pulumiResourceName string, manifest []byte, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
-	// write bytes to file
-	tempFileName := fmt.Sprintf("/tmp/%s.yaml", pulumiResourceName)
-	err := os.WriteFile(tempFileName, manifest, 0644)
-	errorutils.LogOnErr(nil, "error writing manifest to file", err)
+	return syncKubernetesManifest(ctx, pulumiResourceName, manifest, nil, opts...)
+}
+
+func syncKubernetesManifest(ctx *pulumi.Context, pulumiResourceName string, manifest []byte, transformations []yaml.Transformation, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	// manifests used to be synced as config files, alias them so that
+	// existing resources are not replaced
+	opts = append(opts, pulumi.Aliases([]pulumi.Alias{{Type: pulumi.String("kubernetes:yaml:ConfigFile")}}))
+	resource, err := yaml.NewConfigGroup(ctx, pulumiResourceName, &yaml.ConfigGroupArgs{
+		YAML:            []string{string(manifest)},
+		Transformations: transformations,
+	}, opts...)
+	err = logger(ctx, pulumiResourceName).Wrap("error getting pulumi config group from manifest", err)
+	return resource, err
+}
+
+// SyncKubernetesManifests takes in a pulumi resource name, and a multi-document yaml kubernetes manifest as byte array.
+// Every `---` separated document is synced as its own resource, named after the document's kind, namespace, and name,
+// so that changes to one document show up individually in previews. Empty documents are skipped.
+func SyncKubernetesManifests(ctx *pulumi.Context, pulumiResourceName string, manifests []byte, opts ...pulumi.ResourceOption) ([]pulumi.Resource, error) {
+	documents, err := splitManifests(manifests)
+	err = logger(ctx, pulumiResourceName).Wrap("error splitting manifests", err)
 	if err != nil {
 		return nil, err
 	}
-	// defer file deletion
-	defer func() {
-		err = os.Remove(tempFileName)
-		errorutils.LogOnErr(nil, "error deleting manifest file", err)
-	}()
-	// get pulumi configfile from written manifest
-	resource, err := yaml.NewConfigFile(ctx, pulumiResourceName, &yaml.ConfigFileArgs{
-		File: tempFileName,
+
+	var resources []pulumi.Resource
+	names := map[string]bool{}
+	for _, document := range documents {
+		name := pulumiResourceName + "-" + document.name
+		if names[name] {
+			return nil, errorx.IllegalArgument.New("duplicate manifest %s in %s", document.name, pulumiResourceName)
+		}
+		names[name] = true
+
+		resource, err := SyncKubernetesManifest(ctx, name, document.manifest, opts...)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// SyncKustomizeDirectory takes in a pulumi resource name, and a local directory or remote url of a kustomization, and
+// syncs the resources rendered by kustomize
+func SyncKustomizeDirectory(ctx *pulumi.Context, pulumiResourceName string, directory string, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	resource, err := kustomize.NewDirectory(ctx, pulumiResourceName, kustomize.DirectoryArgs{
+		Directory: pulumi.String(directory),
 	}, opts...)
-	errorutils.LogOnErr(nil, "error getting pulumi configfile from manifest file", err)
+	err = logger(ctx, pulumiResourceName).Wrap("error syncing kustomize directory", err)
 	return resource, err
 }
+
+type manifestDocument struct {
+	name     string
+	manifest []byte
+}
+
+// splits a multi-document manifest, naming every document by its kind,
+// namespace, and name
+func splitManifests(manifests []byte) ([]manifestDocument, error) {
+	var documents []manifestDocument
+	decoder := goyaml.NewDecoder(bytes.NewReader(manifests))
+	for {
+		var node goyaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var object struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		}
+		err = node.Decode(&object)
+		if err != nil {
+			return nil, err
+		}
+		if object.Kind == "" && object.Metadata.Name == "" {
+			// empty document
+			continue
+		}
+		if object.Kind == "" || object.Metadata.Name == "" {
+			return nil, errorx.IllegalArgument.New("manifest document requires a kind and metadata.name")
+		}
+
+		manifest, err := goyaml.Marshal(&node)
+		if err != nil {
+			return nil, err
+		}
+		nameParts := []string{strings.ToLower(object.Kind)}
+		if object.Metadata.Namespace != "" {
+			nameParts = append(nameParts, object.Metadata.Namespace)
+		}
+		nameParts = append(nameParts, object.Metadata.Name)
+		documents = append(documents, manifestDocument{
+			name:     strings.Join(nameParts, "-"),
+			manifest: manifest,
+		})
+	}
+	return documents, nil
+}
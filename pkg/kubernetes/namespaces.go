@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/joomcode/errorx"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	networkingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type NamespaceConfig struct {
+	Name string `json:"name" validate:"required"`
+
+	// optional additional labels and annotations
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+
+	// optional pod security admission levels, one of privileged, baseline,
+	// or restricted. enforce defaults to baseline, warn and audit default to
+	// restricted so that violations of the stricter level are reported
+	PodSecurityEnforce string `json:"pod-security-enforce" validate:"oneof=privileged|baseline|restricted"`
+	PodSecurityWarn    string `json:"pod-security-warn" validate:"oneof=privileged|baseline|restricted"`
+	PodSecurityAudit   string `json:"pod-security-audit" validate:"oneof=privileged|baseline|restricted"`
+
+	// optional hard limits of a resource quota, i.e. "requests.cpu": "10" or
+	// "pods": "50"
+	ResourceQuota map[string]string `json:"resource-quota"`
+
+	// optional default requests and limits of containers
+	LimitRange NamespaceLimitRangeConfigInput `json:"limit-range"`
+
+	// optional default-deny network policy
+	NetworkPolicy NamespaceNetworkPolicyConfigInput `json:"network-policy"`
+}
+
+type NamespaceLimitRangeConfigInput struct {
+	// i.e. "cpu": "100m" and "memory": "128Mi"
+	DefaultRequest map[string]string `json:"default-request"`
+	Default        map[string]string `json:"default"`
+	Max            map[string]string `json:"max"`
+}
+
+type NamespaceNetworkPolicyConfigInput struct {
+	// denies all ingress and egress of the namespace's pods except dns
+	// lookups, so that other policies must allow traffic explicitly
+	DefaultDeny bool `json:"default-deny"`
+	// optional, allows traffic between the pods of the namespace
+	AllowSameNamespace bool `json:"allow-same-namespace"`
+}
+
+// pod security admission levels
+const (
+	PodSecurityPrivileged = "privileged"
+	PodSecurityBaseline   = "baseline"
+	PodSecurityRestricted = "restricted"
+)
+
+// SyncNamespaces creates namespaces with pod security admission labels, and optionally a resource quota, a limit
+// range, and a default-deny network policy each.
+func SyncNamespaces(ctx *pulumi.Context, namespaces []NamespaceConfig, opts ...pulumi.ResourceOption) ([]*corev1.Namespace, error) {
+	return syncNamespaces(ctx, "", namespaces, opts...)
+}
+
+// manages the namespaces of the k8s namespaces config
+func deployNamespaces(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if len(bootstrap.K8sConfig.Namespaces) == 0 {
+		return nil, nil
+	}
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("namespaces"), opts...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = syncNamespaces(ctx, bootstrap.Cluster.Name, bootstrap.K8sConfig.Namespaces, resourceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Done(ctx)
+}
+
+func syncNamespaces(ctx *pulumi.Context, resourcePrefix string, namespaces []NamespaceConfig, opts ...pulumi.ResourceOption) ([]*corev1.Namespace, error) {
+	var resources []*corev1.Namespace
+	for _, namespaceConfig := range namespaces {
+		namespace, err := syncNamespace(ctx, resourcePrefix, namespaceConfig, opts...)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, namespace)
+	}
+	return resources, nil
+}
+
+func syncNamespace(ctx *pulumi.Context, resourcePrefix string, config NamespaceConfig, opts ...pulumi.ResourceOption) (*corev1.Namespace, error) {
+	if config.Name == "" {
+		return nil, errorx.IllegalArgument.New("namespace name not supplied")
+	}
+	resourceName := func(name string) string {
+		return utils.PrefixedName(resourcePrefix, fmt.Sprintf("namespace-%s%s", config.Name, name))
+	}
+
+	labels := map[string]string{
+		"pod-security.kubernetes.io/enforce": stringOrDefault(config.PodSecurityEnforce, PodSecurityBaseline),
+		"pod-security.kubernetes.io/warn":    stringOrDefault(config.PodSecurityWarn, PodSecurityRestricted),
+		"pod-security.kubernetes.io/audit":   stringOrDefault(config.PodSecurityAudit, PodSecurityRestricted),
+	}
+	for key, value := range config.Labels {
+		labels[key] = value
+	}
+	namespace, err := corev1.NewNamespace(ctx, resourceName(""), &corev1.NamespaceArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:        pulumi.String(config.Name),
+			Labels:      pulumi.ToStringMap(labels),
+			Annotations: pulumi.ToStringMap(config.Annotations),
+		},
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the namespace's resources are created after the namespace
+	namespacedOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{namespace}))
+	metadata := func(name string) *metav1.ObjectMetaArgs {
+		return &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(name),
+			Namespace: pulumi.String(config.Name),
+		}
+	}
+
+	if len(config.ResourceQuota) != 0 {
+		_, err = corev1.NewResourceQuota(ctx, resourceName("-resource-quota"), &corev1.ResourceQuotaArgs{
+			Metadata: metadata("default"),
+			Spec: &corev1.ResourceQuotaSpecArgs{
+				Hard: pulumi.ToStringMap(config.ResourceQuota),
+			},
+		}, namespacedOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limitRange := config.LimitRange
+	if len(limitRange.DefaultRequest) != 0 || len(limitRange.Default) != 0 || len(limitRange.Max) != 0 {
+		_, err = corev1.NewLimitRange(ctx, resourceName("-limit-range"), &corev1.LimitRangeArgs{
+			Metadata: metadata("default"),
+			Spec: &corev1.LimitRangeSpecArgs{
+				Limits: corev1.LimitRangeItemArray{
+					corev1.LimitRangeItemArgs{
+						Type:           pulumi.String("Container"),
+						DefaultRequest: pulumi.ToStringMap(limitRange.DefaultRequest),
+						Default:        pulumi.ToStringMap(limitRange.Default),
+						Max:            pulumi.ToStringMap(limitRange.Max),
+					},
+				},
+			},
+		}, namespacedOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.NetworkPolicy.DefaultDeny {
+		_, err = networkingv1.NewNetworkPolicy(ctx, resourceName("-default-deny"), &networkingv1.NetworkPolicyArgs{
+			Metadata: metadata("default-deny"),
+			Spec:     defaultDenyNetworkPolicySpec(config.NetworkPolicy),
+		}, namespacedOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return namespace, nil
+}
+
+// selects all pods of the namespace, denying ingress and egress except dns
+// lookups in kube-system, and traffic within the namespace if allowed
+func defaultDenyNetworkPolicySpec(config NamespaceNetworkPolicyConfigInput) *networkingv1.NetworkPolicySpecArgs {
+	egress := networkingv1.NetworkPolicyEgressRuleArray{
+		networkingv1.NetworkPolicyEgressRuleArgs{
+			To: networkingv1.NetworkPolicyPeerArray{
+				networkingv1.NetworkPolicyPeerArgs{
+					NamespaceSelector: &metav1.LabelSelectorArgs{
+						MatchLabels: pulumi.StringMap{
+							"kubernetes.io/metadata.name": pulumi.String("kube-system"),
+						},
+					},
+				},
+			},
+			Ports: networkingv1.NetworkPolicyPortArray{
+				networkingv1.NetworkPolicyPortArgs{Protocol: pulumi.String("UDP"), Port: pulumi.Int(53)},
+				networkingv1.NetworkPolicyPortArgs{Protocol: pulumi.String("TCP"), Port: pulumi.Int(53)},
+			},
+		},
+	}
+	var ingress networkingv1.NetworkPolicyIngressRuleArray
+	if config.AllowSameNamespace {
+		// a pod selector without a namespace selector selects the pods of the
+		// policy's namespace
+		sameNamespace := networkingv1.NetworkPolicyPeerArray{
+			networkingv1.NetworkPolicyPeerArgs{
+				PodSelector: &metav1.LabelSelectorArgs{},
+			},
+		}
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRuleArgs{From: sameNamespace})
+		egress = append(egress, networkingv1.NetworkPolicyEgressRuleArgs{To: sameNamespace})
+	}
+
+	return &networkingv1.NetworkPolicySpecArgs{
+		PodSelector: &metav1.LabelSelectorArgs{},
+		PolicyTypes: pulumi.ToStringArray([]string{"Ingress", "Egress"}),
+		Ingress:     ingress,
+		Egress:      egress,
+	}
+}
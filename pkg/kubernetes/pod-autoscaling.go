@@ -0,0 +1,91 @@
+package kubernetes
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type MetricsServerConfigInput struct {
+	// optional, installs metrics-server, which provides the resource metrics
+	// api that horizontal pod autoscalers and kubectl top use
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+}
+
+type VerticalPodAutoscalerConfigInput struct {
+	// optional, installs the vertical-pod-autoscaler recommender, updater, and
+	// admission controller
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+
+	// optional, only recommends resources without evicting or mutating pods,
+	// for VPAs in "Off" update mode
+	RecommenderOnly bool `json:"recommender-only"`
+}
+
+// installs metrics-server in kube-system, with a replica per zone and a
+// disruption budget so that autoscaling survives node drains
+func deployMetricsServer(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	metricsServerConfig := bootstrap.K8sConfig.MetricsServer
+	if !metricsServerConfig.Enabled {
+		return nil, nil
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("metrics-server"),
+		Name:                "metrics-server",
+		Namespace:           "kube-system",
+		SkipCreateNamespace: true,
+		Repo:                "https://kubernetes-sigs.github.io/metrics-server",
+		Version:             "3.11.0",
+		Values: pulumi.Map{
+			"replicas": pulumi.Int(2),
+			"podDisruptionBudget": pulumi.Map{
+				"enabled":      pulumi.Bool(true),
+				"minAvailable": pulumi.Int(1),
+			},
+			"topologySpreadConstraints": pulumi.Array{
+				pulumi.Map{
+					"maxSkew":           pulumi.Int(1),
+					"topologyKey":       pulumi.String("topology.kubernetes.io/zone"),
+					"whenUnsatisfiable": pulumi.String("ScheduleAnyway"),
+					"labelSelector": pulumi.Map{
+						"matchLabels": pulumi.Map{
+							"app.kubernetes.io/name": pulumi.String("metrics-server"),
+						},
+					},
+				},
+			},
+		},
+		Config:       metricsServerConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
+
+// installs the vertical-pod-autoscaler, which reads usage from the resource
+// metrics api
+func deployVerticalPodAutoscaler(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	vpaConfig := bootstrap.K8sConfig.VerticalPodAutoscaler
+	if !vpaConfig.Enabled {
+		return nil, nil
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("vertical-pod-autoscaler"),
+		Name:         "vpa",
+		Repo:         "https://charts.fairwinds.com/stable",
+		Version:      "4.4.6",
+		Values: pulumi.Map{
+			"recommender": pulumi.Map{
+				"enabled": pulumi.Bool(true),
+			},
+			"updater": pulumi.Map{
+				"enabled": pulumi.Bool(!vpaConfig.RecommenderOnly),
+			},
+			"admissionController": pulumi.Map{
+				"enabled": pulumi.Bool(!vpaConfig.RecommenderOnly),
+			},
+		},
+		Config:       vpaConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
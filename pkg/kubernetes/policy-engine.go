@@ -0,0 +1,365 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+type PolicyEngineConfigInput struct {
+	// optional, installs a policy engine
+	Enabled bool `json:"enabled"`
+	// one of "kyverno" (default) or "gatekeeper"
+	Engine string                 `json:"engine" validate:"oneof=kyverno|gatekeeper"`
+	Helm   HelmReleaseConfigInput `json:"helm-release"`
+
+	// optional, one of "enforce" (default), which rejects violating
+	// resources, or "audit", which only reports them
+	ValidationAction string `json:"validation-action" validate:"oneof=enforce|audit"`
+	// optional namespaces the policies do not apply to, defaults to
+	// kube-system and the engine's namespace
+	ExcludedNamespaces []string `json:"excluded-namespaces"`
+
+	// optional policies of the module's library
+	Policies PolicyLibraryConfigInput `json:"policies"`
+}
+
+type PolicyLibraryConfigInput struct {
+	// rejects privileged containers
+	DisallowPrivileged bool `json:"disallow-privileged"`
+	// optional labels that every pod must have, i.e. "app.kubernetes.io/name"
+	RequiredLabels []string `json:"required-labels"`
+	// optional image registry prefixes that containers must be pulled from,
+	// i.e. "123456789012.dkr.ecr.us-east-1.amazonaws.com/"
+	AllowedRegistries []string `json:"allowed-registries"`
+}
+
+// policy engines
+const (
+	PolicyEngineKyverno    = "kyverno"
+	PolicyEngineGatekeeper = "gatekeeper"
+)
+
+// policy validation actions
+const (
+	PolicyValidationEnforce = "enforce"
+	PolicyValidationAudit   = "audit"
+)
+
+// a policy of the library, rendered as kyverno cluster policy or gatekeeper
+// constraint template and constraint
+type libraryPolicy struct {
+	name string
+	// kyverno validate rule
+	kyvernoValidate map[string]interface{}
+	// gatekeeper constraint kind, rego, and constraint parameters
+	gatekeeperKind       string
+	gatekeeperRego       string
+	gatekeeperParameters map[string]interface{}
+}
+
+// installs kyverno or gatekeeper and the configured policies of the library
+func deployPolicyEngine(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	policyConfig := bootstrap.K8sConfig.PolicyEngine
+	if !policyConfig.Enabled {
+		return nil, nil
+	}
+
+	var spec HelmReleaseSpec
+	switch policyConfig.Engine {
+	case "", PolicyEngineKyverno:
+		spec = HelmReleaseSpec{
+			ResourceName: bootstrap.ResourceName("kyverno"),
+			Name:         "kyverno",
+			Repo:         "https://kyverno.github.io/kyverno",
+			Version:      "2.6.5",
+		}
+	case PolicyEngineGatekeeper:
+		spec = HelmReleaseSpec{
+			ResourceName: bootstrap.ResourceName("gatekeeper"),
+			Name:         "gatekeeper",
+			Namespace:    "gatekeeper-system",
+			Repo:         "https://open-policy-agent.github.io/gatekeeper/charts",
+			Version:      "3.10.0",
+		}
+	default:
+		return nil, errorx.IllegalArgument.New("unknown policy engine: '%s'", policyConfig.Engine)
+	}
+	spec.Config = policyConfig.Helm
+	spec.PulumiConfig = bootstrap.Config
+	release, err := DeployHelmRelease(ctx, spec, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedNamespaces := policyConfig.ExcludedNamespaces
+	if len(excludedNamespaces) == 0 {
+		excludedNamespaces = []string{"kube-system", stringOrDefault(spec.Namespace, spec.Name)}
+	}
+
+	// the helm release waits for the engine to be ready, so its CRDs and
+	// webhooks are available to the policies
+	policyOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{release}))
+	for _, policy := range libraryPolicies(policyConfig.Policies) {
+		if policyConfig.Engine == PolicyEngineGatekeeper {
+			err = deployGatekeeperPolicy(ctx, bootstrap, policy, policyConfig.ValidationAction, excludedNamespaces, policyOpts...)
+		} else {
+			err = deployKyvernoPolicy(ctx, bootstrap, policy, policyConfig.ValidationAction, excludedNamespaces, policyOpts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// returns the configured policies of the library
+func libraryPolicies(config PolicyLibraryConfigInput) []libraryPolicy {
+	var policies []libraryPolicy
+	if config.DisallowPrivileged {
+		// kyverno's =() anchors only check the fields when they are present
+		privileged := []interface{}{
+			map[string]interface{}{
+				"=(securityContext)": map[string]interface{}{
+					"=(privileged)": "false",
+				},
+			},
+		}
+		policies = append(policies, libraryPolicy{
+			name: "disallow-privileged-containers",
+			kyvernoValidate: map[string]interface{}{
+				"message": "privileged containers are not allowed",
+				"pattern": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"=(ephemeralContainers)": privileged,
+						"=(initContainers)":      privileged,
+						"containers":             privileged,
+					},
+				},
+			},
+			gatekeeperKind: "K8sDisallowPrivileged",
+			gatekeeperRego: `package k8sdisallowprivileged
+
+violation[{"msg": msg}] {
+  c := input_containers[_]
+  c.securityContext.privileged
+  msg := sprintf("privileged containers are not allowed: %v", [c.name])
+}
+` + gatekeeperInputContainersRego,
+		})
+	}
+
+	if len(config.RequiredLabels) != 0 {
+		labels := map[string]interface{}{}
+		for _, label := range config.RequiredLabels {
+			labels[label] = "?*"
+		}
+		policies = append(policies, libraryPolicy{
+			name: "require-labels",
+			kyvernoValidate: map[string]interface{}{
+				"message": fmt.Sprintf("the labels %s are required", strings.Join(config.RequiredLabels, ", ")),
+				"pattern": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": labels,
+					},
+				},
+			},
+			gatekeeperKind: "K8sRequiredLabels",
+			gatekeeperRego: `package k8srequiredlabels
+
+violation[{"msg": msg}] {
+  provided := {label | input.review.object.metadata.labels[label]}
+  required := {label | label := input.parameters.labels[_]}
+  missing := required - provided
+  count(missing) > 0
+  msg := sprintf("missing required labels: %v", [missing])
+}
+`,
+			gatekeeperParameters: map[string]interface{}{
+				"labels": config.RequiredLabels,
+			},
+		})
+	}
+
+	if len(config.AllowedRegistries) != 0 {
+		var images []string
+		for _, registry := range config.AllowedRegistries {
+			images = append(images, registry+"*")
+		}
+		allowed := []interface{}{
+			map[string]interface{}{
+				"image": strings.Join(images, " | "),
+			},
+		}
+		policies = append(policies, libraryPolicy{
+			name: "restrict-image-registries",
+			kyvernoValidate: map[string]interface{}{
+				"message": fmt.Sprintf("images must be pulled from %s", strings.Join(config.AllowedRegistries, ", ")),
+				"pattern": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"=(ephemeralContainers)": allowed,
+						"=(initContainers)":      allowed,
+						"containers":             allowed,
+					},
+				},
+			},
+			gatekeeperKind: "K8sAllowedRepos",
+			gatekeeperRego: `package k8sallowedrepos
+
+violation[{"msg": msg}] {
+  c := input_containers[_]
+  not startswith_any(c.image, input.parameters.repos)
+  msg := sprintf("image %v of container %v is not from an allowed registry", [c.image, c.name])
+}
+
+startswith_any(image, repos) {
+  startswith(image, repos[_])
+}
+` + gatekeeperInputContainersRego,
+			gatekeeperParameters: map[string]interface{}{
+				"repos": config.AllowedRegistries,
+			},
+		})
+	}
+	return policies
+}
+
+// collects the containers of a reviewed pod
+const gatekeeperInputContainersRego = `
+input_containers[c] {
+  c := input.review.object.spec.containers[_]
+}
+
+input_containers[c] {
+  c := input.review.object.spec.initContainers[_]
+}
+
+input_containers[c] {
+  c := input.review.object.spec.ephemeralContainers[_]
+}
+`
+
+// creates a kyverno cluster policy validating pods. kyverno generates the
+// rules of pod controllers, like deployments, from pod rules
+func deployKyvernoPolicy(ctx *pulumi.Context, bootstrap *BootstrapContext, policy libraryPolicy, validationAction string, excludedNamespaces []string, opts ...pulumi.ResourceOption) error {
+	manifest, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kyverno.io/v1",
+		"kind":       "ClusterPolicy",
+		"metadata": map[string]interface{}{
+			"name": policy.name,
+		},
+		"spec": map[string]interface{}{
+			"validationFailureAction": stringOrDefault(validationAction, PolicyValidationEnforce),
+			"background":              true,
+			"rules": []interface{}{
+				map[string]interface{}{
+					"name": policy.name,
+					"match": map[string]interface{}{
+						"any": []interface{}{
+							map[string]interface{}{
+								"resources": map[string]interface{}{"kinds": []string{"Pod"}},
+							},
+						},
+					},
+					"exclude": map[string]interface{}{
+						"any": []interface{}{
+							map[string]interface{}{
+								"resources": map[string]interface{}{"namespaces": excludedNamespaces},
+							},
+						},
+					},
+					"validate": policy.kyvernoValidate,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = SyncKubernetesManifest(ctx, bootstrap.ResourceName("kyverno-policy-"+policy.name), manifest, opts...)
+	return err
+}
+
+// creates a gatekeeper constraint template and its constraint on pods. the
+// constraint's kind is created by gatekeeper from the template
+func deployGatekeeperPolicy(ctx *pulumi.Context, bootstrap *BootstrapContext, policy libraryPolicy, validationAction string, excludedNamespaces []string, opts ...pulumi.ResourceOption) error {
+	crd := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"names": map[string]interface{}{"kind": policy.gatekeeperKind},
+		},
+	}
+	if len(policy.gatekeeperParameters) != 0 {
+		properties := map[string]interface{}{}
+		for parameter := range policy.gatekeeperParameters {
+			properties[parameter] = map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			}
+		}
+		crd["spec"].(map[string]interface{})["validation"] = map[string]interface{}{
+			"openAPIV3Schema": map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+			},
+		}
+	}
+	templateManifest, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "templates.gatekeeper.sh/v1",
+		"kind":       "ConstraintTemplate",
+		"metadata": map[string]interface{}{
+			"name": strings.ToLower(policy.gatekeeperKind),
+		},
+		"spec": map[string]interface{}{
+			"crd": crd,
+			"targets": []interface{}{
+				map[string]interface{}{
+					"target": "admission.k8s.gatekeeper.sh",
+					"rego":   policy.gatekeeperRego,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	template, err := SyncKubernetesManifest(ctx, bootstrap.ResourceName("gatekeeper-template-"+policy.name), templateManifest, opts...)
+	if err != nil {
+		return err
+	}
+
+	enforcementAction := "deny"
+	if validationAction == PolicyValidationAudit {
+		enforcementAction = "dryrun"
+	}
+	constraintSpec := map[string]interface{}{
+		"enforcementAction": enforcementAction,
+		"match": map[string]interface{}{
+			"kinds": []interface{}{
+				map[string]interface{}{
+					"apiGroups": []string{""},
+					"kinds":     []string{"Pod"},
+				},
+			},
+			"excludedNamespaces": excludedNamespaces,
+		},
+	}
+	if len(policy.gatekeeperParameters) != 0 {
+		constraintSpec["parameters"] = policy.gatekeeperParameters
+	}
+	constraintManifest, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "constraints.gatekeeper.sh/v1beta1",
+		"kind":       policy.gatekeeperKind,
+		"metadata": map[string]interface{}{
+			"name": policy.name,
+		},
+		"spec": constraintSpec,
+	})
+	if err != nil {
+		return err
+	}
+	constraintOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{template}))
+	_, err = SyncKubernetesManifest(ctx, bootstrap.ResourceName("gatekeeper-constraint-"+policy.name), constraintManifest, constraintOpts...)
+	return err
+}
@@ -0,0 +1,156 @@
+package kubernetes
+
+import (
+	"fmt"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	policyv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/policy/v1"
+	schedulingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/scheduling/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+type PlatformResilienceConfigInput struct {
+	// optional, creates the platform-critical, platform, and default priority
+	// classes, and sets them on the argo-cd, kube-prometheus-stack, ingress
+	// controller, and falco pods so that they are not preempted by workloads
+	PriorityClasses bool `json:"priority-classes"`
+	// optional, creates pod disruption budgets for argo-cd, prometheus, and
+	// the ingress controller, so that node drains evict one pod at a time
+	PodDisruptionBudgets bool `json:"pod-disruption-budgets"`
+}
+
+// platform priority classes. names starting with system- are reserved by
+// kubernetes, so the highest class is platform-critical
+const (
+	PriorityClassPlatformCritical = "platform-critical"
+	PriorityClassPlatform         = "platform"
+	PriorityClassDefault          = "default"
+)
+
+// a platform priority class, in creation order
+var platformPriorityClasses = []struct {
+	name          string
+	value         int
+	globalDefault bool
+	description   string
+}{
+	{PriorityClassDefault, 0, true, "default priority of workloads without a priority class"},
+	{PriorityClassPlatform, 100000, false, "platform services, i.e. argo-cd and monitoring"},
+	{PriorityClassPlatformCritical, 1000000, false, "platform services that serve traffic, i.e. ingress controllers"},
+}
+
+// creates the platform priority classes
+func deployPriorityClasses(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.Resilience.PriorityClasses {
+		return nil, nil
+	}
+
+	// each class depends on the previous one, so that depending on the last
+	// class waits for all of them
+	var priorityClass pulumi.Resource
+	for _, class := range platformPriorityClasses {
+		classOpts := opts
+		if priorityClass != nil {
+			classOpts = append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{priorityClass}))
+		}
+		var err error
+		priorityClass, err = schedulingv1.NewPriorityClass(ctx, bootstrap.ResourceName(fmt.Sprintf("priority-class-%s", class.name)), &schedulingv1.PriorityClassArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Name: pulumi.String(class.name),
+			},
+			Value:         pulumi.Int(class.value),
+			GlobalDefault: pulumi.Bool(class.globalDefault),
+			Description:   pulumi.String(class.description),
+		}, classOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return priorityClass, nil
+}
+
+// returns helm values that set the priority class at each of the given
+// dotted values paths, i.e. "controller" for "controller.priorityClassName".
+// nil is returned when the priority classes are not managed
+func priorityClassValues(k8sConfig K8sPlatformConfigInput, priorityClass string, paths ...string) pulumi.Map {
+	if !k8sConfig.Resilience.PriorityClasses {
+		return nil
+	}
+	values := pulumi.Map{}
+	for _, path := range paths {
+		var keys []string
+		if path != "" {
+			keys = strings.Split(path, ".")
+		}
+		setHelmValue(values, append(keys, "priorityClassName"), pulumi.String(priorityClass))
+	}
+	return values
+}
+
+// a pod disruption budget of a platform service
+type platformPodDisruptionBudget struct {
+	name      string
+	namespace string
+	labels    map[string]string
+}
+
+// creates pod disruption budgets that allow one unavailable pod of each
+// platform service, so that drains keep the rest of its replicas running
+// without blocking on single replica services
+func deployPodDisruptionBudgets(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.Resilience.PodDisruptionBudgets {
+		return nil, nil
+	}
+
+	for _, budget := range platformPodDisruptionBudgets(bootstrap.K8sConfig) {
+		_, err := policyv1.NewPodDisruptionBudget(ctx, bootstrap.ResourceName(fmt.Sprintf("pdb-%s", budget.name)), &policyv1.PodDisruptionBudgetArgs{
+			Metadata: &metav1.ObjectMetaArgs{
+				Name:      pulumi.String(budget.name),
+				Namespace: pulumi.String(budget.namespace),
+			},
+			Spec: &policyv1.PodDisruptionBudgetSpecArgs{
+				MaxUnavailable: pulumi.Int(1),
+				Selector: &metav1.LabelSelectorArgs{
+					MatchLabels: pulumi.ToStringMap(budget.labels),
+				},
+			},
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// returns the pod disruption budgets of the deployed platform services
+func platformPodDisruptionBudgets(k8sConfig K8sPlatformConfigInput) []platformPodDisruptionBudget {
+	var budgets []platformPodDisruptionBudget
+	if bootstrapComponentEnabled(k8sConfig, "argo-cd") && !fluxEnabled(k8sConfig) {
+		for _, component := range []string{"argocd-application-controller", "argocd-server", "argocd-repo-server"} {
+			budgets = append(budgets, platformPodDisruptionBudget{component, "argo-cd", map[string]string{
+				"app.kubernetes.io/name":     component,
+				"app.kubernetes.io/instance": "argo-cd",
+			}})
+		}
+	}
+	if bootstrapComponentEnabled(k8sConfig, "kube-prometheus-stack") {
+		budgets = append(budgets, platformPodDisruptionBudget{"prometheus", kubePrometheusStackNamespace, map[string]string{
+			"prometheus": "kube-prometheus-stack-prometheus",
+		}})
+	}
+	ingressConfig := k8sConfig.IngressController
+	if ingressConfig.Enabled && bootstrapComponentEnabled(k8sConfig, "ingress-controller") {
+		if ingressConfig.Controller == IngressControllerTraefik {
+			budgets = append(budgets, platformPodDisruptionBudget{"traefik", "traefik", map[string]string{
+				"app.kubernetes.io/name": "traefik",
+			}})
+		} else {
+			budgets = append(budgets, platformPodDisruptionBudget{"ingress-nginx-controller", "ingress-nginx", map[string]string{
+				"app.kubernetes.io/name":      "ingress-nginx",
+				"app.kubernetes.io/instance":  "ingress-nginx",
+				"app.kubernetes.io/component": "controller",
+			}})
+		}
+	}
+	return budgets
+}
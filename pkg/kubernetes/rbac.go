@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/joomcode/errorx"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	rbacv1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/rbac/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type RbacGroupConfigInput struct {
+	// kubernetes group of the identities, as mapped by the aws-auth configmap
+	// permission groups, i.e. "platform-admins"
+	Group string `json:"group" validate:"required"`
+
+	// cluster role granted to the group, i.e. cluster-admin, admin, edit, or
+	// view
+	ClusterRole string `json:"cluster-role" validate:"required"`
+
+	// optional namespaces the role is granted in, the role is granted cluster
+	// wide when not supplied
+	Namespaces []string `json:"namespaces"`
+}
+
+// SyncRbacBindings grants cluster roles to kubernetes groups, i.e. the permission groups of the aws-auth configmap, so
+// that IAM identities get in-cluster permissions. Groups are bound with a ClusterRoleBinding, or with a RoleBinding in
+// each of the given namespaces.
+func SyncRbacBindings(ctx *pulumi.Context, groups []RbacGroupConfigInput, opts ...pulumi.ResourceOption) error {
+	return syncRbacBindings(ctx, "", groups, opts...)
+}
+
+// grants the cluster roles of the k8s rbac config, after the namespaces
+// component so that managed namespaces exist
+func deployRbac(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	return nil, syncRbacBindings(ctx, bootstrap.Cluster.Name, bootstrap.K8sConfig.Rbac, opts...)
+}
+
+func syncRbacBindings(ctx *pulumi.Context, resourcePrefix string, groups []RbacGroupConfigInput, opts ...pulumi.ResourceOption) error {
+	for _, group := range groups {
+		if group.Group == "" || group.ClusterRole == "" {
+			return errorx.IllegalArgument.New("rbac bindings require a group and a cluster role")
+		}
+		bindingName := fmt.Sprintf("%s-%s", group.Group, group.ClusterRole)
+		roleRef := rbacv1.RoleRefArgs{
+			ApiGroup: pulumi.String("rbac.authorization.k8s.io"),
+			Kind:     pulumi.String("ClusterRole"),
+			Name:     pulumi.String(group.ClusterRole),
+		}
+		subjects := rbacv1.SubjectArray{
+			rbacv1.SubjectArgs{
+				ApiGroup: pulumi.String("rbac.authorization.k8s.io"),
+				Kind:     pulumi.String("Group"),
+				Name:     pulumi.String(group.Group),
+			},
+		}
+
+		if len(group.Namespaces) == 0 {
+			_, err := rbacv1.NewClusterRoleBinding(ctx, utils.PrefixedName(resourcePrefix, fmt.Sprintf("rbac-%s", bindingName)), &rbacv1.ClusterRoleBindingArgs{
+				Metadata: &metav1.ObjectMetaArgs{
+					Name: pulumi.String(bindingName),
+				},
+				RoleRef:  roleRef,
+				Subjects: subjects,
+			}, opts...)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, namespace := range group.Namespaces {
+			_, err := rbacv1.NewRoleBinding(ctx, utils.PrefixedName(resourcePrefix, fmt.Sprintf("rbac-%s-%s", namespace, bindingName)), &rbacv1.RoleBindingArgs{
+				Metadata: &metav1.ObjectMetaArgs{
+					Name:      pulumi.String(bindingName),
+					Namespace: pulumi.String(namespace),
+				},
+				RoleRef:  roleRef,
+				Subjects: subjects,
+			}, opts...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	moduleconfig "github.com/catalystcommunity/pulumi-modules-go/pkg/config"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/security"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type FalcoConfigInput struct {
+	// optional, installs falco with the security baseline, which alerts on
+	// suspicious syscalls of the cluster's containers. requires
+	// manage-security-baseline of the k8s config, validation fails without it
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+	// optional driver, one of "modern-bpf", "ebpf", or "module", defaults to
+	// the chart's default
+	Driver string `json:"driver" validate:"oneof=modern-bpf|ebpf|module"`
+}
+
+// enables guardduty and exports the audit logs of the cluster, requires an
+// additional configuration object if enabled
+func deploySecurityBaseline(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if !bootstrap.K8sConfig.ManageSecurityBaseline {
+		return nil, nil
+	}
+
+	var baselineConfig security.SecurityBaselineInput
+	if bootstrap.Cluster.SecurityBaseline != nil {
+		baselineConfig = *bootstrap.Cluster.SecurityBaseline
+	} else {
+		err := moduleconfig.GetObject(bootstrap.Config, "security-baseline", &baselineConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if baselineConfig.ResourcePrefix == "" {
+		baselineConfig.ResourcePrefix = bootstrap.Cluster.Name
+	}
+
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("security-baseline"), opts...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = security.SyncSecurityBaseline(ctx, baselineConfig, resourceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Done(ctx)
+}
+
+// installs falco as a daemonset on every node, as part of the security
+// baseline
+func deployFalco(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	falcoConfig := bootstrap.K8sConfig.Falco
+	if !falcoConfig.Enabled {
+		return nil, nil
+	}
+	if !bootstrap.K8sConfig.ManageSecurityBaseline {
+		return nil, errorx.IllegalArgument.New("falco is installed by the security baseline, enable manage-security-baseline")
+	}
+
+	values := pulumi.Map{
+		// run on tainted nodes too, i.e. dedicated node groups
+		"tolerations": pulumi.Array{
+			pulumi.Map{
+				"operator": pulumi.String("Exists"),
+			},
+		},
+	}
+	if falcoConfig.Driver != "" {
+		values["driver"] = pulumi.Map{
+			"kind": pulumi.String(falcoConfig.Driver),
+		}
+	}
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("falco"),
+		Name:         "falco",
+		Repo:         "https://falcosecurity.github.io/charts",
+		Version:      "3.3.0",
+		Values:       mergeHelmValues(values, priorityClassValues(bootstrap.K8sConfig, PriorityClassPlatformCritical, "")),
+		Config:       falcoConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
@@ -0,0 +1,293 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ServiceMeshConfigInput struct {
+	// optional, installs cilium as CNI
+	Cilium CiliumConfigInput `json:"cilium"`
+	// optional, installs istio
+	Istio IstioConfigInput `json:"istio"`
+
+	// cluster whose nodes get the security group rules that cilium and istio
+	// need, and optional security group of the nodes, defaults to the cluster
+	// security group
+	EKSClusterName      string `json:"eks-cluster-name"`
+	NodeSecurityGroupId string `json:"node-security-group-id"`
+}
+
+type CiliumConfigInput struct {
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+	// one of "chaining" (default), which adds cilium's network policies and
+	// observability to the vpc cni, or "eni", which replaces the vpc cni.
+	// in eni mode the aws-node daemonset and the vpc-cni addon must be
+	// removed, and existing nodes replaced
+	Mode string `json:"mode" validate:"oneof=chaining|eni"`
+}
+
+type IstioConfigInput struct {
+	Enabled bool `json:"enabled"`
+	// istiod release. its version, repository, and credentials apply to the
+	// base, cni, and ztunnel charts as well, so that all charts are pinned to
+	// one istio version
+	Helm HelmReleaseConfigInput `json:"helm-release"`
+	// one of "sidecar" (default) or "ambient", which installs the istio cni
+	// and ztunnel instead of injecting sidecars
+	Mode string `json:"mode" validate:"oneof=sidecar|ambient"`
+}
+
+// cilium modes
+const (
+	CiliumModeChaining = "chaining"
+	CiliumModeEni      = "eni"
+)
+
+// istio modes
+const (
+	IstioModeSidecar = "sidecar"
+	IstioModeAmbient = "ambient"
+)
+
+// installs cilium and istio, along with the node security group rules they
+// need
+func deployServiceMesh(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	meshConfig := bootstrap.K8sConfig.ServiceMesh
+	if !meshConfig.Cilium.Enabled && !meshConfig.Istio.Enabled {
+		return nil, nil
+	}
+
+	resource, resourceOpts, err := NewBootstrapComponentResource(ctx, bootstrap.ResourceName("service-mesh"), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the mesh's pods and webhooks need the node security group rules
+	meshOpts := resourceOpts
+	rules := serviceMeshSecurityGroupRules(meshConfig)
+	if len(rules) != 0 {
+		ruleResources, err := eks.SyncNodeSecurityGroupRules(ctx, eks.NodeSecurityGroupRulesInput{
+			EKSClusterName:      meshConfig.EKSClusterName,
+			NodeSecurityGroupId: meshConfig.NodeSecurityGroupId,
+			Rules:               rules,
+			AwsProvider:         bootstrap.Cluster.AwsProvider,
+			ResourcePrefix:      bootstrap.Cluster.Name,
+		}, resourceOpts...)
+		if err != nil {
+			return nil, err
+		}
+		meshOpts = append(append([]pulumi.ResourceOption{}, resourceOpts...), pulumi.DependsOn(ruleResources))
+	}
+
+	var cilium pulumi.Resource
+	if meshConfig.Cilium.Enabled {
+		cilium, err = deployCilium(ctx, bootstrap, meshConfig, meshOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if meshConfig.Istio.Enabled {
+		// istio pods need the cni to be ready
+		istioOpts := meshOpts
+		if cilium != nil {
+			istioOpts = append(append([]pulumi.ResourceOption{}, meshOpts...), pulumi.DependsOn([]pulumi.Resource{cilium}))
+		}
+		_, err = deployIstio(ctx, bootstrap, meshConfig.Istio, istioOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resource.Done(ctx)
+}
+
+// returns the ingress rules of the nodes for the enabled components
+func serviceMeshSecurityGroupRules(meshConfig ServiceMeshConfigInput) []eks.NodeSecurityGroupRuleInput {
+	var rules []eks.NodeSecurityGroupRuleInput
+	if meshConfig.Cilium.Enabled {
+		rules = append(rules, eks.NodeSecurityGroupRuleInput{
+			Name:        "cilium-health",
+			Description: "cilium health checks between nodes",
+			Port:        4240,
+		})
+	}
+	if meshConfig.Istio.Enabled {
+		rules = append(rules, eks.NodeSecurityGroupRuleInput{
+			Name:             "istiod-webhook",
+			Description:      "istiod admission webhooks called by the control plane",
+			Port:             15017,
+			FromControlPlane: true,
+		})
+		if meshConfig.Istio.Mode == IstioModeAmbient {
+			rules = append(rules, eks.NodeSecurityGroupRuleInput{
+				Name:        "istio-hbone",
+				Description: "istio ambient HBONE tunnels between ztunnels",
+				Port:        15008,
+			})
+		}
+	}
+	return rules
+}
+
+// installs cilium in kube-system, chained to the vpc cni or in eni mode
+// with an IRSA role that manages the network interfaces
+func deployCilium(ctx *pulumi.Context, bootstrap *BootstrapContext, meshConfig ServiceMeshConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	ciliumConfig := meshConfig.Cilium
+	var values pulumi.Map
+	switch ciliumConfig.Mode {
+	case "", CiliumModeChaining:
+		values = pulumi.Map{
+			"cni": pulumi.Map{
+				"chainingMode": pulumi.String("aws-cni"),
+				"exclusive":    pulumi.Bool(false),
+			},
+			"enableIPv4Masquerade": pulumi.Bool(false),
+			"tunnel":               pulumi.String("disabled"),
+			"endpointRoutes": pulumi.Map{
+				"enabled": pulumi.Bool(true),
+			},
+		}
+	case CiliumModeEni:
+		if meshConfig.EKSClusterName == "" {
+			return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create cilium IRSA role")
+		}
+		role, err := eks.NewIrsaRole(ctx, bootstrap.ResourceName("cilium-operator-role"), eks.IrsaRoleInput{
+			Name:           fmt.Sprintf("CiliumOperatorRole-%s", meshConfig.EKSClusterName),
+			EKSClusterName: meshConfig.EKSClusterName,
+			Namespace:      "kube-system",
+			ServiceAccount: "cilium-operator",
+			PolicyArns:     []string{"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy"},
+			AwsProvider:    bootstrap.Cluster.AwsProvider,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		values = pulumi.Map{
+			"eni": pulumi.Map{
+				"enabled": pulumi.Bool(true),
+			},
+			"ipam": pulumi.Map{
+				"mode": pulumi.String("eni"),
+			},
+			"egressMasqueradeInterfaces": pulumi.String("eth0"),
+			"tunnel":                     pulumi.String("disabled"),
+			"serviceAccounts": pulumi.Map{
+				"operator": pulumi.Map{
+					"annotations": pulumi.Map{
+						"eks.amazonaws.com/role-arn": role.Arn,
+					},
+				},
+			},
+		}
+	default:
+		return nil, errorx.IllegalArgument.New("unknown cilium mode: '%s'", ciliumConfig.Mode)
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("cilium"),
+		Name:                "cilium",
+		Namespace:           "kube-system",
+		SkipCreateNamespace: true,
+		Repo:                "https://helm.cilium.io",
+		Version:             "1.12.5",
+		Values:              values,
+		Config:              ciliumConfig.Helm,
+		PulumiConfig:        bootstrap.Config,
+	}, opts...)
+}
+
+// installs the istio base chart and istiod, and the istio cni and ztunnel
+// in ambient mode
+func deployIstio(ctx *pulumi.Context, bootstrap *BootstrapContext, istioConfig IstioConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	ambient := false
+	switch istioConfig.Mode {
+	case "", IstioModeSidecar:
+	case IstioModeAmbient:
+		ambient = true
+	default:
+		return nil, errorx.IllegalArgument.New("unknown istio mode: '%s'", istioConfig.Mode)
+	}
+	chartConfig := istioChartConfig(istioConfig.Helm)
+
+	// the base chart installs the CRDs of istiod
+	base, err := DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("istio-base"),
+		Name:         "istio-base",
+		Namespace:    "istio-system",
+		Repo:         "https://istio-release.storage.googleapis.com/charts",
+		Chart:        "base",
+		Version:      "1.18.2",
+		Config:       chartConfig,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	baseOpts := append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{base}))
+
+	istiodValues := pulumi.Map{}
+	if ambient {
+		istiodValues["profile"] = pulumi.String("ambient")
+	}
+	istiod, err := DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("istiod"),
+		Name:                "istiod",
+		Namespace:           "istio-system",
+		SkipCreateNamespace: true,
+		Repo:                "https://istio-release.storage.googleapis.com/charts",
+		Version:             "1.18.2",
+		Values:              istiodValues,
+		Config:              istioConfig.Helm,
+		PulumiConfig:        bootstrap.Config,
+	}, baseOpts...)
+	if err != nil || !ambient {
+		return istiod, err
+	}
+
+	_, err = DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("istio-cni"),
+		Name:                "istio-cni",
+		Namespace:           "istio-system",
+		SkipCreateNamespace: true,
+		Repo:                "https://istio-release.storage.googleapis.com/charts",
+		Chart:               "cni",
+		Version:             "1.18.2",
+		Values: pulumi.Map{
+			"profile": pulumi.String("ambient"),
+		},
+		Config:       chartConfig,
+		PulumiConfig: bootstrap.Config,
+	}, baseOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName:        bootstrap.ResourceName("ztunnel"),
+		Name:                "ztunnel",
+		Namespace:           "istio-system",
+		SkipCreateNamespace: true,
+		Repo:                "https://istio-release.storage.googleapis.com/charts",
+		Version:             "1.18.2",
+		Config:              chartConfig,
+		PulumiConfig:        bootstrap.Config,
+	}, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{istiod}))...)
+}
+
+// returns the config of the istio charts other than istiod, sharing the
+// version and chart repository of the istiod release but not its values
+func istioChartConfig(istiodConfig HelmReleaseConfigInput) HelmReleaseConfigInput {
+	return HelmReleaseConfigInput{
+		Version:               istiodConfig.Version,
+		Repo:                  istiodConfig.Repo,
+		RepoUsernameSecretKey: istiodConfig.RepoUsernameSecretKey,
+		RepoPasswordSecretKey: istiodConfig.RepoPasswordSecretKey,
+		RepoEcrAuth:           istiodConfig.RepoEcrAuth,
+		Timeout:               istiodConfig.Timeout,
+		Atomic:                istiodConfig.Atomic,
+		CleanupOnFail:         istiodConfig.CleanupOnFail,
+		SkipAwait:             istiodConfig.SkipAwait,
+	}
+}
@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/joomcode/errorx"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/meta/v1"
+	storagev1 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/storage/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type StorageConfigInput struct {
+	// optional, manages storage classes, and creates a default encrypted gp3
+	// class when no storage classes are configured
+	Enabled        bool                      `json:"enabled"`
+	StorageClasses []StorageClassConfigInput `json:"storage-classes"`
+
+	// optional, creates an EFS filesystem that efs storage classes provision
+	// volumes on. the EFS CSI driver must be installed, i.e. as an eks addon
+	ManageEfs bool         `json:"manage-efs"`
+	Efs       eks.EfsInput `json:"efs"`
+}
+
+type StorageClassConfigInput struct {
+	Name string `json:"name" validate:"required"`
+	// one of "ebs" (default) or "efs"
+	Type string `json:"type" validate:"oneof=ebs|efs"`
+	// optional, annotates the class as the cluster default. the default gp2
+	// class of EKS clusters must be unmarked separately
+	Default bool `json:"default"`
+
+	// optional provisioner parameters, merged over the defaults of the type,
+	// i.e. type gp3 and encrypted for ebs
+	Parameters map[string]string `json:"parameters"`
+	// optional, defaults to Delete
+	ReclaimPolicy string `json:"reclaim-policy"`
+	// optional, defaults to WaitForFirstConsumer
+	VolumeBindingMode string `json:"volume-binding-mode"`
+	// optional, defaults to true
+	AllowVolumeExpansion *bool `json:"allow-volume-expansion"`
+}
+
+// storage class types
+const (
+	StorageClassTypeEbs = "ebs"
+	StorageClassTypeEfs = "efs"
+)
+
+// creates the configured EFS filesystem and storage classes
+func deployStorage(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	storageConfig := bootstrap.K8sConfig.Storage
+	if !storageConfig.Enabled {
+		return nil, nil
+	}
+
+	var fileSystemId pulumi.StringOutput
+	if storageConfig.ManageEfs {
+		efsConfig := storageConfig.Efs
+		if efsConfig.ResourcePrefix == "" {
+			efsConfig.ResourcePrefix = bootstrap.Cluster.Name
+		}
+		fileSystem, err := eks.SyncEfsFilesystem(ctx, efsConfig, opts...)
+		if err != nil {
+			return nil, err
+		}
+		fileSystemId = fileSystem.ID().ToStringOutput()
+	}
+
+	storageClasses := storageConfig.StorageClasses
+	if len(storageClasses) == 0 {
+		storageClasses = []StorageClassConfigInput{{Name: "gp3", Default: true}}
+	}
+	for _, storageClass := range storageClasses {
+		_, err := newStorageClass(ctx, bootstrap, storageClass, fileSystemId, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func newStorageClass(ctx *pulumi.Context, bootstrap *BootstrapContext, storageClass StorageClassConfigInput, fileSystemId pulumi.StringOutput, opts ...pulumi.ResourceOption) (*storagev1.StorageClass, error) {
+	if storageClass.Name == "" {
+		return nil, errorx.IllegalArgument.New("storage class name not supplied")
+	}
+
+	var provisioner string
+	parameters := pulumi.StringMap{}
+	switch storageClass.Type {
+	case "", StorageClassTypeEbs:
+		provisioner = "ebs.csi.aws.com"
+		parameters["type"] = pulumi.String("gp3")
+		parameters["encrypted"] = pulumi.String("true")
+	case StorageClassTypeEfs:
+		if fileSystemId.OutputState == nil {
+			return nil, errorx.IllegalArgument.New("efs storage class %s requires manage-efs", storageClass.Name)
+		}
+		provisioner = "efs.csi.aws.com"
+		parameters["provisioningMode"] = pulumi.String("efs-ap")
+		parameters["fileSystemId"] = fileSystemId
+		parameters["directoryPerms"] = pulumi.String("700")
+	default:
+		return nil, errorx.IllegalArgument.New("unknown type of storage class %s: '%s'", storageClass.Name, storageClass.Type)
+	}
+	for key, value := range storageClass.Parameters {
+		parameters[key] = pulumi.String(value)
+	}
+
+	reclaimPolicy := "Delete"
+	if storageClass.ReclaimPolicy != "" {
+		reclaimPolicy = storageClass.ReclaimPolicy
+	}
+	volumeBindingMode := "WaitForFirstConsumer"
+	if storageClass.VolumeBindingMode != "" {
+		volumeBindingMode = storageClass.VolumeBindingMode
+	}
+	allowVolumeExpansion := true
+	if storageClass.AllowVolumeExpansion != nil {
+		allowVolumeExpansion = *storageClass.AllowVolumeExpansion
+	}
+	annotations := pulumi.StringMap{}
+	if storageClass.Default {
+		annotations["storageclass.kubernetes.io/is-default-class"] = pulumi.String("true")
+	}
+
+	return storagev1.NewStorageClass(ctx, bootstrap.ResourceName("storage-class-"+storageClass.Name), &storagev1.StorageClassArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:        pulumi.String(storageClass.Name),
+			Annotations: annotations,
+		},
+		Provisioner:          pulumi.String(provisioner),
+		Parameters:           parameters,
+		ReclaimPolicy:        pulumi.String(reclaimPolicy),
+		VolumeBindingMode:    pulumi.String(volumeBindingMode),
+		AllowVolumeExpansion: pulumi.Bool(allowVolumeExpansion),
+	}, opts...)
+}
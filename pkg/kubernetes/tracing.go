@@ -0,0 +1,296 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+type TracingConfigInput struct {
+	// optional, installs a tracing backend
+	Enabled bool `json:"enabled"`
+	// one of "tempo" (default) or "jaeger"
+	Backend string            `json:"backend" validate:"oneof=tempo|jaeger"`
+	Tempo   TempoConfigInput  `json:"tempo"`
+	Jaeger  JaegerConfigInput `json:"jaeger"`
+	// optional, installs an opentelemetry collector that exports to the
+	// backend and the configured exporters
+	OpenTelemetryCollector OpenTelemetryCollectorConfigInput `json:"opentelemetry-collector"`
+}
+
+type TempoConfigInput struct {
+	Helm HelmReleaseConfigInput `json:"helm-release"`
+
+	// existing bucket that tempo stores traces in, and optional region,
+	// defaults to the aws:region config
+	S3Bucket string `json:"s3-bucket"`
+	Region   string `json:"region"`
+	// cluster whose OIDC provider is trusted by the tempo IRSA role
+	EKSClusterName string `json:"eks-cluster-name"`
+}
+
+type JaegerConfigInput struct {
+	Helm HelmReleaseConfigInput `json:"helm-release"`
+}
+
+type OpenTelemetryCollectorConfigInput struct {
+	Enabled bool                   `json:"enabled"`
+	Helm    HelmReleaseConfigInput `json:"helm-release"`
+	// one of "deployment" (default) or "daemonset"
+	Mode string `json:"mode" validate:"oneof=deployment|daemonset"`
+	// optional, additional exporters of the traces pipeline
+	Exporters []OpenTelemetryExporterConfigInput `json:"exporters"`
+	// optional, does not export to the tracing backend, i.e. when only
+	// exporting to a vendor
+	SkipBackendExporter bool `json:"skip-backend-exporter"`
+}
+
+type OpenTelemetryExporterConfigInput struct {
+	Name string `json:"name" validate:"required"`
+	// one of "otlp" (grpc, default) or "otlphttp"
+	Type     string `json:"type" validate:"oneof=otlp|otlphttp"`
+	Endpoint string `json:"endpoint" validate:"required"`
+	// optional, disables tls
+	Insecure bool `json:"insecure"`
+	// optional headers, and headers whose values reference pulumi config
+	// secrets by name, i.e. api keys of vendors
+	Headers       map[string]string `json:"headers"`
+	SecretHeaders map[string]string `json:"secret-headers"`
+}
+
+// tracing backends
+const (
+	TracingBackendTempo  = "tempo"
+	TracingBackendJaeger = "jaeger"
+)
+
+// opentelemetry exporter types
+const (
+	OpenTelemetryExporterOtlp     = "otlp"
+	OpenTelemetryExporterOtlpHttp = "otlphttp"
+)
+
+// installs the tracing backend and optionally an opentelemetry collector
+// exporting to it
+func deployTracing(ctx *pulumi.Context, bootstrap *BootstrapContext, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	tracingConfig := bootstrap.K8sConfig.Tracing
+	if !tracingConfig.Enabled {
+		return nil, nil
+	}
+
+	var backend pulumi.Resource
+	var backendName, backendEndpoint string
+	var err error
+	switch tracingConfig.Backend {
+	case "", TracingBackendTempo:
+		backend, err = deployTempo(ctx, bootstrap, tracingConfig.Tempo, opts...)
+		backendName, backendEndpoint = TracingBackendTempo, "tempo.tempo.svc.cluster.local:4317"
+	case TracingBackendJaeger:
+		backend, err = deployJaeger(ctx, bootstrap, tracingConfig.Jaeger, opts...)
+		backendName, backendEndpoint = TracingBackendJaeger, "jaeger-collector.jaeger.svc.cluster.local:4317"
+	default:
+		return nil, errorx.IllegalArgument.New("unknown tracing backend: '%s'", tracingConfig.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	collectorConfig := tracingConfig.OpenTelemetryCollector
+	if !collectorConfig.Enabled {
+		return backend, nil
+	}
+	exporters := collectorConfig.Exporters
+	if !collectorConfig.SkipBackendExporter {
+		exporters = append([]OpenTelemetryExporterConfigInput{{
+			Name:     backendName,
+			Endpoint: backendEndpoint,
+			Insecure: true,
+		}}, exporters...)
+	}
+	return deployOpenTelemetryCollector(ctx, bootstrap, collectorConfig, exporters, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{backend}))...)
+}
+
+// installs tempo in single binary mode, storing traces in s3 with an IRSA role
+func deployTempo(ctx *pulumi.Context, bootstrap *BootstrapContext, tempoConfig TempoConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if tempoConfig.S3Bucket == "" {
+		return nil, errorx.IllegalArgument.New("s3 bucket not supplied, cannot install tempo")
+	}
+	region := tempoConfig.Region
+	if region == "" {
+		region = config.New(ctx, "aws").Require("region")
+	}
+	role, err := newTempoRole(ctx, bootstrap, tempoConfig, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("tempo"),
+		Name:         "tempo",
+		Repo:         "https://grafana.github.io/helm-charts",
+		Version:      "1.7.1",
+		Values: pulumi.Map{
+			"tempo": pulumi.Map{
+				"storage": pulumi.Map{
+					"trace": pulumi.Map{
+						"backend": pulumi.String("s3"),
+						"s3": pulumi.Map{
+							"bucket":   pulumi.String(tempoConfig.S3Bucket),
+							"endpoint": pulumi.String(fmt.Sprintf("s3.%s.amazonaws.com", region)),
+							"region":   pulumi.String(region),
+						},
+					},
+				},
+			},
+			"serviceAccount": pulumi.Map{
+				"name": pulumi.String("tempo"),
+				"annotations": pulumi.Map{
+					"eks.amazonaws.com/role-arn": role.Arn,
+				},
+			},
+		},
+		Config:       tempoConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
+
+// creates the tempo IRSA role, allowed to read and write the trace bucket
+func newTempoRole(ctx *pulumi.Context, bootstrap *BootstrapContext, tempoConfig TempoConfigInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if tempoConfig.EKSClusterName == "" {
+		return nil, errorx.IllegalArgument.New("EKS cluster name not supplied, cannot create tempo IRSA role")
+	}
+	policy, err := iampolicy.NewDocument(
+		iampolicy.Allow("s3:ListBucket").On(fmt.Sprintf("arn:aws:s3:::%s", tempoConfig.S3Bucket)),
+		iampolicy.Allow(
+			"s3:PutObject",
+			"s3:GetObject",
+			"s3:DeleteObject",
+			"s3:GetObjectTagging",
+			"s3:PutObjectTagging",
+		).On(fmt.Sprintf("arn:aws:s3:::%s/*", tempoConfig.S3Bucket)),
+	).Json()
+	if err != nil {
+		return nil, err
+	}
+
+	return eks.NewIrsaRole(ctx, bootstrap.ResourceName("tempo-role"), eks.IrsaRoleInput{
+		Name:           fmt.Sprintf("TempoRole-%s", tempoConfig.EKSClusterName),
+		EKSClusterName: tempoConfig.EKSClusterName,
+		Namespace:      "tempo",
+		ServiceAccount: "tempo",
+		InlinePolicy:   policy,
+		AwsProvider:    bootstrap.Cluster.AwsProvider,
+	}, opts...)
+}
+
+// installs jaeger all-in-one with in-memory storage, accepting otlp
+func deployJaeger(ctx *pulumi.Context, bootstrap *BootstrapContext, jaegerConfig JaegerConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("jaeger"),
+		Name:         "jaeger",
+		Repo:         "https://jaegertracing.github.io/helm-charts",
+		Version:      "0.71.11",
+		Values: pulumi.Map{
+			"provisionDataStore": pulumi.Map{
+				"cassandra": pulumi.Bool(false),
+			},
+			"storage": pulumi.Map{
+				"type": pulumi.String("none"),
+			},
+			"allInOne": pulumi.Map{
+				"enabled": pulumi.Bool(true),
+				"extraEnv": pulumi.Array{
+					pulumi.Map{
+						"name":  pulumi.String("COLLECTOR_OTLP_ENABLED"),
+						"value": pulumi.String("true"),
+					},
+				},
+			},
+			"agent": pulumi.Map{
+				"enabled": pulumi.Bool(false),
+			},
+			"collector": pulumi.Map{
+				"enabled": pulumi.Bool(false),
+			},
+			"query": pulumi.Map{
+				"enabled": pulumi.Bool(false),
+			},
+		},
+		Config:       jaegerConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
+
+// installs the opentelemetry collector with a traces pipeline from the otlp
+// receiver to the given exporters
+func deployOpenTelemetryCollector(ctx *pulumi.Context, bootstrap *BootstrapContext, collectorConfig OpenTelemetryCollectorConfigInput, exporters []OpenTelemetryExporterConfigInput, opts ...pulumi.ResourceOption) (pulumi.Resource, error) {
+	if len(exporters) == 0 {
+		return nil, errorx.IllegalArgument.New("opentelemetry collector requires at least one exporter")
+	}
+	mode := "deployment"
+	if collectorConfig.Mode != "" {
+		mode = collectorConfig.Mode
+	}
+
+	exporterValues := pulumi.Map{}
+	var exporterNames []string
+	for _, exporter := range exporters {
+		if exporter.Name == "" || exporter.Endpoint == "" {
+			return nil, errorx.IllegalArgument.New("opentelemetry exporter name or endpoint not supplied")
+		}
+		exporterType := OpenTelemetryExporterOtlp
+		if exporter.Type != "" {
+			exporterType = exporter.Type
+		}
+		if exporterType != OpenTelemetryExporterOtlp && exporterType != OpenTelemetryExporterOtlpHttp {
+			return nil, errorx.IllegalArgument.New("unknown type of opentelemetry exporter %s: '%s'", exporter.Name, exporter.Type)
+		}
+
+		headers := pulumi.Map{}
+		for header, value := range exporter.Headers {
+			headers[header] = pulumi.String(value)
+		}
+		for header, secretKey := range exporter.SecretHeaders {
+			headers[header] = bootstrap.Config.RequireSecret(secretKey)
+		}
+		exporterValue := pulumi.Map{
+			"endpoint": pulumi.String(exporter.Endpoint),
+			"tls": pulumi.Map{
+				"insecure": pulumi.Bool(exporter.Insecure),
+			},
+		}
+		if len(headers) != 0 {
+			exporterValue["headers"] = headers
+		}
+
+		name := fmt.Sprintf("%s/%s", exporterType, exporter.Name)
+		exporterValues[name] = exporterValue
+		exporterNames = append(exporterNames, name)
+	}
+
+	return DeployHelmRelease(ctx, HelmReleaseSpec{
+		ResourceName: bootstrap.ResourceName("opentelemetry-collector"),
+		Name:         "opentelemetry-collector",
+		Repo:         "https://open-telemetry.github.io/opentelemetry-helm-charts",
+		Version:      "0.62.0",
+		Values: pulumi.Map{
+			"mode": pulumi.String(mode),
+			"config": pulumi.Map{
+				"exporters": exporterValues,
+				"service": pulumi.Map{
+					"pipelines": pulumi.Map{
+						"traces": pulumi.Map{
+							"exporters": pulumi.ToStringArray(exporterNames),
+						},
+					},
+				},
+			},
+		},
+		Config:       collectorConfig.Helm,
+		PulumiConfig: bootstrap.Config,
+	}, opts...)
+}
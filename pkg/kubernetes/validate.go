@@ -0,0 +1,239 @@
+package kubernetes
+
+import (
+	"fmt"
+	moduleconfig "github.com/catalystcommunity/pulumi-modules-go/pkg/config"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/cost"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/security"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValidateBootstrapConfig checks the stack's bootstrap config without creating resources, so that misconfigurations
+// fail fast in CI: the k8s, eks-auth, eks-addons, and karpenter config objects and the component graph are validated,
+// referenced values files must exist, referenced pulumi config secrets must be set, and pinned chart versions must
+// exist in their repository. All problems are returned in one error.
+func ValidateBootstrapConfig(ctx *pulumi.Context) error {
+	cfg := config.New(ctx, "")
+	var failures []string
+	fail := func(err error) {
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	var k8sConfig K8sPlatformConfigInput
+	err := moduleconfig.GetObject(cfg, "k8s", &k8sConfig)
+	if err != nil {
+		return err
+	}
+	if k8sConfig.ManageEksAuthConfigMap {
+		fail(moduleconfig.GetObject(cfg, "eks-auth", &eks.AuthConfigMapInput{}))
+	}
+	if k8sConfig.ManageEksAddons {
+		fail(moduleconfig.GetObject(cfg, "eks-addons", &eks.AddonsInput{}))
+	}
+	if k8sConfig.ManageKarpenter {
+		fail(moduleconfig.GetObject(cfg, "karpenter", &eks.KarpenterInput{}))
+	}
+	if k8sConfig.ManageSecurityBaseline {
+		fail(moduleconfig.GetObject(cfg, "security-baseline", &security.SecurityBaselineInput{}))
+	} else if k8sConfig.Falco.Enabled {
+		fail(fmt.Errorf("falco requires manage-security-baseline"))
+	}
+	if k8sConfig.ManageCostBudgets {
+		fail(moduleconfig.GetObject(cfg, "cost-budgets", &cost.BudgetsInput{}))
+	}
+
+	// the components config must name registered components without
+	// circular dependencies
+	_, _, err = orderBootstrapComponents(bootstrapComponents, k8sConfig.Components)
+	fail(err)
+
+	releases := validatedHelmReleases(k8sConfig)
+	for _, release := range releases {
+		valuesFiles := release.config.ValuesFiles
+		if len(valuesFiles) == 0 {
+			valuesFiles = release.defaultValuesFiles
+		}
+		for _, valuesFile := range valuesFiles {
+			if _, err := os.Stat(valuesFile); err != nil {
+				fail(fmt.Errorf("values file %s of %s: %v", valuesFile, release.name, err))
+			}
+		}
+	}
+
+	// dashboards must set one source and their files must exist
+	for _, dashboard := range k8sConfig.Grafana.Dashboards {
+		_, err := grafanaDashboardJson(dashboard)
+		fail(err)
+	}
+
+	secretKeys := bootstrapSecretKeys(k8sConfig)
+	for _, secretKey := range secretKeys {
+		if _, err := cfg.Try(secretKey); err != nil {
+			fail(fmt.Errorf("pulumi config secret %s is not set", secretKey))
+		}
+	}
+
+	for _, release := range releases {
+		fail(validateHelmChartVersion(release))
+	}
+
+	if len(failures) != 0 {
+		return errorx.IllegalArgument.New("invalid bootstrap config:\n  - %s", strings.Join(failures, "\n  - "))
+	}
+	return nil
+}
+
+// a helm release of the bootstrap and its default chart repository
+type validatedHelmRelease struct {
+	name               string
+	defaultRepo        string
+	defaultValuesFiles []string
+	config             HelmReleaseConfigInput
+}
+
+// returns the helm releases of the enabled bootstrap components
+func validatedHelmReleases(k8sConfig K8sPlatformConfigInput) []validatedHelmRelease {
+	var releases []validatedHelmRelease
+	optional := []struct {
+		enabled bool
+		release validatedHelmRelease
+	}{
+		{bootstrapComponentEnabled(k8sConfig, "argo-cd") && !fluxEnabled(k8sConfig), validatedHelmRelease{"argo-cd", "https://argoproj.github.io/argo-helm", []string{"./helm-values/argo-cd-values.yaml"}, k8sConfig.ArgocdHelm}},
+		{bootstrapComponentEnabled(k8sConfig, "kube-prometheus-stack"), validatedHelmRelease{"kube-prometheus-stack", "https://prometheus-community.github.io/helm-charts", []string{"./helm-values/prometheus-values.yaml"}, k8sConfig.KubePrometheusStackHelm}},
+		{fluxEnabled(k8sConfig), validatedHelmRelease{"flux2", "https://fluxcd-community.github.io/helm-charts", nil, k8sConfig.Gitops.Flux.Helm}},
+		{k8sConfig.PrometheusOperatorCrds.Enabled, validatedHelmRelease{"prometheus-operator-crds", "https://prometheus-community.github.io/helm-charts", nil, k8sConfig.PrometheusOperatorCrds.Helm}},
+		{k8sConfig.ExternalSecrets.Enabled, validatedHelmRelease{"external-secrets", "https://charts.external-secrets.io", nil, k8sConfig.ExternalSecrets.Helm}},
+		{k8sConfig.CertManager.Enabled, validatedHelmRelease{"cert-manager", "https://charts.jetstack.io", nil, k8sConfig.CertManager.Helm}},
+		{k8sConfig.ExternalDns.Enabled, validatedHelmRelease{"external-dns", "https://kubernetes-sigs.github.io/external-dns", nil, k8sConfig.ExternalDns.Helm}},
+		{k8sConfig.LoadBalancerController.Enabled, validatedHelmRelease{"aws-load-balancer-controller", "https://aws.github.io/eks-charts", nil, k8sConfig.LoadBalancerController.Helm}},
+		{k8sConfig.ClusterAutoscaler.Enabled, validatedHelmRelease{"cluster-autoscaler", "https://kubernetes.github.io/autoscaler", nil, k8sConfig.ClusterAutoscaler.Helm}},
+		{k8sConfig.MetricsServer.Enabled, validatedHelmRelease{"metrics-server", "https://kubernetes-sigs.github.io/metrics-server", nil, k8sConfig.MetricsServer.Helm}},
+		{k8sConfig.VerticalPodAutoscaler.Enabled, validatedHelmRelease{"vpa", "https://charts.fairwinds.com/stable", nil, k8sConfig.VerticalPodAutoscaler.Helm}},
+		{k8sConfig.Tracing.Enabled && k8sConfig.Tracing.Backend != TracingBackendJaeger, validatedHelmRelease{"tempo", "https://grafana.github.io/helm-charts", nil, k8sConfig.Tracing.Tempo.Helm}},
+		{k8sConfig.Tracing.Enabled && k8sConfig.Tracing.Backend == TracingBackendJaeger, validatedHelmRelease{"jaeger", "https://jaegertracing.github.io/helm-charts", nil, k8sConfig.Tracing.Jaeger.Helm}},
+		{k8sConfig.PolicyEngine.Enabled && k8sConfig.PolicyEngine.Engine != PolicyEngineGatekeeper, validatedHelmRelease{"kyverno", "https://kyverno.github.io/kyverno", nil, k8sConfig.PolicyEngine.Helm}},
+		{k8sConfig.PolicyEngine.Enabled && k8sConfig.PolicyEngine.Engine == PolicyEngineGatekeeper, validatedHelmRelease{"gatekeeper", "https://open-policy-agent.github.io/gatekeeper/charts", nil, k8sConfig.PolicyEngine.Helm}},
+		{k8sConfig.IngressController.Enabled && k8sConfig.IngressController.Controller != IngressControllerTraefik, validatedHelmRelease{"ingress-nginx", "https://kubernetes.github.io/ingress-nginx", nil, k8sConfig.IngressController.Helm}},
+		{k8sConfig.IngressController.Enabled && k8sConfig.IngressController.Controller == IngressControllerTraefik, validatedHelmRelease{"traefik", "https://traefik.github.io/charts", nil, k8sConfig.IngressController.Helm}},
+		{k8sConfig.ServiceMesh.Cilium.Enabled, validatedHelmRelease{"cilium", "https://helm.cilium.io", nil, k8sConfig.ServiceMesh.Cilium.Helm}},
+		{k8sConfig.Opencost.Enabled, validatedHelmRelease{"opencost", "https://opencost.github.io/opencost-helm-chart", nil, k8sConfig.Opencost.Helm}},
+		{k8sConfig.ManageSecurityBaseline && k8sConfig.Falco.Enabled, validatedHelmRelease{"falco", "https://falcosecurity.github.io/charts", nil, k8sConfig.Falco.Helm}},
+		{k8sConfig.ServiceMesh.Istio.Enabled, validatedHelmRelease{"istiod", "https://istio-release.storage.googleapis.com/charts", nil, k8sConfig.ServiceMesh.Istio.Helm}},
+		{k8sConfig.Tracing.Enabled && k8sConfig.Tracing.OpenTelemetryCollector.Enabled, validatedHelmRelease{"opentelemetry-collector", "https://open-telemetry.github.io/opentelemetry-helm-charts", nil, k8sConfig.Tracing.OpenTelemetryCollector.Helm}},
+	}
+	for _, release := range optional {
+		if release.enabled {
+			releases = append(releases, release.release)
+		}
+	}
+	return releases
+}
+
+// checks that a pinned chart version exists in the index of its repository,
+// or that a version range resolves. the module's default versions and oci
+// charts are not checked
+func validateHelmChartVersion(release validatedHelmRelease) error {
+	if release.config.Version == "" {
+		return nil
+	}
+	repo := release.defaultRepo
+	if release.config.Repo != "" {
+		repo = release.config.Repo
+	}
+	if strings.HasPrefix(repo, "oci://") {
+		if isHelmChartVersionRange(release.config.Version) {
+			return fmt.Errorf("chart version %s of %s cannot be resolved from oci registry %s", release.config.Version, release.name, repo)
+		}
+		return nil
+	}
+	chart := release.name
+	if release.config.Chart != "" {
+		chart = release.config.Chart
+	}
+
+	if isHelmChartVersionRange(release.config.Version) {
+		if !release.config.AllowFloatingVersion {
+			return fmt.Errorf("%s: %v", release.name, floatingHelmChartVersionError(chart, release.config.Version))
+		}
+		_, err := resolveHelmChartVersion(repo, chart, release.config.Version)
+		if err != nil {
+			return fmt.Errorf("%s: %v", release.name, err)
+		}
+		return nil
+	}
+	versions, err := fetchHelmChartVersions(repo, chart)
+	if err != nil {
+		return fmt.Errorf("%s: %v", release.name, err)
+	}
+	for _, version := range versions {
+		if version == release.config.Version {
+			return nil
+		}
+	}
+	return fmt.Errorf("chart %s version %s of %s not found in %s", chart, release.config.Version, release.name, repo)
+}
+
+// returns the names of the pulumi config secrets referenced by the config,
+// that is every field named *-secret-key and the values of secret-values
+// and secret-headers maps
+func bootstrapSecretKeys(k8sConfig K8sPlatformConfigInput) []string {
+	keys := map[string]bool{}
+	if k8sConfig.ManagePrometheusRemoteWriteBasicAuthSecret {
+		keys["prometheusRemoteWriteBasicAuthPassword"] = true
+	}
+	collectSecretKeys(reflect.ValueOf(k8sConfig), keys)
+
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func collectSecretKeys(value reflect.Value, keys map[string]bool) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			collectSecretKeys(value.Elem(), keys)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			collectSecretKeys(value.Index(i), keys)
+		}
+	case reflect.Struct:
+		// the settings of disabled features are not needed
+		if enabled := value.FieldByName("Enabled"); enabled.IsValid() && enabled.Kind() == reflect.Bool && !enabled.Bool() {
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			fieldValue := value.Field(i)
+			switch {
+			case fieldValue.Kind() == reflect.String && strings.HasSuffix(jsonName, "secret-key"):
+				if fieldValue.String() != "" {
+					keys[fieldValue.String()] = true
+				}
+			case fieldValue.Kind() == reflect.Map && (jsonName == "secret-values" || jsonName == "secret-headers"):
+				for _, key := range fieldValue.MapKeys() {
+					keys[fieldValue.MapIndex(key).String()] = true
+				}
+			default:
+				collectSecretKeys(fieldValue, keys)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/joomcode/errorx"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+// Logger writes module messages through pulumi's diagnostics, so that they are shown with the resource they concern
+// in the pulumi cli and service instead of on stderr.
+type Logger struct {
+	ctx          *pulumi.Context
+	module       string
+	resourceName string
+	debug        bool
+}
+
+// New returns the logger of a module, i.e. "kubernetes" or "eks". Debug messages are logged as info messages when the
+// debug-logging config of the project is true, and otherwise only shown by pulumi's --debug flag.
+func New(ctx *pulumi.Context, module string) *Logger {
+	return &Logger{
+		ctx:    ctx,
+		module: module,
+		debug:  config.New(ctx, "").GetBool("debug-logging"),
+	}
+}
+
+// WithResource returns a logger that names the given pulumi resource in its messages
+func (l *Logger) WithResource(resourceName string) *Logger {
+	logger := *l
+	logger.resourceName = resourceName
+	return &logger
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.debug {
+		_ = l.ctx.Log.Info(l.format(format, args...), nil)
+	} else {
+		_ = l.ctx.Log.Debug(l.format(format, args...), nil)
+	}
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	_ = l.ctx.Log.Info(l.format(format, args...), nil)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	_ = l.ctx.Log.Warn(l.format(format, args...), nil)
+}
+
+// LogOnErr logs the error with the message, and returns the error decorated with the message so that callers keep
+// the context. Nil errors are not logged and nil is returned.
+func (l *Logger) LogOnErr(message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	_ = l.ctx.Log.Error(l.format("%s: %v", message, err), nil)
+	return l.Wrap(message, err)
+}
+
+// Wrap returns the error decorated with the message, like LogOnErr, without logging it. Functions called by other
+// functions of the modules wrap their errors, so that an error is logged once by the entrypoint that returns it to
+// the program. Nil errors are returned as nil.
+func (l *Logger) Wrap(message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return errorx.Decorate(err, "%s", l.format("%s", message))
+}
+
+// prefixes a message with the module and resource name
+func (l *Logger) format(format string, args ...interface{}) string {
+	prefix := l.module
+	if l.resourceName != "" {
+		prefix = fmt.Sprintf("%s %s", l.module, l.resourceName)
+	}
+	return fmt.Sprintf("[%s] %s", prefix, fmt.Sprintf(format, args...))
+}
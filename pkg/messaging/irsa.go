@@ -0,0 +1,112 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type MessagingIrsaRoleInput struct {
+	// the role and the service account allowed to assume it. the role's
+	// inline policy is generated from the queue and topic access below
+	eks.IrsaRoleInput
+
+	// optional names of queues the service account may receive and delete
+	// messages from
+	ConsumeQueues []string `json:"consume-queues"`
+	// optional names of queues the service account may send messages to
+	SendQueues []string `json:"send-queues"`
+	// optional names of topics the service account may publish to
+	PublishTopics []string `json:"publish-topics"`
+	// optional kms keys of the queues and topics, which the service account
+	// needs to use for customer managed keys
+	KmsKeyArns []string `json:"kms-key-arns"`
+}
+
+// NewMessagingIrsaRole creates an IRSA role granting a kubernetes service account access to queues and topics of the
+// current account and region, referenced by name.
+func NewMessagingIrsaRole(ctx *pulumi.Context, pulumiResourceName string, input MessagingIrsaRoleInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if len(input.ConsumeQueues) == 0 && len(input.SendQueues) == 0 && len(input.PublishTopics) == 0 {
+		return nil, errors.New("messaging IRSA role requires at least one queue or topic")
+	}
+
+	arn, err := newArnFormatter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var consumeQueueArns, sendQueueArns, topicArns []string
+	for _, name := range input.ConsumeQueues {
+		consumeQueueArns = append(consumeQueueArns, arn("sqs", name))
+	}
+	for _, name := range input.SendQueues {
+		sendQueueArns = append(sendQueueArns, arn("sqs", name))
+	}
+	for _, name := range input.PublishTopics {
+		topicArns = append(topicArns, arn("sns", name))
+	}
+
+	policy, err := MessagingAccessPolicy(consumeQueueArns, sendQueueArns, topicArns, input.KmsKeyArns)
+	if err != nil {
+		return nil, err
+	}
+	roleInput := input.IrsaRoleInput
+	roleInput.InlinePolicy = policy
+	return eks.NewIrsaRole(ctx, pulumiResourceName, roleInput, opts...)
+}
+
+// MessagingAccessPolicy renders an IAM policy document granting consume access to the first queues, send access to
+// the second queues, and publish access to the topics, by arn.
+func MessagingAccessPolicy(consumeQueueArns []string, sendQueueArns []string, topicArns []string, kmsKeyArns []string) (string, error) {
+	var statements []iampolicy.Statement
+	if len(consumeQueueArns) != 0 {
+		statements = append(statements, iampolicy.Allow(
+			"sqs:ReceiveMessage",
+			"sqs:DeleteMessage",
+			"sqs:ChangeMessageVisibility",
+			"sqs:GetQueueAttributes",
+			"sqs:GetQueueUrl",
+		).On(consumeQueueArns...))
+	}
+	if len(sendQueueArns) != 0 {
+		statements = append(statements, iampolicy.Allow(
+			"sqs:SendMessage",
+			"sqs:GetQueueAttributes",
+			"sqs:GetQueueUrl",
+		).On(sendQueueArns...))
+	}
+	if len(topicArns) != 0 {
+		statements = append(statements, iampolicy.Allow("sns:Publish").On(topicArns...))
+	}
+	if len(kmsKeyArns) != 0 {
+		statements = append(statements, iampolicy.Allow(
+			"kms:Decrypt",
+			"kms:GenerateDataKey",
+		).On(kmsKeyArns...))
+	}
+
+	return iampolicy.NewDocument(statements...).Json()
+}
+
+// returns a function that formats the arn of a resource of the given service
+// in the current partition, region, and account
+func newArnFormatter(ctx *pulumi.Context) (func(service string, name string) string, error) {
+	partition, err := aws.GetPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	callerIdentity, err := aws.GetCallerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(service string, name string) string {
+		return fmt.Sprintf("arn:%s:%s:%s:%s:%s", partition.Partition, service, region.Name, callerIdentity.AccountId, name)
+	}, nil
+}
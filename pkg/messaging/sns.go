@@ -0,0 +1,135 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/sns"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/sqs"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+type TopicInput struct {
+	// topic name, must end with .fifo for fifo topics
+	Name                      string `json:"name"`
+	Fifo                      bool   `json:"fifo"`
+	ContentBasedDeduplication bool   `json:"content-based-deduplication"`
+
+	// optional kms key to encrypt messages with, defaults to the aws managed
+	// alias/aws/sns key
+	KmsKeyId string `json:"kms-key-id"`
+
+	// optional subscriptions to endpoints outside of the stack, i.e. https,
+	// email, or lambda. see SubscribeQueue for queues
+	Subscriptions []SubscriptionInput `json:"subscriptions"`
+
+	Tags map[string]string `json:"tags"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one topic of the same name in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type SubscriptionInput struct {
+	Protocol string `json:"protocol"`
+	Endpoint string `json:"endpoint"`
+	// optional, delivers the message body without the sns envelope
+	RawMessageDelivery bool `json:"raw-message-delivery"`
+	// optional sns filter policy
+	FilterPolicy map[string]interface{} `json:"filter-policy"`
+}
+
+// SyncTopic creates an encrypted sns topic and its configured subscriptions.
+func SyncTopic(ctx *pulumi.Context, config TopicInput, opts ...pulumi.ResourceOption) (*sns.Topic, error) {
+	if config.Name == "" {
+		return nil, errors.New("topic name not supplied, cannot create topic")
+	}
+	if config.Fifo != strings.HasSuffix(config.Name, ".fifo") {
+		return nil, fmt.Errorf("topic %s must be fifo exactly when its name ends with .fifo", config.Name)
+	}
+	kmsKeyId := "alias/aws/sns"
+	if config.KmsKeyId != "" {
+		kmsKeyId = config.KmsKeyId
+	}
+
+	topic, err := sns.NewTopic(ctx, utils.PrefixedName(config.ResourcePrefix, config.Name), &sns.TopicArgs{
+		Name:                      pulumi.String(config.Name),
+		FifoTopic:                 pulumi.Bool(config.Fifo),
+		ContentBasedDeduplication: pulumi.Bool(config.ContentBasedDeduplication),
+		KmsMasterKeyId:            pulumi.String(kmsKeyId),
+		Tags:                      pulumi.ToStringMap(config.Tags),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, subscription := range config.Subscriptions {
+		if subscription.Protocol == "" || subscription.Endpoint == "" {
+			return nil, fmt.Errorf("subscription %d of topic %s requires a protocol and endpoint", i, config.Name)
+		}
+		args := &sns.TopicSubscriptionArgs{
+			Topic:              topic.Arn,
+			Protocol:           pulumi.String(subscription.Protocol),
+			Endpoint:           pulumi.String(subscription.Endpoint),
+			RawMessageDelivery: pulumi.Bool(subscription.RawMessageDelivery),
+		}
+		if len(subscription.FilterPolicy) != 0 {
+			filterPolicy, err := json.Marshal(subscription.FilterPolicy)
+			if err != nil {
+				return nil, err
+			}
+			args.FilterPolicy = pulumi.String(string(filterPolicy))
+		}
+		_, err = sns.NewTopicSubscription(ctx, utils.PrefixedName(config.ResourcePrefix, fmt.Sprintf("%s-subscription-%d", config.Name, i)), args, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return topic, nil
+}
+
+type QueueSubscriptionInput struct {
+	// optional, delivers the message body without the sns envelope
+	RawMessageDelivery bool `json:"raw-message-delivery"`
+	// optional sns filter policy
+	FilterPolicy map[string]interface{} `json:"filter-policy"`
+}
+
+// SubscribeQueue subscribes a queue to a topic, along with the queue policy that allows the topic to send to the
+// queue. A queue policy replaces any other policy of the queue, so a queue can only be subscribed to one topic this way.
+func SubscribeQueue(ctx *pulumi.Context, pulumiResourceName string, topic *sns.Topic, queue *sqs.Queue, input QueueSubscriptionInput, opts ...pulumi.ResourceOption) (*sns.TopicSubscription, error) {
+	policy := pulumi.All(topic.Arn, queue.Arn).ApplyT(func(args []interface{}) (string, error) {
+		return iampolicy.NewDocument(
+			iampolicy.Allow("sqs:SendMessage").
+				For("Service", "sns.amazonaws.com").
+				On(args[1].(string)).
+				When("ArnEquals", "aws:SourceArn", args[0].(string)),
+		).Json()
+	}).(pulumi.StringOutput)
+	queuePolicy, err := sqs.NewQueuePolicy(ctx, pulumiResourceName+"-queue-policy", &sqs.QueuePolicyArgs{
+		QueueUrl: queue.Url,
+		Policy:   policy,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	args := &sns.TopicSubscriptionArgs{
+		Topic:              topic.Arn,
+		Protocol:           pulumi.String("sqs"),
+		Endpoint:           queue.Arn,
+		RawMessageDelivery: pulumi.Bool(input.RawMessageDelivery),
+	}
+	if len(input.FilterPolicy) != 0 {
+		filterPolicy, err := json.Marshal(input.FilterPolicy)
+		if err != nil {
+			return nil, err
+		}
+		args.FilterPolicy = pulumi.String(string(filterPolicy))
+	}
+	return sns.NewTopicSubscription(ctx, pulumiResourceName, args, append(append([]pulumi.ResourceOption{}, opts...), pulumi.DependsOn([]pulumi.Resource{queuePolicy}))...)
+}
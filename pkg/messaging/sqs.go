@@ -0,0 +1,136 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/sqs"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+type QueueInput struct {
+	// queue name, must end with .fifo for fifo queues
+	Name                      string `json:"name"`
+	Fifo                      bool   `json:"fifo"`
+	ContentBasedDeduplication bool   `json:"content-based-deduplication"`
+
+	// optional, default to the sqs defaults
+	VisibilityTimeoutSeconds int `json:"visibility-timeout-seconds"`
+	MessageRetentionSeconds  int `json:"message-retention-seconds"`
+	DelaySeconds             int `json:"delay-seconds"`
+	ReceiveWaitTimeSeconds   int `json:"receive-wait-time-seconds"`
+
+	// optional kms key to encrypt messages with, defaults to the aws managed
+	// alias/aws/sqs key. queues subscribed to sns topics need a customer
+	// managed key that sns may use
+	KmsKeyId string `json:"kms-key-id"`
+
+	// optional, creates a dead letter queue named <name>-dlq that messages are
+	// moved to after MaxReceiveCount receives, defaults to 5
+	DeadLetterQueue bool `json:"dead-letter-queue"`
+	MaxReceiveCount int  `json:"max-receive-count"`
+	// optional, defaults to 14 days
+	DeadLetterMessageRetentionSeconds int `json:"dead-letter-message-retention-seconds"`
+
+	Tags map[string]string `json:"tags"`
+
+	// optional protection of the queue and its dead letter queue against deletion
+	utils.ProtectionInput
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one queue of the same name in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type QueueOutput struct {
+	Queue *sqs.Queue
+	// nil unless DeadLetterQueue is set
+	DeadLetterQueue *sqs.Queue
+}
+
+// the redrive policy of a queue, which is not an IAM policy, so it is not
+// built with iampolicy
+type redrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     int    `json:"maxReceiveCount"`
+}
+
+// SyncQueue creates an encrypted sqs queue, and optionally a dead letter queue with a redrive policy.
+func SyncQueue(ctx *pulumi.Context, config QueueInput, opts ...pulumi.ResourceOption) (QueueOutput, error) {
+	var output QueueOutput
+	if config.Name == "" {
+		return output, errors.New("queue name not supplied, cannot create queue")
+	}
+	if config.Fifo != strings.HasSuffix(config.Name, ".fifo") {
+		return output, fmt.Errorf("queue %s must be fifo exactly when its name ends with .fifo", config.Name)
+	}
+	kmsKeyId := "alias/aws/sqs"
+	if config.KmsKeyId != "" {
+		kmsKeyId = config.KmsKeyId
+	}
+
+	args := &sqs.QueueArgs{
+		Name:                      pulumi.String(config.Name),
+		FifoQueue:                 pulumi.Bool(config.Fifo),
+		ContentBasedDeduplication: pulumi.Bool(config.ContentBasedDeduplication),
+		KmsMasterKeyId:            pulumi.String(kmsKeyId),
+		Tags:                      pulumi.ToStringMap(config.Tags),
+	}
+	if config.VisibilityTimeoutSeconds != 0 {
+		args.VisibilityTimeoutSeconds = pulumi.Int(config.VisibilityTimeoutSeconds)
+	}
+	if config.MessageRetentionSeconds != 0 {
+		args.MessageRetentionSeconds = pulumi.Int(config.MessageRetentionSeconds)
+	}
+	if config.DelaySeconds != 0 {
+		args.DelaySeconds = pulumi.Int(config.DelaySeconds)
+	}
+	if config.ReceiveWaitTimeSeconds != 0 {
+		args.ReceiveWaitTimeSeconds = pulumi.Int(config.ReceiveWaitTimeSeconds)
+	}
+
+	if config.DeadLetterQueue {
+		// fifo queues require fifo dead letter queues
+		deadLetterName := strings.TrimSuffix(config.Name, ".fifo") + "-dlq"
+		if config.Fifo {
+			deadLetterName += ".fifo"
+		}
+		retention := 1209600
+		if config.DeadLetterMessageRetentionSeconds != 0 {
+			retention = config.DeadLetterMessageRetentionSeconds
+		}
+		deadLetterQueue, err := sqs.NewQueue(ctx, utils.PrefixedName(config.ResourcePrefix, deadLetterName), &sqs.QueueArgs{
+			Name:                    pulumi.String(deadLetterName),
+			FifoQueue:               pulumi.Bool(config.Fifo),
+			KmsMasterKeyId:          pulumi.String(kmsKeyId),
+			MessageRetentionSeconds: pulumi.Int(retention),
+			Tags:                    pulumi.ToStringMap(config.Tags),
+		}, utils.ProtectionOpts(config.ProtectionInput, opts)...)
+		if err != nil {
+			return output, err
+		}
+		output.DeadLetterQueue = deadLetterQueue
+
+		maxReceiveCount := 5
+		if config.MaxReceiveCount != 0 {
+			maxReceiveCount = config.MaxReceiveCount
+		}
+		args.RedrivePolicy = deadLetterQueue.Arn.ApplyT(func(arn string) (string, error) {
+			policy, err := json.Marshal(redrivePolicy{
+				DeadLetterTargetArn: arn,
+				MaxReceiveCount:     maxReceiveCount,
+			})
+			return string(policy), err
+		}).(pulumi.StringOutput)
+	}
+
+	queue, err := sqs.NewQueue(ctx, utils.PrefixedName(config.ResourcePrefix, config.Name), args, utils.ProtectionOpts(config.ProtectionInput, opts)...)
+	if err != nil {
+		return output, err
+	}
+	output.Queue = queue
+
+	return output, nil
+}
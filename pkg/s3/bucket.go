@@ -0,0 +1,143 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/s3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type BucketInput struct {
+	// globally unique bucket name
+	Name string `json:"name"`
+
+	// optional, versioning is enabled unless disabled
+	DisableVersioning bool `json:"disable-versioning"`
+	// optional kms key to encrypt objects with, defaults to SSE-S3 (AES256)
+	KmsKeyArn string `json:"kms-key-arn"`
+	// optional, allows the bucket to be deleted while it holds objects
+	ForceDestroy bool `json:"force-destroy"`
+
+	// optional lifecycle rules
+	LifecycleRules []LifecycleRuleInput `json:"lifecycle-rules"`
+
+	Tags map[string]string `json:"tags"`
+
+	// optional protection of the bucket against deletion
+	utils.ProtectionInput
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one bucket of the same name in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type LifecycleRuleInput struct {
+	Id string `json:"id"`
+	// optional object key prefix the rule applies to, defaults to all objects
+	Prefix string `json:"prefix"`
+
+	// optional days after which current objects expire
+	ExpirationDays int `json:"expiration-days"`
+	// optional days after which noncurrent versions expire
+	NoncurrentVersionExpirationDays int `json:"noncurrent-version-expiration-days"`
+	// optional days after which incomplete multipart uploads are aborted
+	AbortIncompleteMultipartUploadDays int `json:"abort-incomplete-multipart-upload-days"`
+	// optional storage class transitions
+	Transitions []LifecycleTransitionInput `json:"transitions"`
+}
+
+type LifecycleTransitionInput struct {
+	Days int `json:"days"`
+	// i.e. STANDARD_IA, INTELLIGENT_TIERING, or GLACIER
+	StorageClass string `json:"storage-class"`
+}
+
+// SyncBucket creates a bucket with secure defaults: versioned, encrypted at rest, and with all public access blocked.
+func SyncBucket(ctx *pulumi.Context, config BucketInput, opts ...pulumi.ResourceOption) (*s3.Bucket, error) {
+	if config.Name == "" {
+		return nil, errors.New("bucket name not supplied, cannot create bucket")
+	}
+
+	encryption := &s3.BucketServerSideEncryptionConfigurationRuleApplyServerSideEncryptionByDefaultArgs{
+		SseAlgorithm: pulumi.String("AES256"),
+	}
+	if config.KmsKeyArn != "" {
+		encryption = &s3.BucketServerSideEncryptionConfigurationRuleApplyServerSideEncryptionByDefaultArgs{
+			SseAlgorithm:   pulumi.String("aws:kms"),
+			KmsMasterKeyId: pulumi.String(config.KmsKeyArn),
+		}
+	}
+
+	var lifecycleRules s3.BucketLifecycleRuleArray
+	for i, ruleConfig := range config.LifecycleRules {
+		id := ruleConfig.Id
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i)
+		}
+		rule := s3.BucketLifecycleRuleArgs{
+			Id:      pulumi.String(id),
+			Enabled: pulumi.Bool(true),
+		}
+		if ruleConfig.Prefix != "" {
+			rule.Prefix = pulumi.String(ruleConfig.Prefix)
+		}
+		if ruleConfig.ExpirationDays != 0 {
+			rule.Expiration = &s3.BucketLifecycleRuleExpirationArgs{
+				Days: pulumi.Int(ruleConfig.ExpirationDays),
+			}
+		}
+		if ruleConfig.NoncurrentVersionExpirationDays != 0 {
+			rule.NoncurrentVersionExpiration = &s3.BucketLifecycleRuleNoncurrentVersionExpirationArgs{
+				Days: pulumi.Int(ruleConfig.NoncurrentVersionExpirationDays),
+			}
+		}
+		if ruleConfig.AbortIncompleteMultipartUploadDays != 0 {
+			rule.AbortIncompleteMultipartUploadDays = pulumi.Int(ruleConfig.AbortIncompleteMultipartUploadDays)
+		}
+		var transitions s3.BucketLifecycleRuleTransitionArray
+		for _, transition := range ruleConfig.Transitions {
+			transitions = append(transitions, s3.BucketLifecycleRuleTransitionArgs{
+				Days:         pulumi.Int(transition.Days),
+				StorageClass: pulumi.String(transition.StorageClass),
+			})
+		}
+		if len(transitions) != 0 {
+			rule.Transitions = transitions
+		}
+		lifecycleRules = append(lifecycleRules, rule)
+	}
+
+	bucket, err := s3.NewBucket(ctx, utils.PrefixedName(config.ResourcePrefix, config.Name), &s3.BucketArgs{
+		Bucket:       pulumi.String(config.Name),
+		Acl:          pulumi.String("private"),
+		ForceDestroy: pulumi.Bool(config.ForceDestroy),
+		Versioning: &s3.BucketVersioningArgs{
+			Enabled: pulumi.Bool(!config.DisableVersioning),
+		},
+		ServerSideEncryptionConfiguration: &s3.BucketServerSideEncryptionConfigurationArgs{
+			Rule: &s3.BucketServerSideEncryptionConfigurationRuleArgs{
+				ApplyServerSideEncryptionByDefault: encryption,
+				BucketKeyEnabled:                   pulumi.Bool(config.KmsKeyArn != ""),
+			},
+		},
+		LifecycleRules: lifecycleRules,
+		Tags:           pulumi.ToStringMap(config.Tags),
+	}, utils.ProtectionOpts(config.ProtectionInput, opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s3.NewBucketPublicAccessBlock(ctx, utils.PrefixedName(config.ResourcePrefix, config.Name+"-public-access-block"), &s3.BucketPublicAccessBlockArgs{
+		Bucket:                bucket.ID(),
+		BlockPublicAcls:       pulumi.Bool(true),
+		BlockPublicPolicy:     pulumi.Bool(true),
+		IgnorePublicAcls:      pulumi.Bool(true),
+		RestrictPublicBuckets: pulumi.Bool(true),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return bucket, nil
+}
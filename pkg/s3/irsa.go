@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/eks"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type BucketIrsaRoleInput struct {
+	// the role and the service account allowed to assume it. the role's
+	// inline policy is generated from the bucket access below
+	eks.IrsaRoleInput
+
+	// bucket the service account is granted access to
+	BucketName string `json:"bucket-name"`
+	// optional object key prefix that access is limited to, i.e. "tenant-a/"
+	Prefix string `json:"prefix"`
+	// optional, only grants read access
+	ReadOnly bool `json:"read-only"`
+}
+
+// NewBucketIrsaRole creates an IRSA role granting a kubernetes service account access to the objects of a bucket,
+// optionally limited to a key prefix or to read access.
+func NewBucketIrsaRole(ctx *pulumi.Context, pulumiResourceName string, input BucketIrsaRoleInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	if input.BucketName == "" {
+		return nil, errors.New("bucket name not supplied, cannot create bucket IRSA role")
+	}
+	policy, err := BucketAccessPolicy(input.BucketName, input.Prefix, input.ReadOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	roleInput := input.IrsaRoleInput
+	roleInput.InlinePolicy = policy
+	return eks.NewIrsaRole(ctx, pulumiResourceName, roleInput, opts...)
+}
+
+// BucketAccessPolicy renders an IAM policy document granting read, and unless readOnly write, access to the objects of
+// a bucket under the given key prefix.
+func BucketAccessPolicy(bucketName string, prefix string, readOnly bool) (string, error) {
+	return iampolicy.NewDocument(BucketAccessStatements(bucketName, prefix, readOnly)...).Json()
+}
+
+// BucketAccessStatements returns the statements of BucketAccessPolicy, so that they can be combined with others in
+// one policy
+func BucketAccessStatements(bucketName string, prefix string, readOnly bool) []iampolicy.Statement {
+	objectActions := []string{
+		"s3:GetObject",
+		"s3:GetObjectVersion",
+		"s3:GetObjectTagging",
+	}
+	if !readOnly {
+		objectActions = append(objectActions,
+			"s3:PutObject",
+			"s3:PutObjectTagging",
+			"s3:DeleteObject",
+			"s3:AbortMultipartUpload",
+		)
+	}
+
+	listStatement := iampolicy.Allow("s3:ListBucket").On(fmt.Sprintf("arn:aws:s3:::%s", bucketName))
+	if prefix != "" {
+		listStatement = listStatement.When("StringLike", "s3:prefix", prefix+"*")
+	}
+
+	return []iampolicy.Statement{
+		listStatement,
+		iampolicy.Allow(objectActions...).On(fmt.Sprintf("arn:aws:s3:::%s/%s*", bucketName, prefix)),
+	}
+}
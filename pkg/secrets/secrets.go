@@ -1,11 +1,10 @@
 package secrets
 
 import (
-	"github.com/catalystcommunity/app-utils-go/templating"
 	"github.com/joomcode/errorx"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
-	"strings"
+	"regexp"
 	"sync"
 )
 
@@ -60,39 +59,140 @@ func SecretProviderFromString(secretProvider string) SecretProvider {
 // from the secret. Authentication/authorization should happen before running `pulumi up`. This makes no attempt to
 // auth to providers and depends on that configuration already being present via env vars.
 func ReplaceSecrets(ctx *pulumi.Context, source string) (string, error) {
+	resolver, err := NewSecretResolver(ctx)
+	if err != nil {
+		return "", err
+	}
+	return resolver.Replace(source)
+}
+
+// ReplaceSecretsOutput replaces secrets in the given string like ReplaceSecrets, returning the result as a pulumi
+// secret output so that the secret values stay out of plain text state and diffs, see SecretResolver.ReplaceOutput.
+func ReplaceSecretsOutput(ctx *pulumi.Context, source string) (pulumi.StringOutput, error) {
+	resolver, err := NewSecretResolver(ctx)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return resolver.ReplaceOutput(source), nil
+}
+
+// ReplaceSecretsFromPulumi uses pulumi as the secrets provider to retrieve secrets
+func ReplaceSecretsFromPulumi(conf *config.Config, source string) (string, error) {
+	return pulumiSecretResolver(conf).Replace(source)
+}
+
+// SecretResolver replaces secret placeholders with the secrets of a secret provider, resolving the distinct secrets of
+// a string concurrently and caching them for the resolver's lifetime. Callers create a resolver for the manifests or
+// values they replace secrets in, so that secrets are not cached beyond them.
+type SecretResolver struct {
+	resolve func(key string) (string, error)
+	cache   map[string]string
+	mutex   sync.Mutex
+}
+
+// NewSecretResolver returns a resolver of the secret provider configured by the secretProvider config key, failing
+// when the key is missing or names an unknown provider
+func NewSecretResolver(ctx *pulumi.Context) (*SecretResolver, error) {
 	conf := config.New(ctx, "")
-	secretProvider := conf.Require("secretProvider")
+	secretProvider, err := conf.Try("secretProvider")
+	if err != nil {
+		return nil, errorx.IllegalArgument.Wrap(err, "missing pulumi config secretProvider, required to replace secrets")
+	}
 	switch SecretProviderFromString(secretProvider) {
 	case Pulumi:
-		return ReplaceSecretsFromPulumi(conf, source)
-	case AWS:
-		return ReplaceSecretsFromAWS(conf, source)
-	case GCP:
-		return ReplaceSecretsFromGCP(conf, source)
+		return pulumiSecretResolver(conf), nil
+	case AWS, GCP:
+		return nil, errorx.IllegalArgument.New("%s secret provider is not yet implemented", secretProvider)
 	default:
-		return "", errorx.IllegalArgument.New("unknown secretProvider: %s . Please use one of ['%s','%s','%s']", secretProvider, SecretProviderTypePulumi, SecretProviderTypeAWS, SecretProviderTypeGCP)
+		return nil, errorx.IllegalArgument.New("unknown secretProvider: %s . Please use one of ['%s','%s','%s']", secretProvider, SecretProviderTypePulumi, SecretProviderTypeAWS, SecretProviderTypeGCP)
 	}
 }
 
-// ReplaceSecretsFromPulumi uses pulumi as the secrets provider to retrieve secrets
-func ReplaceSecretsFromPulumi(conf *config.Config, source string) (string, error) {
-	return templating.TemplateWithFunction(source, func(key string) (string, error) {
-		// require secret and apply are async, so we need to wait until we get the value back
-		wg := sync.WaitGroup{}
+// returns a resolver of pulumi config secrets
+func pulumiSecretResolver(conf *config.Config) *SecretResolver {
+	// config secrets are decrypted before the program runs, so they can be
+	// read synchronously instead of waiting on secret outputs
+	return newSecretResolver(func(key string) (string, error) {
+		value, err := conf.Try(key)
+		if err != nil {
+			return "", errorx.IllegalArgument.Wrap(err, "missing pulumi config secret: %s", key)
+		}
+		return value, nil
+	})
+}
+
+// matches secret placeholders, i.e. <<mySecretValue>>
+var secretPlaceholderPattern = regexp.MustCompile(`<<([^<>]+)>>`)
+
+func newSecretResolver(resolve func(key string) (string, error)) *SecretResolver {
+	return &SecretResolver{
+		resolve: resolve,
+		cache:   map[string]string{},
+	}
+}
+
+// Replace pre-scans the source for placeholders, resolves every distinct uncached secret concurrently, and replaces
+// the placeholders with the secrets
+func (r *SecretResolver) Replace(source string) (string, error) {
+	values := map[string]string{}
+	var missing []string
+	r.mutex.Lock()
+	for _, match := range secretPlaceholderPattern.FindAllStringSubmatch(source, -1) {
+		key := match[1]
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if value, ok := r.cache[key]; ok {
+			values[key] = value
+		} else if !contains(missing, key) {
+			missing = append(missing, key)
+		}
+	}
+	r.mutex.Unlock()
+
+	resolved := make([]string, len(missing))
+	errs := make([]error, len(missing))
+	wg := sync.WaitGroup{}
+	for i, key := range missing {
 		wg.Add(1)
-		var secretValue string
-		key = strings.ReplaceAll(key, "<<", "")
-		key = strings.ReplaceAll(key, ">>", "")
-		conf.RequireSecret(key).ApplyT(func(value string) string {
+		go func(i int, key string) {
 			defer wg.Done()
-			secretValue = value
-			return value
-		})
-		// wait for apply to set the secret value
-		wg.Wait()
-		// return the secret value
-		return secretValue, nil
-	})
+			resolved[i], errs[i] = r.resolve(key)
+		}(i, key)
+	}
+	wg.Wait()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, key := range missing {
+		if errs[i] != nil {
+			return "", errs[i]
+		}
+		values[key] = resolved[i]
+		r.cache[key] = resolved[i]
+	}
+
+	// replace with the pattern of the pre-scan, so that exactly the resolved
+	// placeholders are replaced
+	return secretPlaceholderPattern.ReplaceAllStringFunc(source, func(placeholder string) string {
+		return values[secretPlaceholderPattern.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// ReplaceOutput replaces secrets in the source like Replace, returning the result as a pulumi secret output so that
+// the secret values stay out of plain text state and diffs. Errors resolving a secret reject the output.
+func (r *SecretResolver) ReplaceOutput(source string) pulumi.StringOutput {
+	replaced := pulumi.String(source).ToStringOutput().ApplyT(r.Replace).(pulumi.StringOutput)
+	return pulumi.ToSecret(replaced).(pulumi.StringOutput)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // ReplaceSecretsFromAWS uses AWS Secrets Manager as the secrets provider to retrieve secrets
@@ -0,0 +1,200 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/iampolicy"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/s3"
+	"github.com/catalystcommunity/pulumi-modules-go/pkg/utils"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/guardduty"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/kinesis"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type SecurityBaselineInput struct {
+	EKSClusterName string `json:"eks-cluster-name" validate:"required"`
+
+	// optional, a guardduty detector is created unless disabled. there can
+	// only be one detector per account and region, so disable it when the
+	// account already has one. EKS audit log monitoring is not a detector
+	// datasource of the aws provider in use, enable it on the detector with
+	// the console or the aws cli
+	DisableGuardDuty bool `json:"disable-guardduty"`
+
+	// optional, the control plane audit logs are exported to s3 unless
+	// disabled. the audit log type must be enabled on the cluster
+	DisableAuditLogExport bool `json:"disable-audit-log-export"`
+	// optional bucket of the exported audit logs, the name defaults to
+	// <cluster name>-eks-audit-logs-<account id>
+	AuditLogBucket s3.BucketInput `json:"audit-log-bucket"`
+	// optional cloudwatch log group and filter pattern of the exported
+	// events, default to the cluster's control plane log group and to audit
+	// events
+	AuditLogGroupName     string `json:"audit-log-group-name"`
+	AuditLogFilterPattern string `json:"audit-log-filter-pattern"`
+
+	Tags map[string]string `json:"tags"`
+
+	// optional prefix of pulumi resource names, needed when the module is used
+	// for more than one cluster in a stack
+	ResourcePrefix string `json:"resource-prefix"`
+}
+
+type SecurityBaselineOutput struct {
+	// empty when guardduty is disabled
+	GuardDutyDetectorId pulumi.StringOutput
+	// empty when the audit log export is disabled
+	AuditLogBucketName string
+}
+
+// audit events of the control plane log group, which holds the other
+// enabled log types as well
+const auditLogFilterPattern = `{ $.kind = "Event" }`
+
+// SyncSecurityBaseline enables GuardDuty and exports the cluster's control plane audit logs from CloudWatch to s3,
+// through a log subscription to a firehose delivery stream, for retention beyond CloudWatch. GuardDuty EKS audit log
+// monitoring is not configurable with the aws provider in use and must be enabled on the detector separately.
+func SyncSecurityBaseline(ctx *pulumi.Context, config SecurityBaselineInput, opts ...pulumi.ResourceOption) (SecurityBaselineOutput, error) {
+	var output SecurityBaselineOutput
+	if config.EKSClusterName == "" {
+		return output, errors.New("EKS cluster name not supplied, cannot create security baseline")
+	}
+
+	if !config.DisableGuardDuty {
+		detector, err := guardduty.NewDetector(ctx, utils.PrefixedName(config.ResourcePrefix, "guardduty-detector"), &guardduty.DetectorArgs{
+			Enable: pulumi.Bool(true),
+			Tags:   pulumi.ToStringMap(config.Tags),
+		}, opts...)
+		if err != nil {
+			return output, err
+		}
+		output.GuardDutyDetectorId = detector.ID().ToStringOutput()
+	}
+
+	if !config.DisableAuditLogExport {
+		bucketName, err := syncAuditLogExport(ctx, config, opts...)
+		if err != nil {
+			return output, err
+		}
+		output.AuditLogBucketName = bucketName
+	}
+	return output, nil
+}
+
+// creates the audit log bucket, the delivery stream writing to it, and the
+// log subscription feeding the stream, and returns the bucket name
+func syncAuditLogExport(ctx *pulumi.Context, config SecurityBaselineInput, opts ...pulumi.ResourceOption) (string, error) {
+	bucketConfig := config.AuditLogBucket
+	if bucketConfig.Name == "" {
+		callerIdentity, err := aws.GetCallerIdentity(ctx)
+		if err != nil {
+			return "", err
+		}
+		bucketConfig.Name = fmt.Sprintf("%s-eks-audit-logs-%s", config.EKSClusterName, callerIdentity.AccountId)
+	}
+	if bucketConfig.ResourcePrefix == "" {
+		bucketConfig.ResourcePrefix = config.ResourcePrefix
+	}
+	if len(bucketConfig.Tags) == 0 {
+		bucketConfig.Tags = config.Tags
+	}
+	bucket, err := s3.SyncBucket(ctx, bucketConfig, opts...)
+	if err != nil {
+		return "", err
+	}
+	bucketArn := fmt.Sprintf("arn:aws:s3:::%s", bucketConfig.Name)
+
+	resourceName := func(name string) string {
+		return utils.PrefixedName(config.ResourcePrefix, "audit-log-"+name)
+	}
+
+	// firehose writes the records to the bucket as they arrive, they are
+	// already gzipped by cloudwatch logs
+	firehosePolicy, err := iampolicy.NewDocument(
+		iampolicy.Allow(
+			"s3:AbortMultipartUpload",
+			"s3:GetBucketLocation",
+			"s3:GetObject",
+			"s3:ListBucket",
+			"s3:ListBucketMultipartUploads",
+			"s3:PutObject",
+		).On(bucketArn, bucketArn+"/*"),
+	).Json()
+	if err != nil {
+		return "", err
+	}
+	firehoseRole, err := newServiceRole(ctx, resourceName("firehose-role"), fmt.Sprintf("AuditLogFirehoseRole-%s", config.EKSClusterName), "firehose.amazonaws.com", pulumi.String(firehosePolicy), opts...)
+	if err != nil {
+		return "", err
+	}
+	stream, err := kinesis.NewFirehoseDeliveryStream(ctx, resourceName("delivery-stream"), &kinesis.FirehoseDeliveryStreamArgs{
+		Name:        pulumi.String(fmt.Sprintf("%s-eks-audit-logs", config.EKSClusterName)),
+		Destination: pulumi.String("extended_s3"),
+		ExtendedS3Configuration: &kinesis.FirehoseDeliveryStreamExtendedS3ConfigurationArgs{
+			BucketArn: bucket.Arn,
+			RoleArn:   firehoseRole.Arn,
+			Prefix:    pulumi.String("audit/"),
+		},
+		Tags: pulumi.ToStringMap(config.Tags),
+	}, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	logsPolicy := stream.Arn.ApplyT(func(streamArn string) (string, error) {
+		return iampolicy.NewDocument(
+			iampolicy.Allow("firehose:PutRecord", "firehose:PutRecordBatch").On(streamArn),
+		).Json()
+	}).(pulumi.StringOutput)
+	logsRole, err := newServiceRole(ctx, resourceName("subscription-role"), fmt.Sprintf("AuditLogSubscriptionRole-%s", config.EKSClusterName), fmt.Sprintf("logs.%s.amazonaws.com", region.Name), logsPolicy, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	logGroupName := config.AuditLogGroupName
+	if logGroupName == "" {
+		logGroupName = fmt.Sprintf("/aws/eks/%s/cluster", config.EKSClusterName)
+	}
+	filterPattern := config.AuditLogFilterPattern
+	if filterPattern == "" {
+		filterPattern = auditLogFilterPattern
+	}
+	_, err = cloudwatch.NewLogSubscriptionFilter(ctx, resourceName("subscription-filter"), &cloudwatch.LogSubscriptionFilterArgs{
+		Name:           pulumi.String(fmt.Sprintf("%s-audit-log-export", config.EKSClusterName)),
+		LogGroup:       pulumi.String(logGroupName),
+		FilterPattern:  pulumi.String(filterPattern),
+		DestinationArn: stream.Arn,
+		RoleArn:        logsRole.Arn,
+	}, opts...)
+	if err != nil {
+		return "", err
+	}
+	return bucketConfig.Name, nil
+}
+
+// creates a role assumed by an aws service, with an inline policy
+func newServiceRole(ctx *pulumi.Context, pulumiResourceName string, name string, service string, policy pulumi.StringInput, opts ...pulumi.ResourceOption) (*iam.Role, error) {
+	assumeRolePolicy, err := iampolicy.NewDocument(
+		iampolicy.Allow("sts:AssumeRole").For("Service", service),
+	).Json()
+	if err != nil {
+		return nil, err
+	}
+	return iam.NewRole(ctx, pulumiResourceName, &iam.RoleArgs{
+		Name:             pulumi.String(name),
+		AssumeRolePolicy: pulumi.String(assumeRolePolicy),
+		InlinePolicies: iam.RoleInlinePolicyArray{
+			iam.RoleInlinePolicyArgs{
+				Name:   pulumi.String(name),
+				Policy: policy,
+			},
+		},
+	}, opts...)
+}
@@ -0,0 +1,125 @@
+package stackrefs
+
+import (
+	"fmt"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// names of the stack outputs exported and imported by this module
+const (
+	vpcIdKey            = "vpc-id"
+	vpcCidrKey          = "vpc-cidr"
+	publicSubnetIdsKey  = "public-subnet-ids"
+	privateSubnetIdsKey = "private-subnet-ids"
+
+	eksClusterNameKey     = "eks-cluster-name"
+	eksClusterArnKey      = "eks-cluster-arn"
+	eksKubeConfigKey      = "eks-kubeconfig"
+	eksOidcProviderArnKey = "eks-oidc-provider-arn"
+	eksNodeRoleArnKey     = "eks-node-role-arn"
+)
+
+// VpcOutputs are the outputs of a networking stack that cluster stacks deploy into
+type VpcOutputs struct {
+	VpcId            pulumi.StringOutput
+	VpcCidr          pulumi.StringOutput
+	PublicSubnetIds  pulumi.StringArrayOutput
+	PrivateSubnetIds pulumi.StringArrayOutput
+}
+
+// EksOutputs are the outputs of a cluster stack that bootstrap stacks deploy to. The kubeconfig is exported as a secret,
+// and stays secret when imported.
+type EksOutputs struct {
+	ClusterName     pulumi.StringOutput
+	ClusterArn      pulumi.StringOutput
+	KubeConfig      pulumi.StringOutput
+	OidcProviderArn pulumi.StringOutput
+	NodeRoleArn     pulumi.StringOutput
+}
+
+// ExportVpcOutputs exports the outputs of a networking stack, so that they can be read with ImportVpcOutputs
+func ExportVpcOutputs(ctx *pulumi.Context, outputs VpcOutputs) {
+	exportString(ctx, vpcIdKey, outputs.VpcId)
+	exportString(ctx, vpcCidrKey, outputs.VpcCidr)
+	exportStringArray(ctx, publicSubnetIdsKey, outputs.PublicSubnetIds)
+	exportStringArray(ctx, privateSubnetIdsKey, outputs.PrivateSubnetIds)
+}
+
+// ImportVpcOutputs reads the outputs of a networking stack exported with ExportVpcOutputs. stackName is the fully
+// qualified name of the stack, i.e. "org/vpc/prod".
+func ImportVpcOutputs(ctx *pulumi.Context, stackName string, opts ...pulumi.ResourceOption) (VpcOutputs, error) {
+	ref, err := newStackReference(ctx, "vpc", stackName, opts...)
+	if err != nil {
+		return VpcOutputs{}, err
+	}
+	return VpcOutputs{
+		VpcId:            ref.GetStringOutput(pulumi.String(vpcIdKey)),
+		VpcCidr:          ref.GetStringOutput(pulumi.String(vpcCidrKey)),
+		PublicSubnetIds:  getStringArrayOutput(ref, publicSubnetIdsKey),
+		PrivateSubnetIds: getStringArrayOutput(ref, privateSubnetIdsKey),
+	}, nil
+}
+
+// ExportEksOutputs exports the outputs of a cluster stack, so that they can be read with ImportEksOutputs
+func ExportEksOutputs(ctx *pulumi.Context, outputs EksOutputs) {
+	exportString(ctx, eksClusterNameKey, outputs.ClusterName)
+	exportString(ctx, eksClusterArnKey, outputs.ClusterArn)
+	if outputs.KubeConfig.OutputState != nil {
+		ctx.Export(eksKubeConfigKey, pulumi.ToSecret(outputs.KubeConfig))
+	}
+	exportString(ctx, eksOidcProviderArnKey, outputs.OidcProviderArn)
+	exportString(ctx, eksNodeRoleArnKey, outputs.NodeRoleArn)
+}
+
+// ImportEksOutputs reads the outputs of a cluster stack exported with ExportEksOutputs. The kubeconfig can be passed to
+// kubernetes.BootstrapClusterWithKubeconfig, or ClusterBootstrapConfig.KubeConfig, to bootstrap the cluster from a
+// separate stack.
+func ImportEksOutputs(ctx *pulumi.Context, stackName string, opts ...pulumi.ResourceOption) (EksOutputs, error) {
+	ref, err := newStackReference(ctx, "eks", stackName, opts...)
+	if err != nil {
+		return EksOutputs{}, err
+	}
+	return EksOutputs{
+		ClusterName:     ref.GetStringOutput(pulumi.String(eksClusterNameKey)),
+		ClusterArn:      ref.GetStringOutput(pulumi.String(eksClusterArnKey)),
+		KubeConfig:      ref.GetStringOutput(pulumi.String(eksKubeConfigKey)),
+		OidcProviderArn: ref.GetStringOutput(pulumi.String(eksOidcProviderArnKey)),
+		NodeRoleArn:     ref.GetStringOutput(pulumi.String(eksNodeRoleArnKey)),
+	}, nil
+}
+
+// references the stack, named after the layer so that the vpc and eks
+// outputs of the same stack can both be imported
+func newStackReference(ctx *pulumi.Context, layer string, stackName string, opts ...pulumi.ResourceOption) (*pulumi.StackReference, error) {
+	return pulumi.NewStackReference(ctx, fmt.Sprintf("%s-%s", layer, stackName), &pulumi.StackReferenceArgs{
+		Name: pulumi.String(stackName),
+	}, opts...)
+}
+
+// exports the output unless it is unset, so that stacks only export what
+// they create
+func exportString(ctx *pulumi.Context, key string, output pulumi.StringOutput) {
+	if output.OutputState != nil {
+		ctx.Export(key, output)
+	}
+}
+
+func exportStringArray(ctx *pulumi.Context, key string, output pulumi.StringArrayOutput) {
+	if output.OutputState != nil {
+		ctx.Export(key, output)
+	}
+}
+
+// stack outputs are untyped, arrays are read as []interface{}
+func getStringArrayOutput(ref *pulumi.StackReference, key string) pulumi.StringArrayOutput {
+	return ref.GetOutput(pulumi.String(key)).ApplyT(func(value interface{}) []string {
+		values, _ := value.([]interface{})
+		var strings []string
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				strings = append(strings, s)
+			}
+		}
+		return strings
+	}).(pulumi.StringArrayOutput)
+}
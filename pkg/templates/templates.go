@@ -4,5 +4,20 @@ import (
 	_ "embed"
 )
 
+//go:generate sh update-application-crds.sh
+
 //go:embed platform-application.yaml
 var PlatformApplicationBytes []byte
+
+// ApplicationCRDBytes holds the embedded argo-cd Application CRDs by argo-cd minor version, vendored from upstream
+// with update-application-crds.sh
+var ApplicationCRDBytes = map[string][]byte{
+	"v2.4": applicationCRDV24Bytes,
+	"v2.6": applicationCRDV26Bytes,
+}
+
+//go:embed application-crd-v2.4.yaml
+var applicationCRDV24Bytes []byte
+
+//go:embed application-crd-v2.6.yaml
+var applicationCRDV26Bytes []byte
@@ -0,0 +1,17 @@
+#!/bin/sh
+# vendors the Application CRD of every supported argo-cd version from upstream, run from this directory or with
+# go generate ./pkg/templates. add a version to VERSIONS and to templates.ApplicationCRDBytes to support it
+set -eu
+
+VERSIONS="v2.4 v2.6"
+
+for version in $VERSIONS; do
+	url="https://raw.githubusercontent.com/argoproj/argo-cd/release-${version#v}/manifests/crds/application-crd.yaml"
+	file="application-crd-${version}.yaml"
+	{
+		echo "# vendored from $url by update-application-crds.sh, do not edit"
+		curl --fail --silent --show-error --location "$url"
+	} > "$file.tmp"
+	mv "$file.tmp" "$file"
+	echo "updated $file"
+done
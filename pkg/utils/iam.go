@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v4/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// IamConventionsInput configures the naming conventions and permissions boundary that accounts with SCPs require of
+// IAM roles and policies
+type IamConventionsInput struct {
+	// optional permissions boundary attached to every role
+	PermissionsBoundaryArn string `json:"permissions-boundary-arn"`
+	// optional path of every role, policy, and instance profile, i.e.
+	// "/platform/"
+	Path string `json:"path" validate:"iampath"`
+	// optional prefix and suffix added to the name of every role, policy, and
+	// instance profile
+	NamePrefix string `json:"name-prefix"`
+	NameSuffix string `json:"name-suffix"`
+}
+
+// IsZero returns whether no conventions are configured
+func (c IamConventionsInput) IsZero() bool {
+	return c == IamConventionsInput{}
+}
+
+// IamConventionsTransformation applies the given conventions to every IAM role, policy, and instance profile. A path
+// or permissions boundary set on a resource takes precedence. Changing the names or paths of existing resources
+// replaces them, and the changed names must stay within the 64 characters of role names.
+func IamConventionsTransformation(conventions IamConventionsInput) pulumi.ResourceTransformation {
+	return func(args *pulumi.ResourceTransformationArgs) *pulumi.ResourceTransformationResult {
+		if conventions.IsZero() {
+			return nil
+		}
+		switch props := args.Props.(type) {
+		case *iam.RoleArgs:
+			props.Name = conventions.name(props.Name)
+			props.Path = conventions.path(props.Path)
+			if props.PermissionsBoundary == nil && conventions.PermissionsBoundaryArn != "" {
+				props.PermissionsBoundary = pulumi.String(conventions.PermissionsBoundaryArn)
+			}
+		case *iam.PolicyArgs:
+			props.Name = conventions.name(props.Name)
+			props.Path = conventions.path(props.Path)
+		case *iam.InstanceProfileArgs:
+			props.Name = conventions.name(props.Name)
+			props.Path = conventions.path(props.Path)
+		default:
+			return nil
+		}
+		return &pulumi.ResourceTransformationResult{
+			Props: args.Props,
+			Opts:  args.Opts,
+		}
+	}
+}
+
+// IamConventionsOpt returns a resource option that applies IamConventionsTransformation to a resource and its children
+func IamConventionsOpt(conventions IamConventionsInput) pulumi.ResourceOption {
+	return pulumi.Transformations([]pulumi.ResourceTransformation{IamConventionsTransformation(conventions)})
+}
+
+// adds the prefix and suffix to a name, names left to aws are not changed
+func (c IamConventionsInput) name(name pulumi.StringPtrInput) pulumi.StringPtrInput {
+	if c.NamePrefix == "" && c.NameSuffix == "" {
+		return name
+	}
+	switch existing := name.(type) {
+	case nil:
+		return nil
+	case pulumi.String:
+		return pulumi.String(c.NamePrefix + string(existing) + c.NameSuffix)
+	default:
+		return existing.ToStringPtrOutput().ApplyT(func(name *string) *string {
+			if name == nil {
+				return nil
+			}
+			conventional := c.NamePrefix + *name + c.NameSuffix
+			return &conventional
+		}).(pulumi.StringPtrOutput)
+	}
+}
+
+// returns the configured path unless the resource sets one
+func (c IamConventionsInput) path(path pulumi.StringPtrInput) pulumi.StringPtrInput {
+	if path != nil || c.Path == "" {
+		return path
+	}
+	return pulumi.String(c.Path)
+}
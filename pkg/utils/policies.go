@@ -0,0 +1,56 @@
+package utils
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// ResourcePolicyInput configures how pulumi diffs a resource, i.e. to leave fields alone that another controller
+// manages, like nodegroup labels or the replicas of an autoscaled deployment
+type ResourcePolicyInput struct {
+	// optional property paths whose changes are ignored, i.e.
+	// "scalingConfig.desiredSize"
+	IgnoreChanges []string `json:"ignore-changes"`
+
+	// optional property paths whose changes replace the resource instead of
+	// updating it
+	ReplaceOnChanges []string `json:"replace-on-changes"`
+}
+
+// ResourcePoliciesTransformation applies the given policies to every resource they match. Policies are keyed by the
+// resource type, i.e. "aws:eks/nodeGroup:NodeGroup", to apply to every resource of the type, or by type and pulumi
+// resource name separated by "::", i.e. "aws:eks/nodeGroup:NodeGroup::workers", to apply to one resource. Both apply
+// to a resource matching both.
+func ResourcePoliciesTransformation(policies map[string]ResourcePolicyInput) pulumi.ResourceTransformation {
+	return func(args *pulumi.ResourceTransformationArgs) *pulumi.ResourceTransformationResult {
+		var ignoreChanges, replaceOnChanges []string
+		for _, key := range []string{args.Type, args.Type + "::" + args.Name} {
+			policy, ok := policies[key]
+			if !ok {
+				continue
+			}
+			ignoreChanges = append(ignoreChanges, policy.IgnoreChanges...)
+			replaceOnChanges = append(replaceOnChanges, policy.ReplaceOnChanges...)
+		}
+		if len(ignoreChanges) == 0 && len(replaceOnChanges) == 0 {
+			return nil
+		}
+
+		// the options are appended, so the paths of the policies are added to
+		// the paths the modules ignore themselves
+		opts := append([]pulumi.ResourceOption{}, args.Opts...)
+		if len(ignoreChanges) != 0 {
+			opts = append(opts, pulumi.IgnoreChanges(ignoreChanges))
+		}
+		if len(replaceOnChanges) != 0 {
+			opts = append(opts, pulumi.ReplaceOnChanges(replaceOnChanges))
+		}
+		return &pulumi.ResourceTransformationResult{
+			Props: args.Props,
+			Opts:  opts,
+		}
+	}
+}
+
+// ResourcePoliciesOpt returns a resource option that applies ResourcePoliciesTransformation to a resource and its
+// children
+func ResourcePoliciesOpt(policies map[string]ResourcePolicyInput) pulumi.ResourceOption {
+	return pulumi.Transformations([]pulumi.ResourceTransformation{ResourcePoliciesTransformation(policies)})
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// ProtectionInput guards stateful resources, like buckets, queues, and filesystems, against an accidental pulumi destroy
+// or replacement. It is embedded in the inputs of modules that create such resources.
+type ProtectionInput struct {
+	// optional, fails any update that would delete the resource until unset
+	Protect bool `json:"protect"`
+
+	// optional, keeps the resource in the cloud provider when it is deleted
+	// from the stack
+	RetainOnDelete bool `json:"retain-on-delete"`
+}
+
+// ProtectionOpts returns a copy of the options with the configured protection options appended
+func ProtectionOpts(protection ProtectionInput, opts []pulumi.ResourceOption) []pulumi.ResourceOption {
+	protected := append([]pulumi.ResourceOption{}, opts...)
+	if protection.Protect {
+		protected = append(protected, pulumi.Protect(true))
+	}
+	if protection.RetainOnDelete {
+		protected = append(protected, pulumi.RetainOnDelete(true))
+	}
+	return protected
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+)
+
+var stringMapInputType = reflect.TypeOf((*pulumi.StringMapInput)(nil)).Elem()
+
+// TagsTransformation adds the given tags to every resource whose args have a Tags string map, i.e. AWS resources, so
+// that org-required tags like cost-center or owner don't have to be passed to each module. Tags set on a resource take
+// precedence over the given tags. Resources without tags, like kubernetes resources, are left unchanged.
+func TagsTransformation(tags map[string]string) pulumi.ResourceTransformation {
+	return func(args *pulumi.ResourceTransformationArgs) *pulumi.ResourceTransformationResult {
+		if len(tags) == 0 || args.Props == nil {
+			return nil
+		}
+		props := reflect.ValueOf(args.Props)
+		if props.Kind() != reflect.Ptr || props.IsNil() || props.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		field := props.Elem().FieldByName("Tags")
+		if !field.IsValid() || !field.CanSet() || field.Type() != stringMapInputType {
+			return nil
+		}
+
+		resourceTags, _ := field.Interface().(pulumi.StringMapInput)
+		field.Set(reflect.ValueOf(mergeTags(tags, resourceTags)))
+		return &pulumi.ResourceTransformationResult{
+			Props: args.Props,
+			Opts:  args.Opts,
+		}
+	}
+}
+
+// TagsOpt returns a resource option that applies TagsTransformation to a resource and its children
+func TagsOpt(tags map[string]string) pulumi.ResourceOption {
+	return pulumi.Transformations([]pulumi.ResourceTransformation{TagsTransformation(tags)})
+}
+
+// merges a resource's tags over the default tags
+func mergeTags(defaultTags map[string]string, resourceTags pulumi.StringMapInput) pulumi.StringMapInput {
+	switch existing := resourceTags.(type) {
+	case nil:
+		return pulumi.ToStringMap(defaultTags)
+	case pulumi.StringMap:
+		merged := pulumi.ToStringMap(defaultTags)
+		for key, value := range existing {
+			merged[key] = value
+		}
+		return merged
+	default:
+		return existing.ToStringMapOutput().ApplyT(func(existing map[string]string) map[string]string {
+			merged := map[string]string{}
+			for key, value := range defaultTags {
+				merged[key] = value
+			}
+			for key, value := range existing {
+				merged[key] = value
+			}
+			return merged
+		}).(pulumi.StringMapOutput)
+	}
+}
@@ -8,3 +8,12 @@ func GetImportOpt(id string) pulumi.ResourceOption {
 	}
 	return pulumi.Import(pulumi.ID(id))
 }
+
+// PrefixedName prefixes a pulumi resource name, so that modules can be instantiated more than once per stack. The name
+// is returned unchanged when the prefix is empty, so existing resources keep their URNs.
+func PrefixedName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "-" + name
+}